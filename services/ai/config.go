@@ -1,39 +1,221 @@
 package ai
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"textadventureservices/services/llm"
 )
 
+// DefaultTimeoutSeconds is the request timeout when none is configured
+const DefaultTimeoutSeconds = 30
+
+// DefaultMaxWaitSeconds is how long a player's command waits for the rate
+// limits before failing
+const DefaultMaxWaitSeconds = 5
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		TimeoutSeconds: 30,
-		RateLimit:      60,
-		Model:          "gpt-3.5-turbo",
-		Temperature:    0.7,
-		MaxTokens:      150,
+		Endpoint:      llm.DefaultOpenAIEndpoint,
+		DefaultModel:  "gpt-4o",
+		FallbackModel: "gpt-3.5-turbo",
+		Parameters: ModelParameters{
+			Temperature: 0.7,
+			MaxTokens:   150,
+		},
+		Retry: RetryConfig{
+			MaxRetries:       llm.DefaultRetryPolicy.MaxRetries,
+			InitialBackoffMs: int(llm.DefaultRetryPolicy.InitialBackoff / time.Millisecond),
+			MaxBackoffMs:     int(llm.DefaultRetryPolicy.MaxBackoff / time.Millisecond),
+		},
+		RateLimits: RateLimits{
+			RequestsPerMinute: 60,
+			MaxWaitSeconds:    DefaultMaxWaitSeconds,
+		},
+		TimeoutSeconds: DefaultTimeoutSeconds,
+	}
+}
+
+// policy returns the retry policy of the configuration
+func (r RetryConfig) policy() llm.RetryPolicy {
+	return llm.RetryPolicy{
+		MaxRetries:     r.MaxRetries,
+		InitialBackoff: time.Duration(r.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(r.MaxBackoffMs) * time.Millisecond,
+	}
+}
+
+// LoadConfig reads a JSON configuration file over the defaults
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	return cfg, nil
+}
+
+// ConfigFromEnv returns the defaults overridden by the environment, see ApplyEnv
+func ConfigFromEnv() (*Config, error) {
+	cfg := DefaultConfig()
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides the configuration with the environment variables that
+// are set: AI_ENDPOINT, AI_ORGANIZATION, AI_API_KEY (or OPENAI_API_KEY),
+// AI_MODEL, AI_FALLBACK_MODEL, AI_TEMPERATURE, AI_MAX_TOKENS, AI_TOP_P,
+// AI_FREQUENCY_PENALTY, AI_PRESENCE_PENALTY, AI_RATE_LIMIT (requests per
+// minute), AI_TOKENS_PER_MINUTE, AI_TIMEOUT_SECONDS and AI_MAX_RETRIES.
+func (c *Config) ApplyEnv() error {
+	// AI_API_KEY comes after OPENAI_API_KEY, so it wins when both are set
+	texts := []struct {
+		name  string
+		field *string
+	}{
+		{"AI_ENDPOINT", &c.Endpoint},
+		{"AI_ORGANIZATION", &c.Organization},
+		{"OPENAI_API_KEY", &c.APIKey},
+		{"AI_API_KEY", &c.APIKey},
+		{"AI_MODEL", &c.DefaultModel},
+		{"AI_FALLBACK_MODEL", &c.FallbackModel},
+	}
+	for _, text := range texts {
+		if value, ok := os.LookupEnv(text.name); ok {
+			*text.field = value
+		}
+	}
+
+	floats := map[string]*float64{
+		"AI_TEMPERATURE":         &c.Parameters.Temperature,
+		"AI_TOP_P":               &c.Parameters.TopP,
+		"AI_FREQUENCY_PENALTY":   &c.Parameters.FrequencyPenalty,
+		"AI_PRESENCE_PENALTY":    &c.Parameters.PresencePenalty,
+		"AI_SESSION_BUDGET_COST": &c.Usage.SessionBudget.Cost,
+	}
+	for name, field := range floats {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = parsed
+		}
+	}
+
+	ints := map[string]*int{
+		"AI_MAX_TOKENS":            &c.Parameters.MaxTokens,
+		"AI_RATE_LIMIT":            &c.RateLimits.RequestsPerMinute,
+		"AI_TOKENS_PER_MINUTE":     &c.RateLimits.TokensPerMinute,
+		"AI_MAX_WAIT_SECONDS":      &c.RateLimits.MaxWaitSeconds,
+		"AI_TIMEOUT_SECONDS":       &c.TimeoutSeconds,
+		"AI_MAX_RETRIES":           &c.Retry.MaxRetries,
+		"AI_SESSION_BUDGET_TOKENS": &c.Usage.SessionBudget.Tokens,
+	}
+	for name, field := range ints {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = parsed
+		}
+	}
+	return nil
+}
+
+// legacyConfig holds the flat fields of configurations written before
+// parameters and rate limits had sections of their own
+type legacyConfig struct {
+	Model       *string  `json:"model"`
+	MaxTokens   *int     `json:"max_tokens"`
+	Temperature *float64 `json:"temperature"`
+	RateLimit   *int     `json:"rate_limit"`
+}
+
+// UnmarshalJSON reads a configuration, accepting the flat model,
+// max_tokens, temperature and rate_limit fields of older files
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+
+	var legacy legacyConfig
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	if legacy.Model != nil {
+		c.DefaultModel = *legacy.Model
+	}
+	if legacy.MaxTokens != nil {
+		c.Parameters.MaxTokens = *legacy.MaxTokens
+	}
+	if legacy.Temperature != nil {
+		c.Parameters.Temperature = *legacy.Temperature
+	}
+	if legacy.RateLimit != nil {
+		c.RateLimits.RequestsPerMinute = *legacy.RateLimit
+	}
+	return nil
+}
+
+// endpoint returns the configured endpoint, or OpenAI's when none is set
+func (c *Config) endpoint() string {
+	if c.Endpoint == "" {
+		return llm.DefaultOpenAIEndpoint
+	}
+	return strings.TrimRight(c.Endpoint, "/")
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	if c.TimeoutSeconds <= 0 {
-		return fmt.Errorf("timeoutSeconds must be greater than 0")
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeoutSeconds must not be negative")
 	}
-	if c.RateLimit <= 0 {
-		return fmt.Errorf("rateLimit must be greater than 0")
+	if c.RateLimits.RequestsPerMinute < 0 || c.RateLimits.TokensPerMinute < 0 || c.RateLimits.MaxWaitSeconds < 0 {
+		return fmt.Errorf("rate limits must not be negative")
 	}
-	if c.Model == "" {
-		return fmt.Errorf("model must not be empty")
+	if c.DefaultModel == "" {
+		return fmt.Errorf("defaultModel must not be empty")
 	}
-	if c.APIKey == "" {
+	// Local OpenAI-compatible servers usually do without a key
+	if c.APIKey == "" && c.endpoint() == llm.DefaultOpenAIEndpoint {
 		return fmt.Errorf("apiKey must not be empty")
 	}
-	if c.MaxTokens <= 0 {
-		return fmt.Errorf("maxTokens must be greater than 0")
+	if c.Retry.MaxRetries < 0 || c.Retry.InitialBackoffMs < 0 || c.Retry.MaxBackoffMs < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	for i, fallback := range c.Fallbacks {
+		switch fallback.Type {
+		case FallbackOpenAI, FallbackOllama:
+			if fallback.Model == "" {
+				return fmt.Errorf("fallbacks[%d]: model must not be empty", i)
+			}
+		case FallbackOffline:
+		default:
+			return fmt.Errorf("fallbacks[%d]: unknown type %q", i, fallback.Type)
+		}
 	}
-	if c.Temperature < 0 || c.Temperature > 1 {
+	if c.Parameters.MaxTokens < 0 {
+		return fmt.Errorf("maxTokens must not be negative")
+	}
+	if c.Parameters.Temperature < 0 || c.Parameters.Temperature > 1 {
 		return fmt.Errorf("temperature must be between 0 and 1")
 	}
+	if err := c.Usage.Validate(); err != nil {
+		return fmt.Errorf("usage: %w", err)
+	}
 	return nil
 }
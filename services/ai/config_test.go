@@ -0,0 +1,231 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ai.json")
+	data := `{
+		"endpoint": "http://localhost:8000/v1",
+		"default_model": "llama-3-8b",
+		"fallback_model": "llama-3-1b",
+		"parameters": {"temperature": 0.4, "top_p": 0.9},
+		"rate_limits": {"requests_per_minute": 10, "tokens_per_minute": 20000}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Endpoint != "http://localhost:8000/v1" || cfg.DefaultModel != "llama-3-8b" || cfg.FallbackModel != "llama-3-1b" {
+		t.Errorf("unexpected endpoint or models: %+v", cfg)
+	}
+	if cfg.Parameters.Temperature != 0.4 || cfg.Parameters.TopP != 0.9 || cfg.Parameters.MaxTokens != 150 {
+		t.Errorf("expected file parameters over the defaults, got %+v", cfg.Parameters)
+	}
+	if cfg.RateLimits != (RateLimits{RequestsPerMinute: 10, TokensPerMinute: 20000, MaxWaitSeconds: DefaultMaxWaitSeconds}) {
+		t.Errorf("unexpected rate limits %+v", cfg.RateLimits)
+	}
+	if cfg.TimeoutSeconds != DefaultTimeoutSeconds {
+		t.Errorf("expected the default timeout, got %d", cfg.TimeoutSeconds)
+	}
+	// Local servers do without an API key
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
+
+func TestConfigLegacyFields(t *testing.T) {
+	var cfg Config
+	data := `{"model": "gpt-3.5-turbo", "max_tokens": 200, "temperature": 0.5, "rate_limit": 30, "timeout_seconds": 10}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cfg.DefaultModel != "gpt-3.5-turbo" || cfg.Parameters.MaxTokens != 200 || cfg.Parameters.Temperature != 0.5 ||
+		cfg.RateLimits.RequestsPerMinute != 30 || cfg.TimeoutSeconds != 10 {
+		t.Errorf("legacy fields were not read: %+v", cfg)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("AI_ENDPOINT", "http://localhost:1234/v1")
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+	t.Setenv("AI_API_KEY", "ai-key")
+	t.Setenv("AI_MODEL", "local-model")
+	t.Setenv("AI_TEMPERATURE", "0.3")
+	t.Setenv("AI_TOKENS_PER_MINUTE", "5000")
+	t.Setenv("AI_SESSION_BUDGET_COST", "0.25")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv failed: %v", err)
+	}
+	if cfg.Endpoint != "http://localhost:1234/v1" || cfg.APIKey != "ai-key" || cfg.DefaultModel != "local-model" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.Parameters.Temperature != 0.3 || cfg.RateLimits.TokensPerMinute != 5000 {
+		t.Errorf("unexpected parameters %+v or rate limits %+v", cfg.Parameters, cfg.RateLimits)
+	}
+	if cfg.Usage.SessionBudget.Cost != 0.25 {
+		t.Errorf("unexpected session budget %+v", cfg.Usage.SessionBudget)
+	}
+
+	t.Setenv("AI_MAX_TOKENS", "many")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected an error for a malformed AI_MAX_TOKENS")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an error without an API key for OpenAI")
+	}
+	cfg.APIKey = "test-key"
+	cfg.Parameters.Temperature = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an error for a temperature above 1")
+	}
+}
+
+func TestServiceHonorsConfig(t *testing.T) {
+	var req map[string]interface{}
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(OpenAIResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: "A quiet room."}}}})
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.Endpoint = server.URL + "/v1/"
+	cfg.Organization = "org-test"
+	cfg.DefaultModel = "local-model"
+	cfg.Parameters = ModelParameters{Temperature: 0.2, MaxTokens: 64, PresencePenalty: 0.5}
+
+	service, err := NewService(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if _, err := service.GenerateDescription(context.Background(), "a room"); err != nil {
+		t.Fatalf("GenerateDescription failed: %v", err)
+	}
+
+	if header.Get("OpenAI-Organization") != "org-test" || header.Get("Authorization") != "" {
+		t.Errorf("unexpected headers %v", header)
+	}
+	if req["model"] != "local-model" || req["temperature"] != 0.2 || req["max_tokens"] != 64.0 || req["presence_penalty"] != 0.5 {
+		t.Errorf("request does not follow the config: %v", req)
+	}
+}
+
+func TestServiceFallsBackOffline(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "test-key"
+	cfg.Endpoint = server.URL
+	cfg.Retry = RetryConfig{MaxRetries: 1, InitialBackoffMs: 1, MaxBackoffMs: 1}
+	cfg.Fallbacks = []FallbackConfig{{Type: FallbackOffline}}
+
+	service, err := NewService(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	resp, err := service.ProcessInput(context.Background(), ProcessInputRequest{Input: "look", GameState: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Expected the offline fallback to answer, got %v", err)
+	}
+	// The default and fallback models are tried before the offline hop
+	attempts := resp.ModelInfo.Attempts
+	if len(attempts) != 3 || attempts[0].Model != cfg.DefaultModel || attempts[1].Model != cfg.FallbackModel || attempts[2].Error != "" {
+		t.Errorf("unexpected attempts %+v", attempts)
+	}
+	if calls != 4 {
+		t.Errorf("expected each model to be retried once before falling back, got %d calls", calls)
+	}
+}
+
+func TestServiceRetriesBeforeFallingBack(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0.05")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(OpenAIResponse{Model: "gpt-4o", Choices: []Choice{{Message: Message{Role: "assistant", Content: "You look around."}}}})
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "test-key"
+	cfg.Endpoint = server.URL
+	cfg.Retry = RetryConfig{MaxRetries: 1, InitialBackoffMs: 1, MaxBackoffMs: 1}
+	cfg.Fallbacks = []FallbackConfig{{Type: FallbackOffline}}
+
+	service, err := NewService(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	start := time.Now()
+	resp, err := service.ProcessInput(context.Background(), ProcessInputRequest{Input: "look", GameState: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Failed to process input: %v", err)
+	}
+	// The rate limit is waited out instead of answering offline
+	if resp.ModelInfo.Model != "gpt-4o" || calls != 2 {
+		t.Errorf("expected the default model to answer its retry, got %s after %d calls", resp.ModelInfo.Model, calls)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the retry to wait for Retry-After, waited %s", elapsed)
+	}
+}
+
+func TestServiceDoesNotRetryRejectedRequests(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "test-key"
+	cfg.Endpoint = server.URL
+	cfg.Retry = RetryConfig{MaxRetries: 2, InitialBackoffMs: 1, MaxBackoffMs: 1}
+	cfg.Fallbacks = []FallbackConfig{{Type: FallbackOffline}}
+
+	service, err := NewService(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if _, err := service.ProcessInput(context.Background(), ProcessInputRequest{Input: "look", GameState: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Expected the offline fallback to answer, got %v", err)
+	}
+	// Each model is asked once, as a rejected request fails the same way again
+	if calls != 2 {
+		t.Errorf("expected no retries of a rejected request, got %d calls", calls)
+	}
+}
@@ -1,67 +1,17 @@
 package ai
 
-import (
-	"context"
-	"fmt"
-	"time"
-)
+import "textadventureservices/services/llm"
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(limit int) *RateLimiter {
-	return &RateLimiter{
-		limit:     limit,
-		lastReset: time.Now(),
-		tokens:    0,
-	}
-}
-
-// CheckLimit checks if the current request would exceed rate limits
-func (r *RateLimiter) CheckLimit() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	now := time.Now()
-	if now.Sub(r.lastReset) >= time.Minute {
-		r.tokens = 0
-		r.lastReset = now
-	}
-
-	if r.tokens >= r.limit {
-		return fmt.Errorf("request rate limit exceeded")
-	}
-
-	r.tokens++
-	return nil
-}
+// RateLimiter limits the requests and tokens sent to the model per minute
+type RateLimiter = llm.RateLimiter
 
-// AddTokens adds to the token count and checks the limit
-func (r *RateLimiter) AddTokens(tokens int) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.tokens+tokens > r.limit {
-		return fmt.Errorf("token rate limit exceeded")
-	}
-
-	r.tokens += tokens
-	return nil
+// NewRateLimiter creates a new rate limiter allowing limit requests per
+// minute; zero means no limit
+func NewRateLimiter(limit int) *RateLimiter {
+	return llm.NewRateLimiter(limit, 0)
 }
 
-// Wait waits for the rate limiter to allow a request
-func (r *RateLimiter) Wait(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	now := time.Now()
-	if now.Sub(r.lastReset) >= time.Minute {
-		r.tokens = 0
-		r.lastReset = now
-	}
-
-	if r.tokens >= r.limit {
-		return fmt.Errorf("rate limit exceeded")
-	}
-
-	r.tokens++
-	return nil
+// Limiter creates a rate limiter for the requests and tokens per minute
+func (r RateLimits) Limiter() *RateLimiter {
+	return llm.NewRateLimiter(r.RequestsPerMinute, r.TokensPerMinute)
 }
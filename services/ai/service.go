@@ -1,123 +1,224 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"textadventureservices/services/llm"
+	"textadventureservices/services/template"
+	"textadventureservices/services/usage"
 	"textadventureservices/services/worldgen/logging"
 )
 
-const openAIEndpoint = "https://api.openai.com/v1"
-
 // NewService creates a new AI interaction service
 func NewService(cfg *Config, logger logging.Logger) (*Service, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	client := &http.Client{
-		Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	timeout := cfg.TimeoutSeconds
+	if timeout == 0 {
+		timeout = DefaultTimeoutSeconds
 	}
-
-	rateLimiter := &RateLimiter{
-		limit:     cfg.RateLimit,
-		lastReset: time.Now(),
+	client := &http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
 	}
 
 	return &Service{
 		config:      cfg,
 		client:      client,
 		logger:      logger,
-		rateLimiter: rateLimiter,
+		rateLimiter: cfg.RateLimits.Limiter(),
+		tracker:     usage.NewTracker(cfg.Usage),
+		offline:     NewTemplateProvider(0),
 	}, nil
 }
 
+// SetRateLimiter replaces the limiter created from the configuration's rate
+// limits, so that several services and providers of the same endpoint can
+// share one
+func (s *Service) SetRateLimiter(limiter *RateLimiter) {
+	s.rateLimiter = limiter
+}
+
+// SetTracker replaces the usage tracker created from the configuration, so
+// that usage and budgets are accounted for in one place
+func (s *Service) SetTracker(tracker *usage.Tracker) {
+	s.tracker = tracker
+}
+
+// Usage returns the tracker the service records its usage with
+func (s *Service) Usage() *usage.Tracker {
+	return s.tracker
+}
+
 // ProcessInput processes user input and returns a response
 func (s *Service) ProcessInput(ctx context.Context, req ProcessInputRequest) (*ProcessInputResponse, error) {
-	if err := s.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit exceeded: %w", err)
+	s.logger.Info(logging.WithFields(ctx, logging.Fields{"input": req.Input}), "Processing input")
+	scope := usage.Scope{Session: req.SessionID}
+	if words := strings.Fields(req.Input); len(words) > 0 {
+		scope.Command = strings.ToLower(words[0])
 	}
-
-	response, err := s.callOpenAI(ctx, req.Input)
+	ctx = usage.With(ctx, scope)
+
+	offline := func(ctx context.Context) (string, error) {
+		answer, err := s.offline.ProcessInput(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return answer.ActionSummary, nil
+	}
+	response, err := s.complete(ctx, []llm.Message{{Role: llm.RoleUser, Content: req.Input}}, offline)
 	if err != nil {
 		return nil, fmt.Errorf("OpenAI request failed: %w", err)
 	}
 
+	updatedState, summary := parseReply(response.Content, req.GameState)
 	return &ProcessInputResponse{
-		UpdatedState:  req.GameState,
-		ActionSummary: response,
+		UpdatedState:  updatedState,
+		ActionSummary: summary,
 		ModelInfo: ModelInfo{
-			Model:       s.config.Model,
-			Temperature: s.config.Temperature,
+			Model:       response.Model,
+			TokensUsed:  response.Usage.TotalTokens,
+			Cost:        s.tracker.Cost(response.Model, response.Usage),
+			Temperature: s.config.Parameters.Temperature,
+			Attempts:    response.Attempts,
 		},
 	}, nil
 }
 
+// parseReply reads the updated state and summary from the model's reply. A
+// reply that is not a JSON object with an actionSummary is the summary, and
+// the state stays as it was.
+func parseReply(content string, state interface{}) (interface{}, string) {
+	var reply struct {
+		UpdatedState  interface{} `json:"updatedState"`
+		ActionSummary string      `json:"actionSummary"`
+	}
+	if err := json.Unmarshal([]byte(content), &reply); err != nil || reply.ActionSummary == "" {
+		return state, content
+	}
+	if reply.UpdatedState == nil {
+		return state, reply.ActionSummary
+	}
+	return reply.UpdatedState, reply.ActionSummary
+}
+
 // GenerateDescription generates a description using the AI model
 func (s *Service) GenerateDescription(ctx context.Context, prompt string) (string, error) {
-	if err := s.rateLimiter.Wait(ctx); err != nil {
-		return "", fmt.Errorf("rate limit exceeded: %w", err)
+	offline := func(ctx context.Context) (string, error) {
+		return s.offline.GenerateDescription(ctx, prompt)
 	}
-
-	response, err := s.callOpenAI(ctx, prompt)
+	response, err := s.complete(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt}}, offline)
 	if err != nil {
 		return "", fmt.Errorf("OpenAI request failed: %w", err)
 	}
 
-	return response, nil
+	return response.Content, nil
 }
 
-// callOpenAI makes a request to the OpenAI API
-func (s *Service) callOpenAI(ctx context.Context, prompt string) (string, error) {
-	reqBody := OpenAIRequest{
-		Model: s.config.Model,
-		Messages: []ChatMessage{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		Temperature: s.config.Temperature,
-		MaxTokens:   s.config.MaxTokens,
+// complete sends a conversation through the fallback chain and records its
+// usage for the scope in ctx. offline answers for the offline hop. The
+// configuration is read on every call, so changes apply to the next request.
+func (s *Service) complete(ctx context.Context, messages []llm.Message, offline func(context.Context) (string, error)) (*llm.Response, error) {
+	start := time.Now()
+	client := usage.Track(s.chain(offline), s.tracker)
+	response, err := client.Complete(ctx, llm.Request{
+		Model:    s.config.DefaultModel,
+		Messages: messages,
+		Options:  s.options(),
+	})
+
+	fields := logging.Fields{
+		"endpoint":   s.config.endpoint(),
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
 	}
-
-	reqData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		fields["model"] = s.config.DefaultModel
+		fields["error"] = err.Error()
+		s.logger.Warn(logging.WithFields(ctx, fields), "Model request failed")
+		return nil, err
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", openAIEndpoint+"/chat/completions", bytes.NewReader(reqData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+	fields["model"] = response.Model
+	fields["attempts"] = len(response.Attempts)
+	if len(response.Attempts) > 1 {
+		s.logger.Warn(logging.WithFields(ctx, fields), "Model request fell back or was retried")
+	} else {
+		s.logger.Debug(logging.WithFields(ctx, fields), "Model request finished")
 	}
+	return response, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+// chain returns the configured fallback chain: the default model, the
+// fallback model and then the configured fallbacks in order. Each network
+// hop retries by the retry policy before the chain moves on, so rate limits
+// and server errors are waited out instead of falling through to the next hop.
+func (s *Service) chain(offline func(context.Context) (string, error)) *llm.FallbackClient {
+	policy := s.config.Retry.policy()
+	// Both models of the configured endpoint share its rate limits
+	limited := llm.WithRateLimit(s.openAI(), s.rateLimiter)
+	limited.MaxWait = time.Duration(s.config.RateLimits.MaxWaitSeconds) * time.Second
+	primary := llm.WithRetry(limited, policy)
+	hops := []llm.Hop{{Name: FallbackOpenAI, Client: primary, Model: s.config.DefaultModel}}
+	if s.config.FallbackModel != "" && s.config.FallbackModel != s.config.DefaultModel {
+		hops = append(hops, llm.Hop{Name: FallbackOpenAI, Client: primary, Model: s.config.FallbackModel})
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+	for _, fallback := range s.config.Fallbacks {
+		switch fallback.Type {
+		case FallbackOpenAI:
+			client := s.openAI()
+			if fallback.Endpoint != "" {
+				client.Endpoint = strings.TrimRight(fallback.Endpoint, "/")
+			}
+			hops = append(hops, llm.Hop{Name: FallbackOpenAI, Client: llm.WithRetry(client, policy), Model: fallback.Model})
+		case FallbackOllama:
+			client := llm.NewOllamaClient(fallback.Endpoint)
+			client.Stream = false
+			client.HTTPClient = s.client
+			hops = append(hops, llm.Hop{Name: FallbackOllama, Client: llm.WithRetry(client, policy), Model: fallback.Model})
+		case FallbackOffline:
+			hops = append(hops, llm.Hop{Name: FallbackOffline, Client: llm.ClientFunc(func(ctx context.Context, req llm.Request) (*llm.Response, error) {
+				text, err := offline(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return &llm.Response{Content: text, Model: template.Model}, nil
+			})})
+		}
 	}
+	return llm.Fallback(hops...)
+}
 
-	var openAIResp OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+// openAI returns a client for the configured endpoint
+func (s *Service) openAI() *llm.OpenAIClient {
+	return &llm.OpenAIClient{
+		Endpoint:     s.config.endpoint(),
+		APIKey:       s.config.APIKey,
+		Organization: s.config.Organization,
+		HTTPClient:   s.client,
 	}
+}
 
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices from OpenAI")
+// options returns the configured model parameters
+func (s *Service) options() llm.Options {
+	params := s.config.Parameters
+	extra := make(map[string]interface{})
+	for name, value := range map[string]float64{
+		"top_p":             params.TopP,
+		"frequency_penalty": params.FrequencyPenalty,
+		"presence_penalty":  params.PresencePenalty,
+	} {
+		if value != 0 {
+			extra[name] = value
+		}
+	}
+	return llm.Options{
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		Extra:       extra,
 	}
-
-	return openAIResp.Choices[0].Message.Content, nil
 }
@@ -183,6 +183,8 @@ func TestFallbackModel(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.APIKey = "test-key"
 	cfg.Endpoint = server.URL
+	// Fall back at once instead of retrying the primary model
+	cfg.Retry.MaxRetries = 0
 
 	service, err := NewService(cfg, &mockLogger{})
 	if err != nil {
@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+
+	"textadventureservices/services/template"
+)
+
+// commandGrammar answers player commands; "#target#" and "#direction#" are
+// bound from the command before expansion
+var commandGrammar = template.Grammar{
+	"look":      {"You look around. #noticed.capitalize#.", "You take in your surroundings. #noticed.capitalize#."},
+	"noticed":   {"nothing seems to have changed", "the shadows shift a little", "everything is exactly where you left it"},
+	"examine":   {"You examine the #target# closely. #examined#", "You study the #target#. #examined#"},
+	"examined":  {"Nothing about it seems out of place.", "Faint markings run along one side.", "It is heavier than it looks."},
+	"move":      {"You head #direction#.", "You make your way #direction#.", "You set off #direction#, #pace#."},
+	"pace":      {"careful of your footing", "without looking back", "as quietly as you can"},
+	"take":      {"You pick up the #target#.", "You take the #target#."},
+	"drop":      {"You set down the #target#.", "You drop the #target#."},
+	"open":      {"You open the #target#.", "The #target# swings open with a creak."},
+	"use":       {"You try the #target#, but nothing obvious happens.", "You use the #target#. #noticed.capitalize#."},
+	"inventory": {"You check what you are carrying."},
+	"talk":      {"The #target# does not answer.", "Your words echo without reply."},
+	"wait":      {"Time passes.", "You wait. #noticed.capitalize#."},
+	"unknown":   {"Nothing happens.", "You are not sure how to do that here.", "That does not seem to work."},
+}
+
+// commandVerbs maps player verbs to commandGrammar symbols
+var commandVerbs = map[string]string{
+	"look": "look", "l": "look",
+	"examine": "examine", "x": "examine", "inspect": "examine", "read": "examine",
+	"go": "move", "walk": "move", "run": "move",
+	"take": "take", "get": "take", "grab": "take", "pick": "take",
+	"drop": "drop", "put": "drop",
+	"open":      "open",
+	"use":       "use",
+	"inventory": "inventory", "i": "inventory",
+	"talk": "talk", "say": "talk",
+	"wait": "wait", "z": "wait",
+}
+
+// shortDirections expands abbreviated movement commands
+var shortDirections = map[string]string{
+	"n": "north", "s": "south", "e": "east", "w": "west",
+	"ne": "northeast", "nw": "northwest", "se": "southeast", "sw": "southwest",
+	"u": "up", "d": "down",
+	"north": "north", "south": "south", "east": "east", "west": "west",
+	"northeast": "northeast", "northwest": "northwest", "southeast": "southeast", "southwest": "southwest",
+	"up": "up", "down": "down",
+}
+
+// TemplateProvider answers without a model: descriptions come from the
+// template grammars and commands from commandGrammar. Responses are
+// deterministic for a seed and input, and the game state is returned unchanged.
+type TemplateProvider struct {
+	seed         int64
+	descriptions *template.Generator
+}
+
+// NewTemplateProvider creates a template provider with the given seed
+func NewTemplateProvider(seed int64) *TemplateProvider {
+	return &TemplateProvider{
+		seed:         seed,
+		descriptions: template.New(seed, nil),
+	}
+}
+
+// ProcessInput answers a player command such as "take lamp" or "n"
+func (p *TemplateProvider) ProcessInput(ctx context.Context, req ProcessInputRequest) (*ProcessInputResponse, error) {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s", p.seed, req.Input)
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	return &ProcessInputResponse{
+		UpdatedState:  req.GameState,
+		ActionSummary: respond(rng, req.Input),
+		ModelInfo: ModelInfo{
+			Model: template.Model,
+		},
+	}, nil
+}
+
+// respond expands the commandGrammar rule for a command
+func respond(rng *rand.Rand, input string) string {
+	words := strings.Fields(strings.ToLower(input))
+	if len(words) == 0 {
+		return commandGrammar.Flatten(rng, "unknown")
+	}
+
+	if direction, ok := shortDirections[words[0]]; ok {
+		return commandGrammar.With("direction", direction).Flatten(rng, "move")
+	}
+	symbol, ok := commandVerbs[words[0]]
+	if !ok {
+		return commandGrammar.Flatten(rng, "unknown")
+	}
+
+	args := words[1:]
+	if len(args) > 0 && ((words[0] == "pick" && args[0] == "up") || args[0] == "at" || args[0] == "to") {
+		args = args[1:]
+	}
+	if len(args) > 0 && (args[0] == "the" || args[0] == "a" || args[0] == "an") {
+		args = args[1:]
+	}
+	target := strings.Join(args, " ")
+
+	switch symbol {
+	case "move":
+		if target == "" {
+			return "Which way?"
+		}
+		if direction, ok := shortDirections[target]; ok {
+			target = direction
+		}
+		return commandGrammar.With("direction", target).Flatten(rng, symbol)
+	case "examine", "take", "drop", "open", "use":
+		if target == "" {
+			return fmt.Sprintf("What do you want to %s?", words[0])
+		}
+	case "look":
+		if target != "" {
+			symbol = "examine"
+		}
+	case "talk":
+		if target == "" {
+			target = "silence"
+		}
+	}
+	return commandGrammar.With("target", target).Flatten(rng, symbol)
+}
+
+// GenerateDescription describes the place or object in the prompt
+func (p *TemplateProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	return p.descriptions.Describe(prompt), nil
+}
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewTemplateProvider(1)
+	state := map[string]interface{}{"currentRoom": "hall"}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"take the lamp", "lamp"},
+		{"pick up rope", "rope"},
+		{"n", "north"},
+		{"go west", "west"},
+		{"examine statue", "statue"},
+		{"look at the door", "door"},
+		{"take", "What do you want to take?"},
+	}
+	for _, tt := range tests {
+		resp, err := provider.ProcessInput(ctx, ProcessInputRequest{Input: tt.input, GameState: state})
+		if err != nil {
+			t.Fatalf("ProcessInput(%q) failed: %v", tt.input, err)
+		}
+		if !strings.Contains(resp.ActionSummary, tt.want) {
+			t.Errorf("ProcessInput(%q) = %q, want it to mention %q", tt.input, resp.ActionSummary, tt.want)
+		}
+		if !reflect.DeepEqual(resp.UpdatedState, state) || resp.ModelInfo.Model != "template" {
+			t.Errorf("unexpected response %+v", resp)
+		}
+	}
+
+	first, _ := provider.ProcessInput(ctx, ProcessInputRequest{Input: "dance"})
+	second, _ := provider.ProcessInput(ctx, ProcessInputRequest{Input: "dance"})
+	if first.ActionSummary != second.ActionSummary {
+		t.Errorf("expected deterministic responses, got %q and %q", first.ActionSummary, second.ActionSummary)
+	}
+
+	description, err := provider.GenerateDescription(ctx, "A haunted mansion")
+	if err != nil || !strings.Contains(description, "haunted mansion") {
+		t.Errorf("unexpected description %q, %v", description, err)
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"net/http"
 	"sync"
-	"time"
 
+	"textadventureservices/services/llm"
+	"textadventureservices/services/usage"
 	"textadventureservices/services/worldgen/logging"
 )
 
@@ -15,7 +16,10 @@ type Service struct {
 	client      *http.Client
 	logger      logging.Logger
 	rateLimiter *RateLimiter
-	mu          sync.Mutex
+	tracker     *usage.Tracker
+	// offline answers for the offline hop of the fallback chain
+	offline *TemplateProvider
+	mu      sync.Mutex
 }
 
 // Provider defines the interface for AI services
@@ -26,18 +30,75 @@ type Provider interface {
 
 // Config represents the configuration for the AI service
 type Config struct {
-	Model          string  `json:"model"`
-	MaxTokens      int     `json:"max_tokens"`
-	Temperature    float64 `json:"temperature"`
-	RateLimit      int     `json:"rate_limit"`
-	TimeoutSeconds int     `json:"timeout_seconds"`
-	APIKey         string  `json:"api_key"`
+	// Endpoint is the base URL of an OpenAI-compatible API; empty means OpenAI itself
+	Endpoint string `json:"endpoint,omitempty"`
+	// Organization is sent as the OpenAI-Organization header when set
+	Organization string `json:"organization,omitempty"`
+	APIKey       string `json:"api_key,omitempty"`
+	DefaultModel string `json:"default_model"`
+	// FallbackModel answers when DefaultModel fails; empty means no fallback
+	FallbackModel string `json:"fallback_model,omitempty"`
+	// Fallbacks answer in order, after FallbackModel, when the models above fail
+	Fallbacks      []FallbackConfig `json:"fallbacks,omitempty"`
+	Retry          RetryConfig      `json:"retry"`
+	Parameters     ModelParameters  `json:"parameters"`
+	RateLimits     RateLimits       `json:"rate_limits"`
+	TimeoutSeconds int              `json:"timeout_seconds"`
+	// Usage prices the models and caps the usage of game sessions
+	Usage usage.Config `json:"usage"`
+}
+
+// Fallback types
+const (
+	// FallbackOpenAI is another model at the configured or an own OpenAI-compatible endpoint
+	FallbackOpenAI = "openai"
+	// FallbackOllama is a model of a local Ollama server
+	FallbackOllama = "ollama"
+	// FallbackOffline is a canned answer from the template provider
+	FallbackOffline = "offline"
+)
+
+// FallbackConfig is a hop of the fallback chain
+type FallbackConfig struct {
+	Type string `json:"type"`
+	// Endpoint defaults to the configured endpoint for openai and to the local server for ollama
+	Endpoint string `json:"endpoint,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// RetryConfig sets how failed rounds of the fallback chain are retried after
+// rate limits, server errors and network failures
+type RetryConfig struct {
+	MaxRetries       int `json:"max_retries"`
+	InitialBackoffMs int `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     int `json:"max_backoff_ms,omitempty"`
+}
+
+// ModelParameters tune the replies of the model. Zero values other than
+// Temperature leave the setting to the server.
+type ModelParameters struct {
+	Temperature      float64 `json:"temperature"`
+	MaxTokens        int     `json:"max_tokens,omitempty"`
+	TopP             float64 `json:"top_p,omitempty"`
+	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64 `json:"presence_penalty,omitempty"`
+}
+
+// RateLimits cap the use of the API per minute; zero means no limit
+type RateLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"`
+	// MaxWaitSeconds is the longest a request waits for the limits before
+	// failing; zero waits as long as the request's context allows
+	MaxWaitSeconds int `json:"max_wait_seconds,omitempty"`
 }
 
 // ProcessInputRequest represents the request for processing user input
 type ProcessInputRequest struct {
 	Input     string      `json:"input"`
 	GameState interface{} `json:"gameState"`
+	// SessionID attributes the usage of the request to a game session
+	SessionID string `json:"sessionId,omitempty"`
 }
 
 // ProcessInputResponse represents the response from processing user input
@@ -49,38 +110,32 @@ type ProcessInputResponse struct {
 
 // ModelInfo contains information about the AI model used
 type ModelInfo struct {
-	Model       string  `json:"model"`
-	TokensUsed  int     `json:"tokensUsed"`
+	Model      string `json:"model"`
+	TokensUsed int    `json:"tokensUsed"`
+	// Cost is the estimated cost of the request in US dollars
+	Cost        float64 `json:"cost"`
 	Temperature float64 `json:"temperature"`
+	// Attempts lists every model asked, ending with the one that answered
+	Attempts []ModelAttempt `json:"attempts,omitempty"`
 }
 
-// RateLimiter represents a rate limiter
-type RateLimiter struct {
-	limit     int
-	lastReset time.Time
-	tokens    int
-	mu        sync.Mutex
-}
+// ModelAttempt is a request to one model of the fallback chain
+type ModelAttempt = llm.Attempt
+
+// Message is a message of a conversation with a model
+type Message = llm.Message
 
 // ChatMessage represents a message in the chat
-type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+type ChatMessage = llm.Message
 
 // OpenAIRequest represents a request to the OpenAI API
-type OpenAIRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature"`
-	MaxTokens   int          `json:"max_tokens"`
-}
+type OpenAIRequest = llm.ChatCompletionRequest
 
 // OpenAIResponse represents a response from the OpenAI API
-type OpenAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
+type OpenAIResponse = llm.ChatCompletionResponse
+
+// Choice is one of the replies in an OpenAIResponse
+type Choice = llm.Choice
+
+// Usage counts the tokens of a request
+type Usage = llm.Usage
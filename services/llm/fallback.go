@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Attempt records one request of a fallback chain or retry, failed or not
+type Attempt struct {
+	// Name is the hop of the fallback chain that was asked, e.g. "ollama"
+	Name  string `json:"name,omitempty"`
+	Model string `json:"model"`
+	// Error is why the attempt failed; it is empty for the attempt that answered
+	Error string `json:"error,omitempty"`
+}
+
+// attempts returns the attempts recorded in a response, or the single
+// attempt that produced it
+func (r *Response) attempts(req Request) []Attempt {
+	if len(r.Attempts) > 0 {
+		return r.Attempts
+	}
+	model := r.Model
+	if model == "" {
+		model = req.Model
+	}
+	return []Attempt{{Model: model}}
+}
+
+// failedAttempts returns the attempts behind an error
+func failedAttempts(req Request, err error) []Attempt {
+	var fallbackErr *FallbackError
+	if errors.As(err, &fallbackErr) {
+		return fallbackErr.Attempts
+	}
+	return []Attempt{{Model: req.Model, Error: err.Error()}}
+}
+
+// Hop is a step of a fallback chain
+type Hop struct {
+	// Name identifies the hop in the recorded attempts, e.g. "openai" or "offline"
+	Name   string
+	Client Client
+	// Model replaces the model of the request when set
+	Model string
+}
+
+// FallbackClient asks the hops of a chain in order until one answers
+type FallbackClient struct {
+	hops []Hop
+}
+
+// Fallback creates a chain of hops, e.g. the primary model, a cheaper model,
+// a local model and a canned answer
+func Fallback(hops ...Hop) *FallbackClient {
+	return &FallbackClient{hops: hops}
+}
+
+// Complete asks each hop in turn and returns the first answer, with every
+// hop asked recorded in its Attempts. It stops early when ctx is done.
+func (c *FallbackClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	failure := &FallbackError{}
+	for _, hop := range c.hops {
+		hopReq := req
+		if hop.Model != "" {
+			hopReq.Model = hop.Model
+		}
+
+		resp, err := hop.Client.Complete(ctx, hopReq)
+		if err == nil {
+			resp.Attempts = append(failure.Attempts, named(resp.attempts(hopReq), hop.Name)...)
+			return resp, nil
+		}
+		failure.Attempts = append(failure.Attempts, named(failedAttempts(hopReq, err), hop.Name)...)
+		failure.errs = append(failure.errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(failure.errs) == 0 {
+		return nil, fmt.Errorf("fallback chain has no hops")
+	}
+	return nil, failure
+}
+
+// named sets the name of attempts that do not have one yet
+func named(attempts []Attempt, name string) []Attempt {
+	for i := range attempts {
+		if attempts[i].Name == "" {
+			attempts[i].Name = name
+		}
+	}
+	return attempts
+}
+
+// FallbackError is returned when every hop of a chain failed
+type FallbackError struct {
+	Attempts []Attempt
+	errs     []error
+}
+
+func (e *FallbackError) Error() string {
+	failures := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		failures[i] = fmt.Sprintf("%s: %s", attempt.Model, attempt.Error)
+	}
+	return "all models failed: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the errors of the hops, so errors.Is and errors.As see them
+func (e *FallbackError) Unwrap() []error {
+	return e.errs
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFallbackClientAsksHopsInOrder(t *testing.T) {
+	down := &scriptedClient{errs: []error{&APIError{Provider: "openai", StatusCode: 503, Message: "overloaded"}}}
+	local := &scriptedClient{}
+	chain := Fallback(
+		Hop{Name: "openai", Client: down},
+		Hop{Name: "ollama", Client: local, Model: "llama3"},
+	)
+
+	resp, err := chain.Complete(context.Background(), Request{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Model != "llama3" {
+		t.Errorf("expected the hop's model to be used, got %q", resp.Model)
+	}
+	want := []Attempt{{Name: "openai", Model: "gpt-4o", Error: "openai API error (status 503): overloaded"}, {Name: "ollama", Model: "llama3"}}
+	if len(resp.Attempts) != len(want) {
+		t.Fatalf("expected attempts %+v, got %+v", want, resp.Attempts)
+	}
+	for i := range want {
+		if resp.Attempts[i] != want[i] {
+			t.Errorf("attempt %d: expected %+v, got %+v", i, want[i], resp.Attempts[i])
+		}
+	}
+}
+
+func TestFallbackClientReportsEveryFailure(t *testing.T) {
+	chain := Fallback(
+		Hop{Name: "openai", Client: &scriptedClient{errs: []error{&APIError{Provider: "openai", StatusCode: 429}}}},
+		Hop{Name: "ollama", Client: &scriptedClient{errs: []error{errors.New("connection refused")}}, Model: "llama3"},
+	)
+	_, err := chain.Complete(context.Background(), Request{Model: "gpt-4o"})
+
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) || len(fallbackErr.Attempts) != 2 {
+		t.Fatalf("expected both failures, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "all models failed") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("unexpected error message %q", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Errorf("expected the API error to be unwrapped, got %v", apiErr)
+	}
+	if !Retryable(err) {
+		t.Error("expected a rate limited chain to be retryable")
+	}
+}
+
+func TestFallbackClientStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	second := &scriptedClient{}
+	chain := Fallback(
+		Hop{Client: &scriptedClient{errs: []error{context.Canceled}}},
+		Hop{Client: second},
+	)
+	if _, err := chain.Complete(ctx, Request{}); !errors.Is(err, context.Canceled) || second.calls != 0 {
+		t.Errorf("expected the chain to stop, got %v after %d calls", err, second.calls)
+	}
+}
@@ -0,0 +1,124 @@
+// Package llm is the chat completion client shared by the AI service and
+// world generation. Clients send a conversation to a model and return its
+// reply with the tokens it used; the capability-level providers of both
+// services are built on top of them.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Message is a single message of a conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Roles of the messages in a conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Request asks a model to continue a conversation
+type Request struct {
+	Model    string
+	Messages []Message
+	Options  Options
+	// OnChunk, when set, is called with every piece of a streamed reply as it arrives
+	OnChunk func(chunk string)
+}
+
+// Options tune a request
+type Options struct {
+	// Temperature is always sent to OpenAI; Ollama keeps the model's default when it is zero
+	Temperature float64
+	// MaxTokens limits the reply; zero leaves the limit to the server
+	MaxTokens int
+	// JSON constrains the reply to a single JSON object
+	JSON bool
+	// Extra are passed through to the server as they are, e.g. Ollama's num_ctx or top_k
+	Extra map[string]interface{}
+}
+
+// Usage counts the tokens of a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// Response is a model's reply
+type Response struct {
+	Content string
+	// Model is the model that answered, as reported by the server
+	Model string
+	Usage Usage
+	// FinishReason tells why the model stopped, e.g. "stop" or "length"
+	FinishReason string
+	// Attempts are the requests made by fallback chains and retries, ending
+	// with the one that answered; clients that try once leave it empty
+	Attempts []Attempt
+}
+
+// Client sends conversations to a model
+type Client interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// ClientFunc adapts a function to the Client interface
+type ClientFunc func(ctx context.Context, req Request) (*Response, error)
+
+// Complete calls f
+func (f ClientFunc) Complete(ctx context.Context, req Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// ErrEmptyResponse is returned when a server answers without any reply
+var ErrEmptyResponse = errors.New("no response choices returned")
+
+// APIError is an error reported by a model server
+type APIError struct {
+	// Provider names the server, e.g. "OpenAI" or "ollama"
+	Provider   string
+	StatusCode int
+	// Type is the server's error type when it reports one, e.g. "insufficient_quota"
+	Type    string
+	Message string
+	// RetryAfter is how long the server asked clients to wait, from its Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == http.StatusTooManyRequests {
+		return fmt.Sprintf("%s rate limit exceeded (status %d): %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Complete sends a single prompt as a user message and returns the reply's text
+func Complete(ctx context.Context, client Client, model, prompt string, opts Options) (string, error) {
+	resp, err := client.Complete(ctx, Request{
+		Model:    model,
+		Messages: []Message{{Role: RoleUser, Content: prompt}},
+		Options:  opts,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaEndpoint is where a local Ollama server listens by default
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// ollamaChatRequest is the body of a request to /api/chat
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is a full response, or a single NDJSON line of a streamed one.
+// The final line carries the model, the token counts and done.
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message *struct {
+		Content string `json:"content"`
+	} `json:"message,omitempty"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason,omitempty"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+func (r ollamaChatResponse) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return ""
+}
+
+// OllamaClient talks to the /api/chat endpoint of an Ollama server
+type OllamaClient struct {
+	Endpoint string
+	// Stream asks for the reply as NDJSON chunks, which are passed to Request.OnChunk
+	Stream     bool
+	HTTPClient *http.Client
+}
+
+// NewOllamaClient creates a streaming client for the server at endpoint,
+// which defaults to DefaultOllamaEndpoint
+func NewOllamaClient(endpoint string) *OllamaClient {
+	if endpoint == "" {
+		endpoint = DefaultOllamaEndpoint
+	}
+	return &OllamaClient{
+		Endpoint:   strings.TrimRight(endpoint, "/"),
+		Stream:     true,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Complete sends the conversation to /api/chat. Options.Extra become model
+// options, next to temperature and num_predict from Temperature and MaxTokens.
+func (c *OllamaClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := ollamaChatRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   c.Stream,
+		Options:  ollamaOptions(req.Options),
+	}
+	if req.Options.JSON {
+		body.Format = "json"
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint+"/api/chat", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result ollamaChatResponse
+		data, _ := io.ReadAll(resp.Body)
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &result) == nil && result.Error != "" {
+			message = result.Error
+		}
+		return nil, &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: message, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	result, err := c.decode(resp.Body, req.OnChunk)
+	if err != nil {
+		return nil, err
+	}
+	if result.Model == "" {
+		result.Model = req.Model
+	}
+	return result, nil
+}
+
+// ollamaOptions builds the model options of a request
+func ollamaOptions(opts Options) map[string]interface{} {
+	if opts.Temperature == 0 && opts.MaxTokens == 0 && len(opts.Extra) == 0 {
+		return nil
+	}
+	options := make(map[string]interface{}, len(opts.Extra)+2)
+	if opts.Temperature != 0 {
+		options["temperature"] = opts.Temperature
+	}
+	if opts.MaxTokens != 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+	for key, value := range opts.Extra {
+		options[key] = value
+	}
+	return options
+}
+
+// decode reads a single JSON response or a stream of NDJSON chunks and joins their text
+func (c *OllamaClient) decode(body io.Reader, onChunk func(string)) (*Response, error) {
+	var text strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, &APIError{Provider: "ollama", StatusCode: http.StatusOK, Message: chunk.Error}
+		}
+
+		text.WriteString(chunk.text())
+		if onChunk != nil && chunk.text() != "" {
+			onChunk(chunk.text())
+		}
+		if chunk.Done {
+			return &Response{
+				Content: text.String(),
+				Model:   chunk.Model,
+				Usage: Usage{
+					PromptTokens:     chunk.PromptEvalCount,
+					CompletionTokens: chunk.EvalCount,
+					TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+				},
+				FinishReason: chunk.DoneReason,
+			}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !c.Stream {
+		return &Response{Content: text.String()}, nil
+	}
+	return nil, fmt.Errorf("response stream ended before completion")
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestOllama(t *testing.T, handler http.HandlerFunc) *OllamaClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOllamaClient(server.URL)
+}
+
+func TestOllamaClientStream(t *testing.T) {
+	var req ollamaChatRequest
+	client := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected /api/chat, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, chunk := range []string{"A damp ", "cellar."} {
+			fmt.Fprintf(w, "{\"model\":\"llama3\",\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", chunk)
+		}
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":4}`)
+	})
+
+	var chunks []string
+	resp, err := client.Complete(context.Background(), Request{
+		Model:    "llama3",
+		Messages: []Message{{Role: RoleUser, Content: "a cellar"}},
+		Options:  Options{MaxTokens: 64, JSON: true, Extra: map[string]interface{}{"top_k": 20.0}},
+		OnChunk:  func(chunk string) { chunks = append(chunks, chunk) },
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Content != "A damp cellar." || resp.Model != "llama3" || resp.FinishReason != "stop" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.Usage != (Usage{PromptTokens: 26, CompletionTokens: 4, TotalTokens: 30}) {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("expected 2 streamed chunks, got %q", chunks)
+	}
+	want := map[string]interface{}{"num_predict": 64.0, "top_k": 20.0}
+	if !req.Stream || req.Format != "json" || !reflect.DeepEqual(req.Options, want) {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestOllamaClientErrors(t *testing.T) {
+	missing := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, `{"error":"model 'llama3' not found"}`)
+	})
+	_, err := missing.Complete(context.Background(), Request{Model: "llama3"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "model 'llama3' not found" {
+		t.Errorf("expected model not found error, got %v", err)
+	}
+
+	truncated := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"content":"A dark"},"done":false}`)
+	})
+	if _, err := truncated.Complete(context.Background(), Request{Model: "llama3"}); err == nil {
+		t.Error("expected error for a stream that never completes")
+	}
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIEndpoint is the base URL of the OpenAI API
+const DefaultOpenAIEndpoint = "https://api.openai.com/v1"
+
+// DefaultTimeout is how long a client made by NewOpenAIClient waits for a
+// reply; set HTTPClient.Timeout to change it
+const DefaultTimeout = 60 * time.Second
+
+// ChatCompletionRequest is the body of a request to an OpenAI-compatible
+// /chat/completions endpoint
+type ChatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	// ResponseFormat asks the model for a JSON object when set
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat constrains the reply of a chat completion
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// ChatCompletionResponse is the body of a /chat/completions response
+type ChatCompletionResponse struct {
+	ID      string   `json:"id,omitempty"`
+	Object  string   `json:"object,omitempty"`
+	Created int64    `json:"created,omitempty"`
+	Model   string   `json:"model,omitempty"`
+	Usage   Usage    `json:"usage"`
+	Choices []Choice `json:"choices"`
+	Error   *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// Choice is one of the replies of a chat completion
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// OpenAIClient talks to the OpenAI API or any server with the same
+// /chat/completions endpoint
+type OpenAIClient struct {
+	Endpoint string
+	APIKey   string
+	// Organization is sent as the OpenAI-Organization header when set
+	Organization string
+	HTTPClient   *http.Client
+}
+
+// NewOpenAIClient creates a client for the API at endpoint, which defaults to
+// DefaultOpenAIEndpoint. Requests time out after DefaultTimeout.
+func NewOpenAIClient(endpoint, apiKey string) *OpenAIClient {
+	if endpoint == "" {
+		endpoint = DefaultOpenAIEndpoint
+	}
+	return &OpenAIClient{
+		Endpoint:   strings.TrimRight(endpoint, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// Complete sends the conversation to /chat/completions
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := ChatCompletionRequest{
+		Model:       req.Model,
+		Messages:    req.Messages,
+		Temperature: req.Options.Temperature,
+		MaxTokens:   req.Options.MaxTokens,
+	}
+	if req.Options.JSON {
+		body.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+	jsonBody, err := json.Marshal(withExtra(body, req.Options.Extra))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if c.Organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", c.Organization)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result ChatCompletionResponse
+	decodeErr := json.Unmarshal(data, &result)
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if result.Error != nil {
+		return nil, &APIError{Provider: "OpenAI", StatusCode: resp.StatusCode, Type: result.Error.Type, Message: result.Error.Message, RetryAfter: retryAfter}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{Provider: "OpenAI", StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data)), RetryAfter: retryAfter}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	if len(result.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	model := result.Model
+	if model == "" {
+		model = req.Model
+	}
+	return &Response{
+		Content:      result.Choices[0].Message.Content,
+		Model:        model,
+		Usage:        result.Usage,
+		FinishReason: result.Choices[0].FinishReason,
+	}, nil
+}
+
+// withExtra adds extra top-level fields, such as top_p or seed, to a request body
+func withExtra(body ChatCompletionRequest, extra map[string]interface{}) interface{} {
+	if len(extra) == 0 {
+		return body
+	}
+	fields := make(map[string]interface{}, len(extra)+5)
+	for key, value := range extra {
+		fields[key] = value
+	}
+	data, _ := json.Marshal(body)
+	json.Unmarshal(data, &fields)
+	return fields
+}
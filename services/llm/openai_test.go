@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOpenAI(t *testing.T, handler http.HandlerFunc) *OpenAIClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOpenAIClient(server.URL+"/", "test-key")
+}
+
+func TestOpenAIClientComplete(t *testing.T) {
+	var req ChatCompletionRequest
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("expected /chat/completions, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("missing or invalid Authorization header %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Model:   "gpt-4o-2024-08-06",
+			Usage:   Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20},
+			Choices: []Choice{{Message: Message{Role: RoleAssistant, Content: `{"name": "Cellar"}`}, FinishReason: "stop"}},
+		})
+	})
+
+	resp, err := client.Complete(context.Background(), Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: RoleSystem, Content: "Reply with JSON"}, {Role: RoleUser, Content: "a cellar"}},
+		Options:  Options{Temperature: 0.2, MaxTokens: 100, JSON: true},
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Content != `{"name": "Cellar"}` || resp.Model != "gpt-4o-2024-08-06" || resp.FinishReason != "stop" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.Usage.TotalTokens != 20 {
+		t.Errorf("expected 20 tokens used, got %+v", resp.Usage)
+	}
+	if req.Model != "gpt-4o" || len(req.Messages) != 2 || req.Temperature != 0.2 || req.MaxTokens != 100 {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+		t.Errorf("expected a json_object response format, got %+v", req.ResponseFormat)
+	}
+}
+
+func TestOpenAIClientExtraOptions(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(ChatCompletionResponse{Choices: []Choice{{Message: Message{Content: "ok"}}}})
+	})
+
+	resp, err := client.Complete(context.Background(), Request{
+		Model:    "local",
+		Messages: []Message{{Role: RoleUser, Content: "hello"}},
+		Options:  Options{Temperature: 0.5, Extra: map[string]interface{}{"top_p": 0.9}},
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if body["top_p"] != 0.9 || body["temperature"] != 0.5 || body["model"] != "local" {
+		t.Errorf("expected extra options next to the request fields, got %v", body)
+	}
+	if resp.Model != "local" {
+		t.Errorf("expected the requested model when the server reports none, got %q", resp.Model)
+	}
+}
+
+func TestOpenAIClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		message string
+	}{
+		{
+			name: "error body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota"}}`))
+			},
+			status:  http.StatusTooManyRequests,
+			message: "exceeded your current quota",
+		},
+		{
+			name: "plain text",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			},
+			status:  http.StatusServiceUnavailable,
+			message: "Service unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestOpenAI(t, tt.handler).Complete(context.Background(), Request{Model: "gpt-4o"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || !strings.Contains(apiErr.Message, tt.message) {
+				t.Errorf("unexpected error %+v", apiErr)
+			}
+		})
+	}
+
+	empty := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": []}`))
+	})
+	if _, err := empty.Complete(context.Background(), Request{Model: "gpt-4o"}); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("expected ErrEmptyResponse, got %v", err)
+	}
+
+	malformed := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"invalid": json`))
+	})
+	if _, err := malformed.Complete(context.Background(), Request{Model: "gpt-4o"}); err == nil || !strings.Contains(err.Error(), "failed to decode response") {
+		t.Errorf("expected a decode error, got %v", err)
+	}
+}
+
+func TestOpenAIClientTimeout(t *testing.T) {
+	if timeout := NewOpenAIClient("", "test-key").HTTPClient.Timeout; timeout != DefaultTimeout {
+		t.Errorf("expected the default timeout, got %s", timeout)
+	}
+
+	release := make(chan struct{})
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer close(release)
+	client.HTTPClient.Timeout = 50 * time.Millisecond
+	_, err := client.Complete(context.Background(), Request{Model: "gpt-4o", Messages: []Message{{Role: RoleUser, Content: "hello"}}})
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRequestLimit is returned when a request is not allowed by the requests per minute in time
+	ErrRequestLimit = errors.New("request rate limit exceeded")
+	// ErrTokenLimit is returned when a request does not fit in the tokens per minute in time
+	ErrTokenLimit = errors.New("token limit exceeded")
+)
+
+// RateLimiter limits the requests and tokens sent per minute with a token
+// bucket for each: a bucket holds up to a minute's worth and refills
+// steadily, so bursts are allowed up to the limit and then paced. A limit of
+// zero means no limit. A RateLimiter is safe for concurrent use and meant to
+// be shared by every client of an endpoint.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+}
+
+// NewRateLimiter creates a rate limiter allowing requestsPerMinute requests
+// and tokensPerMinute tokens a minute; zero means no limit
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: newBucket(requestsPerMinute, now),
+		tokens:   newBucket(tokensPerMinute, now),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 0)
+}
+
+// WaitN blocks until a request of the given number of tokens is allowed or
+// ctx is done. The request and its tokens are reserved as soon as it is
+// called, so concurrent callers are served in turn. It fails at once
+// instead of waiting past the deadline of ctx.
+func (l *RateLimiter) WaitN(ctx context.Context, tokens int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.tokens.perMinute > 0 && float64(tokens) > l.tokens.perMinute {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: the request needs %d tokens, the limit is %.0f a minute", ErrTokenLimit, tokens, l.tokens.perMinute)
+	}
+	now := time.Now()
+	requestWait := l.requests.wait(1, now)
+	tokenWait := l.tokens.wait(float64(tokens), now)
+	delay := max(requestWait, tokenWait)
+	if deadline, ok := ctx.Deadline(); ok && delay > deadline.Sub(now) {
+		l.mu.Unlock()
+		if tokenWait > requestWait {
+			return fmt.Errorf("%w: %d tokens are free in %s", ErrTokenLimit, tokens, tokenWait.Round(time.Millisecond))
+		}
+		return fmt.Errorf("%w: the next request is allowed in %s", ErrRequestLimit, requestWait.Round(time.Millisecond))
+	}
+	l.requests.take(1)
+	l.tokens.take(float64(tokens))
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the reservation back to the callers still waiting
+		l.mu.Lock()
+		l.requests.give(1)
+		l.tokens.give(float64(tokens))
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// CheckLimit allows a request if the requests per minute allow it right
+// now, and returns ErrRequestLimit otherwise
+func (l *RateLimiter) CheckLimit() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.requests.wait(1, time.Now()) > 0 {
+		return ErrRequestLimit
+	}
+	l.requests.take(1)
+	return nil
+}
+
+// AddTokens charges tokens that were used without waiting for them, e.g. by
+// a request sent through another limiter
+func (l *RateLimiter) AddTokens(tokens int) {
+	l.Reconcile(0, tokens)
+}
+
+// Reconcile corrects the tokens reserved for a request by WaitN with the
+// tokens it actually used, as reported by the server
+func (l *RateLimiter) Reconcile(reserved, used int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.wait(0, time.Now())
+	if used > reserved {
+		l.tokens.take(float64(used - reserved))
+	} else {
+		l.tokens.give(float64(reserved - used))
+	}
+}
+
+// bucket holds up to perMinute units and refills at perMinute units a
+// minute. Its level goes below zero when units are reserved ahead of time.
+type bucket struct {
+	perMinute float64
+	level     float64
+	updated   time.Time
+}
+
+func newBucket(perMinute int, now time.Time) bucket {
+	return bucket{perMinute: float64(perMinute), level: float64(perMinute), updated: now}
+}
+
+// wait refills the bucket and returns how long it takes until n units are free
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	if b.perMinute <= 0 {
+		return 0
+	}
+	if now.After(b.updated) {
+		b.level = math.Min(b.perMinute, b.level+now.Sub(b.updated).Minutes()*b.perMinute)
+		b.updated = now
+	}
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.perMinute * float64(time.Minute))
+}
+
+func (b *bucket) take(n float64) {
+	if b.perMinute > 0 {
+		b.level -= n
+	}
+}
+
+func (b *bucket) give(n float64) {
+	if b.perMinute > 0 {
+		b.level = math.Min(b.perMinute, b.level+n)
+	}
+}
+
+// EstimateTokens estimates the prompt tokens of a request before it is sent,
+// at about four characters a token plus the overhead of each message
+func EstimateTokens(req Request) int {
+	tokens := 3
+	for _, message := range req.Messages {
+		tokens += 4 + (len(message.Content)+3)/4
+	}
+	return tokens
+}
+
+// RateLimitClient waits for a rate limiter before every request
+type RateLimitClient struct {
+	client  Client
+	limiter *RateLimiter
+	// MaxWait, when set, is the longest a request waits for the limiter
+	// before failing with ErrRequestLimit or ErrTokenLimit
+	MaxWait time.Duration
+}
+
+// WithRateLimit wraps a client so every request first waits for the
+// limiter with an estimate of its prompt tokens plus the completion tokens it
+// may use, as servers count Options.MaxTokens against the limit too. The
+// estimate is corrected with the usage the server reports once the request
+// is done.
+func WithRateLimit(client Client, limiter *RateLimiter) *RateLimitClient {
+	return &RateLimitClient{client: client, limiter: limiter}
+}
+
+// Complete waits for the limiter and sends the request
+func (c *RateLimitClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	estimate := EstimateTokens(req) + req.Options.MaxTokens
+	waitCtx := ctx
+	if c.MaxWait > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, c.MaxWait)
+		defer cancel()
+	}
+	if err := c.limiter.WaitN(waitCtx, estimate); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Complete(ctx, req)
+	used := 0
+	if err == nil {
+		used = resp.Usage.TotalTokens
+		if used == 0 {
+			// Servers that do not report usage are charged the estimate
+			used = estimate
+		}
+	}
+	c.limiter.Reconcile(estimate, used)
+	return resp, err
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowsBursts(t *testing.T) {
+	limiter := NewRateLimiter(2, 0)
+	for i := 0; i < 2; i++ {
+		if err := limiter.CheckLimit(); err != nil {
+			t.Fatalf("request %d should be allowed: %v", i+1, err)
+		}
+	}
+	if err := limiter.CheckLimit(); !errors.Is(err, ErrRequestLimit) {
+		t.Errorf("expected the request limit, got %v", err)
+	}
+
+	unlimited := NewRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if err := unlimited.WaitN(context.Background(), 1000); err != nil {
+			t.Fatalf("expected no limit, got %v", err)
+		}
+	}
+}
+
+func TestRateLimiterWaitBlocks(t *testing.T) {
+	// 600 requests a minute free one every 100ms
+	limiter := NewRateLimiter(600, 0)
+	for i := 0; i < 600; i++ {
+		limiter.CheckLimit()
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Errorf("Wait failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected three waiters to be served in turn, took %v", elapsed)
+	}
+}
+
+func TestRateLimiterHonoursContext(t *testing.T) {
+	limiter := NewRateLimiter(0, 600)
+
+	if err := limiter.WaitN(context.Background(), 601); !errors.Is(err, ErrTokenLimit) {
+		t.Errorf("expected a request over the limit to fail, got %v", err)
+	}
+
+	limiter.AddTokens(600)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.WaitN(ctx, 100); !errors.Is(err, ErrTokenLimit) || time.Since(start) > 40*time.Millisecond {
+		t.Errorf("expected to fail at once instead of waiting past the deadline, got %v after %v", err, time.Since(start))
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := limiter.WaitN(ctx, 100); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+}
+
+func TestRateLimitClientReconcilesUsage(t *testing.T) {
+	limiter := NewRateLimiter(0, 1000)
+	inner := ClientFunc(func(ctx context.Context, req Request) (*Response, error) {
+		return &Response{Content: "ok", Usage: Usage{TotalTokens: 400}}, nil
+	})
+	client := WithRateLimit(inner, limiter)
+	client.MaxWait = 10 * time.Millisecond
+
+	req := Request{Messages: []Message{{Role: RoleUser, Content: "a short prompt"}}}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Complete(context.Background(), req); err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+	}
+	// 800 of 1000 tokens are used, whatever was estimated for the prompts
+	if _, err := client.Complete(context.Background(), Request{Messages: []Message{{Content: string(make([]byte, 1000))}}}); !errors.Is(err, ErrTokenLimit) {
+		t.Errorf("expected the token limit, got %v", err)
+	}
+	if _, err := client.Complete(context.Background(), req); err != nil {
+		t.Errorf("expected a small request to fit, got %v", err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	req := Request{Messages: []Message{
+		{Role: RoleSystem, Content: "You describe rooms."},
+		{Role: RoleUser, Content: "A flooded crypt"},
+	}}
+	if got := EstimateTokens(req); got != 3+4+5+4+4 {
+		t.Errorf("unexpected estimate %d", got)
+	}
+}
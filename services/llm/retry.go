@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy retries failed requests with exponential backoff and jitter
+type RetryPolicy struct {
+	// MaxRetries is how often a request is retried after the first attempt
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles with every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, except for a longer Retry-After
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy retries twice, after about half a second and a second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     2,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Backoff returns the delay before the given retry, counted from one. The
+// delay is picked at random from the upper half of the exponential delay, so
+// clients that failed together do not retry together.
+func (p RetryPolicy) Backoff(retry int, rng *rand.Rand) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rng.Int63n(int64(delay/2)+1))
+}
+
+// Retryable reports whether a failed request may succeed when sent again:
+// rate limits, server errors and network failures are, cancellations and
+// rejected requests are not
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var fallbackErr *FallbackError
+	if errors.As(err, &fallbackErr) {
+		// A chain is worth asking again when any of its hops may recover
+		for _, hopErr := range fallbackErr.errs {
+			if Retryable(hopErr) {
+				return true
+			}
+		}
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryAfter returns the delay a server asked for in its error, if any
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header, given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// RetryClient sends requests again when they fail with a retryable error
+type RetryClient struct {
+	client Client
+	policy RetryPolicy
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// WithRetry wraps a client so failed requests are retried by policy. It
+// waits at least as long as a server's Retry-After, and gives up instead
+// of waiting past the deadline of the request's context.
+func WithRetry(client Client, policy RetryPolicy) *RetryClient {
+	return &RetryClient{
+		client: client,
+		policy: policy,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Complete sends the request until it succeeds, fails for good or runs out of retries.
+// The failed attempts are recorded in the response's Attempts before the one that succeeded.
+func (c *RetryClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	var failed []Attempt
+	for retry := 0; ; retry++ {
+		resp, err := c.client.Complete(ctx, req)
+		if err == nil {
+			resp.Attempts = append(failed, resp.attempts(req)...)
+			return resp, nil
+		}
+		if retry >= c.policy.MaxRetries || !Retryable(err) {
+			return nil, err
+		}
+		failed = append(failed, failedAttempts(req, err)...)
+
+		c.mu.Lock()
+		delay := c.policy.Backoff(retry+1, c.rng)
+		c.mu.Unlock()
+		if after := retryAfter(err); after > delay {
+			delay = after
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedClient fails with the given errors in turn, then answers
+type scriptedClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *scriptedClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	c.calls++
+	if c.calls <= len(c.errs) {
+		return nil, c.errs[c.calls-1]
+	}
+	return &Response{Content: "ok", Model: req.Model}, nil
+}
+
+var fastRetries = RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestRetryableErrors(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{Provider: "openai", StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{Provider: "openai", StatusCode: http.StatusBadGateway}, true},
+		{&APIError{Provider: "openai", StatusCode: http.StatusUnauthorized}, false},
+		{context.Canceled, false},
+		{errors.New("invalid JSON"), false},
+		{&FallbackError{errs: []error{errors.New("no"), &APIError{StatusCode: 503}}}, true},
+	} {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffGrowsWithinBounds(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	rng := rand.New(rand.NewSource(1))
+	for retry, max := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 6: 300} {
+		max *= time.Millisecond
+		delay := policy.Backoff(retry, rng)
+		if delay < max/2 || delay > max {
+			t.Errorf("retry %d: expected a delay between %v and %v, got %v", retry, max/2, max, delay)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("2"); got != 2*time.Second {
+		t.Errorf("expected 2s, got %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); got < 59*time.Minute {
+		t.Errorf("expected about an hour, got %v", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("expected no delay, got %v", got)
+	}
+}
+
+func TestRetryClientRecordsAttempts(t *testing.T) {
+	inner := &scriptedClient{errs: []error{&APIError{Provider: "openai", StatusCode: 500, Message: "boom"}}}
+	resp, err := WithRetry(inner, fastRetries).Complete(context.Background(), Request{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected one retry, got %d calls", inner.calls)
+	}
+	if len(resp.Attempts) != 2 || resp.Attempts[0].Error == "" || resp.Attempts[1].Error != "" {
+		t.Errorf("expected a failed and a successful attempt, got %+v", resp.Attempts)
+	}
+}
+
+func TestRetryClientGivesUp(t *testing.T) {
+	rejected := &scriptedClient{errs: []error{&APIError{Provider: "openai", StatusCode: 400}}}
+	if _, err := WithRetry(rejected, fastRetries).Complete(context.Background(), Request{}); err == nil || rejected.calls != 1 {
+		t.Errorf("expected a rejected request not to be retried, got %d calls and %v", rejected.calls, err)
+	}
+
+	overloaded := &scriptedClient{errs: []error{&APIError{StatusCode: 503}, &APIError{StatusCode: 503}, &APIError{StatusCode: 503}}}
+	if _, err := WithRetry(overloaded, fastRetries).Complete(context.Background(), Request{}); err == nil || overloaded.calls != 3 {
+		t.Errorf("expected to give up after 2 retries, got %d calls and %v", overloaded.calls, err)
+	}
+
+	// A Retry-After beyond the deadline fails at once instead of waiting
+	limited := &scriptedClient{errs: []error{&APIError{StatusCode: 429, RetryAfter: time.Minute}}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := WithRetry(limited, fastRetries).Complete(ctx, Request{})
+	if err == nil || !strings.Contains(err.Error(), "429") || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the rate limit error without waiting, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestRetryClientHonoursRetryAfter(t *testing.T) {
+	limited := &scriptedClient{errs: []error{&APIError{StatusCode: 429, RetryAfter: 50 * time.Millisecond}}}
+	start := time.Now()
+	if _, err := WithRetry(limited, fastRetries).Complete(context.Background(), Request{}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected to wait for Retry-After, retried after %v", elapsed)
+	}
+}
@@ -3,3 +3,8 @@ module github.com/textadventureservices/master
 go 1.21
 
 require github.com/gorilla/mux v1.8.1
+
+require textadventureservices v0.0.0
+
+// The template provider comes from the services module in this repository
+replace textadventureservices => ../..
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/textadventureservices/master/ollama"
 )
 
 // ServiceInfo represents a registered service
@@ -27,20 +28,31 @@ type GameState struct {
 	State       map[string]interface{} `json:"state"`
 }
 
+// CommandProcessor turns a player command into a summary of what happens
+// and the updated game state
+type CommandProcessor interface {
+	ProcessGameCommand(input string, gameState map[string]interface{}) (string, map[string]interface{}, error)
+}
+
 // MasterService is the main orchestrator
 type MasterService struct {
 	services    map[string]*ServiceInfo
 	gameState   *GameState
 	servicesMux sync.RWMutex
 	stateMux    sync.RWMutex
+	// processor answers player commands
+	processor CommandProcessor
 }
 
+// NewMasterService creates a master service that answers player commands
+// offline with the template provider
 func NewMasterService() *MasterService {
 	return &MasterService{
 		services:  make(map[string]*ServiceInfo),
 		gameState: &GameState{
 			State: make(map[string]interface{}),
 		},
+		processor: newTemplateProcessor(0),
 	}
 }
 
@@ -96,7 +108,7 @@ func (ms *MasterService) getServicesStatus(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(services)
 }
 
-// processInput handles user input processing via Ollama
+// processInput handles user input processing through the command processor
 func (ms *MasterService) processInput(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserInput    string                 `json:"userInput"`
@@ -107,11 +119,17 @@ func (ms *MasterService) processInput(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Implement Ollama integration
-	// For now, return a mock response
+	summary, updatedState, err := ms.processor.ProcessGameCommand(req.UserInput, req.CurrentState)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if updatedState == nil {
+		updatedState = req.CurrentState
+	}
 	response := map[string]interface{}{
-		"updatedState": req.CurrentState,
-		"actionSummary": "Command processed successfully",
+		"updatedState": updatedState,
+		"actionSummary": summary,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -157,6 +175,10 @@ func (ms *MasterService) healthCheck(ctx context.Context) {
 
 func main() {
 	ms := NewMasterService()
+	// MASTER_PROVIDER=ollama answers commands with Ollama instead of offline
+	if os.Getenv("MASTER_PROVIDER") == "ollama" {
+		ms.processor = ollama.NewOllamaClient()
+	}
 	router := mux.NewRouter()
 
 	// Service management endpoints
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -255,3 +256,39 @@ func TestGetGameState(t *testing.T) {
 		t.Errorf("got game state = %+v, want %+v", response, initialState)
 	}
 }
+
+func TestProcessInputOffline(t *testing.T) {
+	ms := NewMasterService()
+	router := http.NewServeMux()
+	router.HandleFunc("/api/v1/process-input", ms.processInput)
+
+	answer := func() map[string]interface{} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"userInput":    "take lamp",
+			"currentState": map[string]interface{}{"location": "start_room"},
+		})
+		req := httptest.NewRequest("POST", "/api/v1/process-input", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("processInput() status = %v, want %v", w.Code, http.StatusOK)
+		}
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	first, second := answer(), answer()
+	summary, _ := first["actionSummary"].(string)
+	if !strings.Contains(summary, "lamp") {
+		t.Errorf("Expected the command to be answered offline, got %q", summary)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same answer twice, got %v and %v", first, second)
+	}
+	if state, _ := first["updatedState"].(map[string]interface{}); state["location"] != "start_room" {
+		t.Errorf("Expected the game state back, got %v", first["updatedState"])
+	}
+}
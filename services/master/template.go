@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"textadventureservices/services/ai"
+)
+
+// templateProcessor answers player commands without a model, using the
+// template provider of the AI service. The game state is returned unchanged.
+type templateProcessor struct {
+	provider *ai.TemplateProvider
+}
+
+func newTemplateProcessor(seed int64) templateProcessor {
+	return templateProcessor{provider: ai.NewTemplateProvider(seed)}
+}
+
+// ProcessGameCommand answers a command such as "take lamp" or "n"
+func (p templateProcessor) ProcessGameCommand(input string, gameState map[string]interface{}) (string, map[string]interface{}, error) {
+	resp, err := p.provider.ProcessInput(context.Background(), ai.ProcessInputRequest{Input: input, GameState: gameState})
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.ActionSummary, gameState, nil
+}
@@ -0,0 +1,127 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxExpansionDepth stops recursive grammars from expanding forever
+const maxExpansionDepth = 16
+
+// Grammar is a Tracery-style grammar. Each symbol maps to alternative
+// expansions, and "#symbol#" inside an expansion is replaced by a randomly
+// chosen expansion of symbol. Modifiers follow the symbol name:
+// "#object.a#" prefixes an article and "#place.capitalize#" capitalizes the
+// first letter. Unknown symbols are left in place.
+type Grammar map[string][]string
+
+// LoadGrammar reads a grammar from a JSON file mapping symbols to expansions
+func LoadGrammar(path string) (Grammar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grammar file: %w", err)
+	}
+	var grammar Grammar
+	if err := json.Unmarshal(data, &grammar); err != nil {
+		return nil, fmt.Errorf("failed to parse grammar file: %w", err)
+	}
+	for symbol, expansions := range grammar {
+		if len(expansions) == 0 {
+			return nil, fmt.Errorf("grammar symbol %q has no expansions", symbol)
+		}
+	}
+	return grammar, nil
+}
+
+// Merge returns a new grammar with the rules of other replacing those of g
+func (g Grammar) Merge(other Grammar) Grammar {
+	merged := make(Grammar, len(g)+len(other))
+	for symbol, expansions := range g {
+		merged[symbol] = expansions
+	}
+	for symbol, expansions := range other {
+		merged[symbol] = expansions
+	}
+	return merged
+}
+
+// With returns a copy of the grammar in which symbol expands to one of values
+func (g Grammar) With(symbol string, values ...string) Grammar {
+	return g.Merge(Grammar{symbol: values})
+}
+
+// Expand replaces every "#symbol#" in text, choosing expansions with rng
+func (g Grammar) Expand(rng *rand.Rand, text string) string {
+	return g.expand(rng, text, 0)
+}
+
+// Flatten expands the symbol itself, e.g. Flatten(rng, "origin")
+func (g Grammar) Flatten(rng *rand.Rand, symbol string) string {
+	return g.Expand(rng, "#"+symbol+"#")
+}
+
+func (g Grammar) expand(rng *rand.Rand, text string, depth int) string {
+	var out strings.Builder
+	for {
+		start := strings.IndexByte(text, '#')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '#')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		out.WriteString(text[:start])
+		out.WriteString(g.expandTag(rng, text[start:end+1], depth))
+		text = text[end+1:]
+	}
+	out.WriteString(text)
+	return out.String()
+}
+
+// expandTag expands a single "#symbol.modifier#" tag
+func (g Grammar) expandTag(rng *rand.Rand, tag string, depth int) string {
+	parts := strings.Split(tag[1:len(tag)-1], ".")
+	expansions, ok := g[parts[0]]
+	if !ok || len(expansions) == 0 || depth >= maxExpansionDepth {
+		return tag
+	}
+
+	value := g.expand(rng, expansions[rng.Intn(len(expansions))], depth+1)
+	for _, modifier := range parts[1:] {
+		switch modifier {
+		case "capitalize":
+			value = capitalize(value)
+		case "a":
+			value = withArticle(value)
+		}
+	}
+	return value
+}
+
+// capitalize upper-cases the first letter of s
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// withArticle prefixes "a" or "an" to s
+func withArticle(s string) string {
+	if s == "" {
+		return s
+	}
+	if strings.ContainsRune("aeiouAEIOU", rune(s[0])) {
+		return "an " + s
+	}
+	return "a " + s
+}
@@ -0,0 +1,28 @@
+package template
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGrammarExpand(t *testing.T) {
+	grammar := Grammar{
+		"origin": {"#animal.a.capitalize# sees #animal.a#."},
+		"animal": {"owl"},
+		"loop":   {"#loop#"},
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	if got := grammar.Flatten(rng, "origin"); got != "An owl sees an owl." {
+		t.Errorf("unexpected expansion %q", got)
+	}
+	if got := grammar.Expand(rng, "a #missing# tag"); got != "a #missing# tag" {
+		t.Errorf("expected unknown symbols to be kept, got %q", got)
+	}
+	if got := grammar.Flatten(rng, "loop"); got != "#loop#" {
+		t.Errorf("expected recursion to stop, got %q", got)
+	}
+	if got := grammar.With("animal", "cat").Flatten(rng, "origin"); got != "A cat sees a cat." {
+		t.Errorf("unexpected expansion with a bound symbol %q", got)
+	}
+}
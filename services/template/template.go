@@ -0,0 +1,312 @@
+// Package template writes text adventure prose without a model, by
+// expanding seeded Tracery-style grammars themed by the words of a prompt.
+// Its text is plausible, deterministic for a seed and prompt, and needs no
+// network; both the AI service and world generation answer offline with it.
+package template
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Model is the model name reported for text written by a Generator
+const Model = "template"
+
+// base holds the rules shared by every theme. Themes replace the
+// vocabulary symbols (walls, object, sound, ...) and keep the structure.
+var base = Grammar{
+	"description": {
+		"#opening# #feature# #detail# #sense#",
+		"#opening# #detail# #feature# #sense#",
+	},
+	"opening": {
+		"You stand in #subject#.",
+		"You find yourself in #subject#.",
+		"#subject.capitalize# opens up around you.",
+		"This corner of #subject# is #adjective# and #adjective#.",
+	},
+	"feature": {
+		"You are surrounded by #walls#.",
+		"The floor is covered in #covering#.",
+		"#light.capitalize# falls over everything.",
+	},
+	"detail": {
+		"#object.a.capitalize# lies half-hidden in a corner.",
+		"Someone has left #object.a# here.",
+		"#object.a.capitalize# stands against the far wall.",
+	},
+	"sense": {
+		"The air smells of #smell#.",
+		"You hear #sound#.",
+		"Somewhere nearby you can make out #sound#.",
+	},
+	"ambient":    {"#sound.capitalize#", "The smell of #smell#", "#light.capitalize#"},
+	"name":       {"#name_adj# #name_noun#", "The #name_adj# #name_noun#"},
+	"exit":       {"#passage.a.capitalize# leads #direction#.", "#passage.a.capitalize# heads #direction#, #exit_state#."},
+	"exit_state": {"half-blocked by rubble", "worn smooth by many feet", "barely wide enough to pass", "dark beyond a few steps"},
+	"enhance":    {"#subject_full#, #atmosphere#"},
+	"atmosphere": {"where every shadow hides a secret", "long abandoned and slowly crumbling", "quiet in a way that feels deliberate", "older than anyone remembers"},
+	"inspect": {
+		"#item.a.capitalize#, #wear#. #inspect_detail#",
+		"You take a closer look at the #item#. It is #wear#. #inspect_detail#",
+	},
+	"wear":           {"worn with age", "covered in a fine layer of dust", "surprisingly well kept", "older than it first appears"},
+	"inspect_detail": {"Nothing about it seems out of place.", "Faint markings run along one side.", "It is heavier than it looks.", "Someone has clearly handled it recently."},
+	"adjective":      {"quiet", "dim", "cold", "still", "cramped", "wide"},
+	"light":          {"a thin grey light", "the flicker of a distant flame", "a pale glow"},
+
+	// Vocabulary; themes replace these
+	"walls":     {"walls of rough stone", "crumbling brick walls", "dark timber walls"},
+	"covering":  {"a layer of dust", "scattered straw", "broken tiles"},
+	"object":    {"lantern", "coil of rope", "wooden crate", "brass key", "dusty book", "iron chest", "candle", "old map"},
+	"sound":     {"dripping water", "distant footsteps", "the creak of old wood"},
+	"smell":     {"dust", "damp stone", "old smoke"},
+	"name_adj":  {"Silent", "Dusty", "Forgotten", "Narrow", "Cold"},
+	"name_noun": {"Hall", "Chamber", "Passage", "Room", "Vault"},
+	"passage":   {"doorway", "narrow corridor", "stone archway"},
+}
+
+// theme is vocabulary used when a prompt mentions one of its keywords
+type theme struct {
+	name     string
+	keywords []string
+	grammar  Grammar
+}
+
+// themes are checked in order; the first theme with a matching keyword wins
+var themes = []theme{
+	{
+		name:     "cave",
+		keywords: []string{"cave", "cavern", "caves", "grotto", "tunnel", "tunnels", "mine", "underground"},
+		grammar: Grammar{
+			"walls":     {"walls of damp limestone", "glistening rock", "rough-hewn stone"},
+			"covering":  {"loose gravel", "slick mud", "pale moss"},
+			"object":    {"stalagmite", "rusty pickaxe", "glowing mushroom", "coil of rope", "miner's lantern", "pile of bones", "ore cart"},
+			"sound":     {"dripping water", "the flutter of bats", "a distant rumble"},
+			"smell":     {"wet earth", "minerals", "cold stone"},
+			"name_adj":  {"Dripping", "Hollow", "Echoing", "Crystal", "Sunless"},
+			"name_noun": {"Grotto", "Cavern", "Gallery", "Hollow", "Shaft"},
+			"passage":   {"narrow crawlway", "low tunnel", "natural arch"},
+		},
+	},
+	{
+		name:     "underwater",
+		keywords: []string{"underwater", "sunken", "ocean", "sea", "reef", "submerged"},
+		grammar: Grammar{
+			"walls":     {"coral-crusted stone", "barnacled timbers", "walls of smooth sea glass"},
+			"covering":  {"fine white sand", "swaying kelp", "broken shells"},
+			"object":    {"pearl", "rusted anchor", "sea chest", "conch shell", "tangled net", "diving helmet"},
+			"sound":     {"the slow surge of the tide", "bubbles rising", "the groan of shifting water"},
+			"smell":     {"salt", "brine", "seaweed"},
+			"name_adj":  {"Sunken", "Drowned", "Coral", "Tidal", "Pearl"},
+			"name_noun": {"Grotto", "Hall", "Reef", "Vault", "Lagoon"},
+			"passage":   {"flooded arch", "kelp-hung passage", "submerged doorway"},
+		},
+	},
+	{
+		name:     "scifi",
+		keywords: []string{"station", "spaceship", "starship", "ship", "space", "alien", "laboratory", "lab", "reactor", "orbital", "cyberpunk"},
+		grammar: Grammar{
+			"walls":     {"scuffed alloy panels", "humming steel bulkheads", "cracked composite walls"},
+			"covering":  {"loose cabling", "scorched deck plating", "flickering floor panels"},
+			"object":    {"data pad", "plasma cutter", "access card", "med kit", "cargo crate", "broken drone", "oxygen canister"},
+			"sound":     {"the hum of life support", "a warning chime", "the ticking of cooling metal"},
+			"smell":     {"ozone", "recycled air", "burnt circuitry"},
+			"name_adj":  {"Derelict", "Sealed", "Primary", "Auxiliary", "Quarantined"},
+			"name_noun": {"Bay", "Module", "Deck", "Lab", "Airlock"},
+			"passage":   {"bulkhead door", "maintenance shaft", "sliding hatch"},
+			"light":     {"red emergency lighting", "the glow of a dead console", "a strobing warning light"},
+		},
+	},
+	{
+		name:     "horror",
+		keywords: []string{"mansion", "haunted", "crypt", "graveyard", "victorian", "manor", "tomb", "asylum", "cursed"},
+		grammar: Grammar{
+			"walls":     {"peeling wallpaper", "rotting panelling", "cold marble walls"},
+			"covering":  {"a threadbare carpet", "shattered glass", "drifts of dead leaves"},
+			"object":    {"tarnished mirror", "music box", "candelabra", "faded portrait", "locked diary", "porcelain doll", "iron key"},
+			"sound":     {"a clock ticking somewhere", "whispers just out of earshot", "the creak of floorboards"},
+			"smell":     {"mildew", "candle wax", "something long dead"},
+			"name_adj":  {"Shrouded", "Silent", "Weeping", "Forgotten", "Drowned"},
+			"name_noun": {"Parlour", "Gallery", "Nursery", "Crypt", "Study"},
+			"passage":   {"warped door", "servants' passage", "crooked staircase"},
+		},
+	},
+	{
+		name:     "castle",
+		keywords: []string{"castle", "keep", "fortress", "palace", "citadel", "tower", "throne"},
+		grammar: Grammar{
+			"walls":     {"grey granite walls", "banner-hung stone", "whitewashed walls"},
+			"covering":  {"worn flagstones", "rushes", "a faded carpet"},
+			"object":    {"suit of armour", "torch", "tapestry", "longsword", "goblet", "iron chest", "shield"},
+			"sound":     {"the wind against the battlements", "distant hoofbeats", "the clank of armour"},
+			"smell":     {"torch smoke", "old wax", "horses"},
+			"name_adj":  {"Great", "Royal", "Western", "Lord's", "Guard"},
+			"name_noun": {"Hall", "Armoury", "Gatehouse", "Chapel", "Solar"},
+			"passage":   {"arched doorway", "spiral stair", "vaulted corridor"},
+		},
+	},
+	{
+		name:     "forest",
+		keywords: []string{"forest", "wood", "woods", "grove", "jungle", "glade", "swamp", "marsh"},
+		grammar: Grammar{
+			"walls":     {"moss-covered trunks", "tangled roots", "thick undergrowth"},
+			"covering":  {"fallen leaves", "soft moss", "twisting roots"},
+			"object":    {"fallen log", "mushroom ring", "bird's nest", "hunting bow", "old signpost", "wild berries", "hollow stump"},
+			"sound":     {"birdsong", "rustling leaves", "a stream nearby"},
+			"smell":     {"pine", "damp earth", "wildflowers"},
+			"name_adj":  {"Whispering", "Mossy", "Tangled", "Sunlit", "Ancient"},
+			"name_noun": {"Glade", "Thicket", "Clearing", "Grove", "Hollow"},
+			"passage":   {"winding path", "deer trail", "gap between the trees"},
+			"light":     {"dappled sunlight", "a green-tinged gloom", "shafts of light through the canopy"},
+		},
+	},
+}
+
+// describedObject finds the object in prompts such as "Describe the 'lamp' in
+// detail" or "Describe the lamp in one or two sentences"
+var describedObject = regexp.MustCompile(`(?i)^describe (?:the |an? )?'?(.+?)'?(?: in [^.]*)?(?:\.|$)`)
+
+// Generator writes text from the built-in grammars, seeded so the same seed
+// and prompt always give the same text
+type Generator struct {
+	seed int64
+	// overrides replace rules in every theme; nil keeps the built-in rules
+	overrides Grammar
+	// room is the ID of the room text is written for, if any
+	room string
+}
+
+// New creates a generator with the given seed whose grammars have the rules
+// of overrides replaced; overrides may be nil
+func New(seed int64, overrides Grammar) *Generator {
+	return &Generator{seed: seed, overrides: overrides}
+}
+
+// ForRoom returns a generator writing the text of the room with the given
+// ID, so rooms with the same prompt are described differently
+func (g *Generator) ForRoom(room string) *Generator {
+	forRoom := *g
+	forRoom.room = room
+	return &forRoom
+}
+
+// Rand returns a generator seeded by the seed, the kind of text, such as
+// "description", the prompt and the room, so text does not depend on the
+// order it is asked for in
+func (g *Generator) Rand(kind, prompt string) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s\x00%s", g.seed, kind, prompt)
+	if g.room != "" {
+		fmt.Fprintf(h, "\x00%s", g.room)
+	}
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// Grammar returns the grammar for the theme a prompt mentions, with the
+// prompt's subject bound to the subject symbols
+func (g *Generator) Grammar(prompt string) Grammar {
+	grammar := base
+	if theme, ok := matchTheme(prompt); ok {
+		grammar = grammar.Merge(theme.grammar)
+	}
+	if g.overrides != nil {
+		grammar = grammar.Merge(g.overrides)
+	}
+	return grammar.Merge(Grammar{
+		"subject":      {promptSubject(prompt)},
+		"subject_full": {strings.TrimSpace(prompt)},
+	})
+}
+
+// matchTheme returns the first theme whose keywords appear in the prompt
+func matchTheme(prompt string) (theme, bool) {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		words[word] = true
+	}
+	for _, candidate := range themes {
+		for _, keyword := range candidate.keywords {
+			if words[keyword] {
+				return candidate, true
+			}
+		}
+	}
+	return theme{}, false
+}
+
+// promptSubject turns the start of a prompt such as "A mysterious castle -
+// Eastern Wing, in the Crypt" into a phrase like "the mysterious castle"
+func promptSubject(prompt string) string {
+	subject := strings.TrimSpace(prompt)
+	if i := strings.IndexAny(subject, ",.(:"); i >= 0 {
+		subject = subject[:i]
+	}
+	if i := strings.Index(subject, " - "); i >= 0 {
+		subject = subject[:i]
+	}
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return "a quiet place"
+	}
+
+	lower := strings.ToLower(subject)
+	for _, article := range []string{"a ", "an ", "the "} {
+		if strings.HasPrefix(lower, article) {
+			return "the " + subject[len(article):]
+		}
+	}
+	return "the " + subject
+}
+
+// PromptLabel returns the part of a prompt after " - ", such as "Eastern Wing"
+func PromptLabel(prompt string) string {
+	i := strings.Index(prompt, " - ")
+	if i < 0 {
+		return ""
+	}
+	label := prompt[i+3:]
+	if j := strings.IndexAny(label, ",.("); j >= 0 {
+		label = label[:j]
+	}
+	return strings.TrimSpace(label)
+}
+
+// Describe describes the place in the prompt, or the object when the prompt
+// asks for one, as in "Describe the 'lamp' in detail"
+func (g *Generator) Describe(prompt string) string {
+	rng := g.Rand("description", prompt)
+	grammar := g.Grammar(prompt)
+	if match := describedObject.FindStringSubmatch(prompt); match != nil {
+		return grammar.With("item", match[1]).Flatten(rng, "inspect")
+	}
+	return grammar.Flatten(rng, "description")
+}
+
+// Enhance adds an atmospheric clause to the prompt
+func (g *Generator) Enhance(prompt string) string {
+	return g.Grammar(prompt).Flatten(g.Rand("enhance", prompt), "enhance")
+}
+
+// Objects picks two to four distinct objects from the theme of the scene
+func (g *Generator) Objects(scene string) []string {
+	rng := g.Rand("objects", scene)
+	grammar := g.Grammar(scene)
+
+	candidates := grammar["object"]
+	count := 2 + rng.Intn(3)
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	objects := make([]string, 0, count)
+	for _, i := range rng.Perm(len(candidates))[:count] {
+		objects = append(objects, grammar.Expand(rng, candidates[i]))
+	}
+	return objects
+}
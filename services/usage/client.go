@@ -0,0 +1,37 @@
+package usage
+
+import (
+	"context"
+
+	"textadventureservices/services/llm"
+)
+
+// Client records the usage of every request sent through it with a tracker
+type Client struct {
+	client  llm.Client
+	tracker *Tracker
+}
+
+// Track wraps a client so the usage of its replies is recorded for the
+// scope of the request's context, and requests of sessions that have used
+// up their budget are refused with ErrBudgetExceeded
+func Track(client llm.Client, tracker *Tracker) *Client {
+	return &Client{client: client, tracker: tracker}
+}
+
+// Complete checks the session's budget, sends the request and records its usage
+func (c *Client) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := c.tracker.Check(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	c.tracker.Record(ctx, model, resp.Usage)
+	return resp, nil
+}
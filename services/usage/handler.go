@@ -0,0 +1,145 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Handler serves a tracker's usage over HTTP:
+//
+//	GET /api/v1/usage                        the usage report
+//	GET /api/v1/usage/sessions/{id}          a session's usage and budget
+//	PUT /api/v1/usage/sessions/{id}/budget   set a session's budget
+//	GET /metrics                             usage in the Prometheus text format
+type Handler struct {
+	tracker *Tracker
+}
+
+// NewHandler creates a handler for a tracker
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// RegisterRoutes adds the usage routes to a mux
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/usage", h.HandleReport)
+	mux.HandleFunc("/api/v1/usage/sessions/", h.HandleSession)
+	mux.HandleFunc("/metrics", h.HandleMetrics)
+}
+
+// SessionUsage is the usage of a session against its budget
+type SessionUsage struct {
+	Session  string `json:"session"`
+	Usage    Totals `json:"usage"`
+	Budget   Budget `json:"budget"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+// HandleReport serves the usage report
+func (h *Handler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.tracker.Report())
+}
+
+// HandleSession serves a session's usage and sets its budget
+func (h *Handler) HandleSession(w http.ResponseWriter, r *http.Request) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/usage/sessions/"), "/")
+	if id == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+	case rest == "budget" && r.Method == http.MethodPut:
+		var budget Budget
+		if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if budget.Tokens < 0 || budget.Cost < 0 {
+			writeError(w, http.StatusBadRequest, "budget must not be negative")
+			return
+		}
+		h.tracker.SetBudget(id, budget)
+	case rest == "" || rest == "budget":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	totals, budget := h.tracker.Session(id), h.tracker.Budget(id)
+	writeJSON(w, http.StatusOK, SessionUsage{Session: id, Usage: totals, Budget: budget, Exceeded: budget.exceededBy(totals)})
+}
+
+// HandleMetrics serves the usage in the Prometheus text format
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.tracker.WriteMetrics(w)
+}
+
+// WriteMetrics writes the usage by model and command in the Prometheus text
+// format. Sessions and jobs are left to the report, as labels for them would
+// grow without bound.
+func (t *Tracker) WriteMetrics(w io.Writer) error {
+	report := t.Report()
+	metrics := []struct {
+		name, help string
+		value      func(Totals) float64
+	}{
+		{"llm_requests_total", "Model requests answered.", func(t Totals) float64 { return float64(t.Requests) }},
+		{"llm_prompt_tokens_total", "Prompt tokens sent to models.", func(t Totals) float64 { return float64(t.PromptTokens) }},
+		{"llm_completion_tokens_total", "Completion tokens returned by models.", func(t Totals) float64 { return float64(t.CompletionTokens) }},
+		{"llm_cost_dollars_total", "Estimated cost of model requests in US dollars.", func(t Totals) float64 { return t.Cost }},
+	}
+
+	var b strings.Builder
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, label := range []struct {
+			name   string
+			totals map[string]Totals
+		}{{"model", report.Models}, {"command", report.Commands}} {
+			keys := make([]string, 0, len(label.totals))
+			for key := range label.totals {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(&b, "%s{%s=\"%s\"} %g\n", metric.name, label.name, escapeLabel(key), metric.value(label.totals[key]))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabel escapes a label value for the Prometheus text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,94 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"textadventureservices/services/llm"
+)
+
+func newUsageServer(t *testing.T) (*httptest.Server, *Tracker) {
+	t.Helper()
+	tracker := NewTracker(Config{})
+	ctx := With(context.Background(), Scope{Session: "player-1", Command: "look"})
+	tracker.Record(ctx, "gpt-4o", llm.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100})
+	mux := http.NewServeMux()
+	NewHandler(tracker).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, tracker
+}
+
+func TestHandleReport(t *testing.T) {
+	server, _ := newUsageServer(t)
+	resp, err := http.Get(server.URL + "/api/v1/usage")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Total.TotalTokens != 1100 || report.Sessions["player-1"].Requests != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestHandleSessionBudget(t *testing.T) {
+	server, tracker := newUsageServer(t)
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/usage/sessions/player-1/budget", strings.NewReader(`{"tokens": 1000}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var session SessionUsage
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		t.Fatalf("Failed to decode session: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || session.Budget.Tokens != 1000 || !session.Exceeded || session.Usage.TotalTokens != 1100 {
+		t.Errorf("unexpected session %d %+v", resp.StatusCode, session)
+	}
+	if tracker.Budget("player-1").Tokens != 1000 {
+		t.Error("expected the budget to be set")
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, server.URL+"/api/v1/usage/sessions/player-1/budget", strings.NewReader(`{"tokens": -1}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a negative budget to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	server, _ := newUsageServer(t)
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, line := range []string{
+		"# TYPE llm_requests_total counter",
+		`llm_requests_total{model="gpt-4o"} 1`,
+		`llm_prompt_tokens_total{command="look"} 1000`,
+		`llm_cost_dollars_total{model="gpt-4o"} 0.0035`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected %q in the metrics:\n%s", line, body)
+		}
+	}
+	if strings.Contains(string(body), "player-1") {
+		t.Error("expected no session labels in the metrics")
+	}
+}
@@ -0,0 +1,294 @@
+// Package usage accounts for the tokens and cost of model requests, by
+// model, game session, world generation job and command, and enforces
+// per-session budgets.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"textadventureservices/services/llm"
+)
+
+// ErrBudgetExceeded is returned for requests of a session that has used up its budget
+var ErrBudgetExceeded = errors.New("session budget exceeded")
+
+// Scope attributes usage to a game session, a world generation job and a
+// command; any of them may be empty
+type Scope struct {
+	Session string `json:"session,omitempty"`
+	Job     string `json:"job,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+type scopeKey struct{}
+
+// With returns a context whose requests are attributed to scope. Empty
+// fields of scope keep the ones already in ctx.
+func With(ctx context.Context, scope Scope) context.Context {
+	current := ScopeFrom(ctx)
+	if scope.Session == "" {
+		scope.Session = current.Session
+	}
+	if scope.Job == "" {
+		scope.Job = current.Job
+	}
+	if scope.Command == "" {
+		scope.Command = current.Command
+	}
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope of a context
+func ScopeFrom(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// Price is what a model costs, in US dollars per million tokens
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// Pricing maps model names to their prices. A model without a price of its
+// own takes the price of the longest name it starts with, so "gpt-4o" also
+// prices "gpt-4o-2024-08-06". Models without a price cost nothing.
+type Pricing map[string]Price
+
+// DefaultPricing returns the list prices of the OpenAI models in use when
+// this was written; configure Pricing to keep them current
+func DefaultPricing() Pricing {
+	return Pricing{
+		"gpt-4o":        {Prompt: 2.50, Completion: 10.00},
+		"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.60},
+		"gpt-4-turbo":   {Prompt: 10.00, Completion: 30.00},
+		"gpt-4":         {Prompt: 30.00, Completion: 60.00},
+		"gpt-3.5-turbo": {Prompt: 0.50, Completion: 1.50},
+	}
+}
+
+// Cost returns what the usage of a request to model cost
+func (p Pricing) Cost(model string, u llm.Usage) float64 {
+	price, ok := p[model]
+	if !ok {
+		longest := -1
+		for name, candidate := range p {
+			if len(name) > longest && strings.HasPrefix(model, name) {
+				price, longest = candidate, len(name)
+			}
+		}
+	}
+	return (float64(u.PromptTokens)*price.Prompt + float64(u.CompletionTokens)*price.Completion) / 1e6
+}
+
+// Budget caps what a session may use; zero fields are unlimited
+type Budget struct {
+	Tokens int     `json:"tokens,omitempty"`
+	Cost   float64 `json:"cost,omitempty"`
+}
+
+// exceededBy reports whether totals have used up the budget
+func (b Budget) exceededBy(totals Totals) bool {
+	return (b.Tokens > 0 && totals.TotalTokens >= b.Tokens) || (b.Cost > 0 && totals.Cost >= b.Cost)
+}
+
+// Config configures usage accounting
+type Config struct {
+	// Pricing adds to and overrides the DefaultPricing
+	Pricing Pricing `json:"pricing,omitempty"`
+	// SessionBudget caps every game session unless it has a budget of its own
+	SessionBudget Budget `json:"session_budget,omitempty"`
+}
+
+// Validate checks that no price or budget is negative
+func (c Config) Validate() error {
+	for model, price := range c.Pricing {
+		if price.Prompt < 0 || price.Completion < 0 {
+			return fmt.Errorf("price of %s must not be negative", model)
+		}
+	}
+	if c.SessionBudget.Tokens < 0 || c.SessionBudget.Cost < 0 {
+		return fmt.Errorf("session budget must not be negative")
+	}
+	return nil
+}
+
+// Totals sums the usage of a set of requests
+type Totals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (t *Totals) add(u llm.Usage, cost float64) {
+	t.Requests++
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.TotalTokens += u.TotalTokens
+	t.Cost += cost
+}
+
+// Report is the usage recorded by a tracker, in total and broken down
+type Report struct {
+	Total    Totals            `json:"total"`
+	Models   map[string]Totals `json:"models"`
+	Sessions map[string]Totals `json:"sessions"`
+	Jobs     map[string]Totals `json:"jobs"`
+	Commands map[string]Totals `json:"commands"`
+}
+
+// Tracker records the usage of model requests. It is safe for concurrent
+// use and meant to be shared by everything that sends requests for the
+// same sessions.
+type Tracker struct {
+	pricing Pricing
+
+	mu       sync.Mutex
+	budget   Budget
+	budgets  map[string]Budget
+	total    Totals
+	models   map[string]*Totals
+	sessions map[string]*Totals
+	jobs     map[string]*Totals
+	commands map[string]*Totals
+}
+
+// NewTracker creates a tracker with the configured pricing and budget
+func NewTracker(cfg Config) *Tracker {
+	pricing := DefaultPricing()
+	for model, price := range cfg.Pricing {
+		pricing[model] = price
+	}
+	return &Tracker{
+		pricing:  pricing,
+		budget:   cfg.SessionBudget,
+		budgets:  make(map[string]Budget),
+		models:   make(map[string]*Totals),
+		sessions: make(map[string]*Totals),
+		jobs:     make(map[string]*Totals),
+		commands: make(map[string]*Totals),
+	}
+}
+
+// Cost returns what the usage of a request to model cost
+func (t *Tracker) Cost(model string, u llm.Usage) float64 {
+	return t.pricing.Cost(model, u)
+}
+
+// Record adds the usage of a request to model to the totals of the scope in
+// ctx, and returns its cost
+func (t *Tracker) Record(ctx context.Context, model string, u llm.Usage) float64 {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	cost := t.Cost(model, u)
+	scope := ScopeFrom(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total.add(u, cost)
+	for _, entry := range []struct {
+		totals map[string]*Totals
+		key    string
+	}{{t.models, model}, {t.sessions, scope.Session}, {t.jobs, scope.Job}, {t.commands, scope.Command}} {
+		if entry.key == "" {
+			continue
+		}
+		totals, ok := entry.totals[entry.key]
+		if !ok {
+			totals = &Totals{}
+			entry.totals[entry.key] = totals
+		}
+		totals.add(u, cost)
+	}
+	return cost
+}
+
+// Check returns ErrBudgetExceeded when the session in ctx has used up its
+// budget. The request that crosses the budget is still answered; the ones
+// after it are refused.
+func (t *Tracker) Check(ctx context.Context) error {
+	session := ScopeFrom(ctx).Session
+	if session == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	budget := t.budgetOf(session)
+	var totals Totals
+	if spent, ok := t.sessions[session]; ok {
+		totals = *spent
+	}
+	if budget.exceededBy(totals) {
+		return fmt.Errorf("%w: session %s used %d tokens costing $%.4f", ErrBudgetExceeded, session, totals.TotalTokens, totals.Cost)
+	}
+	return nil
+}
+
+// SetBudget gives a session a budget of its own instead of the configured one
+func (t *Tracker) SetBudget(session string, budget Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets[session] = budget
+}
+
+// Budget returns the budget of a session
+func (t *Tracker) Budget(session string) Budget {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.budgetOf(session)
+}
+
+// budgetOf returns the budget of a session; the caller holds the lock
+func (t *Tracker) budgetOf(session string) Budget {
+	if budget, ok := t.budgets[session]; ok {
+		return budget
+	}
+	return t.budget
+}
+
+// Session returns the usage of a game session
+func (t *Tracker) Session(id string) Totals {
+	return t.totalsOf(t.sessions, id)
+}
+
+// Job returns the usage of a world generation job
+func (t *Tracker) Job(id string) Totals {
+	return t.totalsOf(t.jobs, id)
+}
+
+func (t *Tracker) totalsOf(totals map[string]*Totals, key string) Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if found, ok := totals[key]; ok {
+		return *found
+	}
+	return Totals{}
+}
+
+// Report returns a copy of everything recorded so far
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Report{
+		Total:    t.total,
+		Models:   copyTotals(t.models),
+		Sessions: copyTotals(t.sessions),
+		Jobs:     copyTotals(t.jobs),
+		Commands: copyTotals(t.commands),
+	}
+}
+
+func copyTotals(totals map[string]*Totals) map[string]Totals {
+	copied := make(map[string]Totals, len(totals))
+	for key, value := range totals {
+		copied[key] = *value
+	}
+	return copied
+}
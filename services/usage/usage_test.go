@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"textadventureservices/services/llm"
+)
+
+func TestPricingCost(t *testing.T) {
+	pricing := Pricing{
+		"gpt-4o":      {Prompt: 2.50, Completion: 10.00},
+		"gpt-4o-mini": {Prompt: 0.15, Completion: 0.60},
+	}
+	u := llm.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	for model, want := range map[string]float64{
+		"gpt-4o":                 0.0075,
+		"gpt-4o-2024-08-06":      0.0075,
+		"gpt-4o-mini-2024-07-18": 0.00045,
+		"llama3":                 0,
+	} {
+		if got := pricing.Cost(model, u); math.Abs(got-want) > 1e-12 {
+			t.Errorf("Cost(%s) = %g, want %g", model, got, want)
+		}
+	}
+}
+
+func TestTrackerAttributesUsage(t *testing.T) {
+	tracker := NewTracker(Config{Pricing: Pricing{"local": {Prompt: 1, Completion: 1}}})
+	ctx := With(context.Background(), Scope{Session: "player-1", Command: "look"})
+	tracker.Record(ctx, "gpt-4o", llm.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120})
+
+	// A job of the same session keeps the session and replaces the command
+	jobCtx := With(ctx, Scope{Job: "job_1", Command: "generate-world"})
+	if cost := tracker.Record(jobCtx, "local", llm.Usage{PromptTokens: 500000, CompletionTokens: 500000}); cost != 1 {
+		t.Errorf("expected the configured price to apply, got %g", cost)
+	}
+
+	report := tracker.Report()
+	if report.Total.Requests != 2 || report.Total.TotalTokens != 1000120 {
+		t.Errorf("unexpected total %+v", report.Total)
+	}
+	if session := tracker.Session("player-1"); session.Requests != 2 || session.PromptTokens != 500100 {
+		t.Errorf("unexpected session usage %+v", session)
+	}
+	if job := tracker.Job("job_1"); job.Requests != 1 || job.Cost != 1 {
+		t.Errorf("unexpected job usage %+v", job)
+	}
+	if report.Commands["look"].Requests != 1 || report.Commands["generate-world"].Requests != 1 {
+		t.Errorf("unexpected command usage %+v", report.Commands)
+	}
+	if report.Models["gpt-4o"].Cost == 0 {
+		t.Errorf("expected gpt-4o to be priced by default, got %+v", report.Models)
+	}
+}
+
+func TestTrackClientEnforcesBudget(t *testing.T) {
+	calls := 0
+	model := llm.ClientFunc(func(ctx context.Context, req llm.Request) (*llm.Response, error) {
+		calls++
+		return &llm.Response{Content: "A quiet room.", Model: "gpt-4o", Usage: llm.Usage{PromptTokens: 40, CompletionTokens: 20, TotalTokens: 60}}, nil
+	})
+	tracker := NewTracker(Config{SessionBudget: Budget{Tokens: 100}})
+	client := Track(model, tracker)
+	ctx := With(context.Background(), Scope{Session: "player-1"})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Complete(ctx, llm.Request{Model: "gpt-4o"}); err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+	}
+	if _, err := client.Complete(ctx, llm.Request{Model: "gpt-4o"}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected the budget to be exceeded, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the request over budget not to be sent, got %d calls", calls)
+	}
+
+	// Other sessions and requests without a session are not held to it
+	if _, err := client.Complete(With(context.Background(), Scope{Session: "player-2"}), llm.Request{}); err != nil {
+		t.Errorf("expected another session to be allowed, got %v", err)
+	}
+	if _, err := client.Complete(context.Background(), llm.Request{}); err != nil {
+		t.Errorf("expected a request without a session to be allowed, got %v", err)
+	}
+
+	// A budget of its own replaces the configured one
+	tracker.SetBudget("player-1", Budget{Tokens: 1000})
+	if _, err := client.Complete(ctx, llm.Request{}); err != nil {
+		t.Errorf("expected the raised budget to allow the request, got %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Pricing: Pricing{"gpt-4o": {Prompt: -1}}}).Validate(); err == nil {
+		t.Error("expected error for a negative price")
+	}
+	if err := (Config{SessionBudget: Budget{Cost: -1}}).Validate(); err == nil {
+		t.Error("expected error for a negative budget")
+	}
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"textadventureservices/services/usage"
+)
+
+// FallbackProvider asks its fallback when the primary provider fails, e.g.
+// a local Ollama model when OpenAI is down, and the template provider after
+// that. Open builds one from ProviderConfig.Fallback.
+type FallbackProvider struct {
+	primary  Provider
+	fallback Provider
+}
+
+// NewFallbackProvider creates a provider that falls back from primary to fallback
+func NewFallbackProvider(primary, fallback Provider) *FallbackProvider {
+	return &FallbackProvider{primary: primary, fallback: fallback}
+}
+
+// Initialize initializes the primary provider with config, and the fallback with config.Fallback
+func (p *FallbackProvider) Initialize(config ProviderConfig) error {
+	primary := config
+	primary.Fallback = nil
+	if err := p.primary.Initialize(primary); err != nil {
+		return err
+	}
+	if config.Fallback != nil {
+		return p.fallback.Initialize(*config.Fallback)
+	}
+	return nil
+}
+
+// Model returns the model of the primary provider
+func (p *FallbackProvider) Model() string {
+	if named, ok := p.primary.(interface{ Model() string }); ok {
+		return named.Model()
+	}
+	return ""
+}
+
+// try calls the primary provider, and the fallback when it fails
+func (p *FallbackProvider) try(ctx context.Context, call func(Provider) error) error {
+	err := call(p.primary)
+	// A session out of budget stops rather than carrying on with the fallback
+	if err == nil || ctx.Err() != nil || errors.Is(err, usage.ErrBudgetExceeded) {
+		return err
+	}
+	if fallbackErr := call(p.fallback); fallbackErr != nil {
+		return fmt.Errorf("%w (after the primary provider failed: %v)", fallbackErr, err)
+	}
+	return nil
+}
+
+func (p *FallbackProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	var desc string
+	err := p.try(ctx, func(provider Provider) (err error) {
+		desc, err = provider.GenerateDescription(ctx, prompt)
+		return err
+	})
+	return desc, err
+}
+
+func (p *FallbackProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	var prompt string
+	err := p.try(ctx, func(provider Provider) (err error) {
+		prompt, err = provider.EnhancePrompt(ctx, basePrompt)
+		return err
+	})
+	return prompt, err
+}
+
+func (p *FallbackProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	var objects []string
+	err := p.try(ctx, func(provider Provider) (err error) {
+		objects, err = provider.GenerateObjects(ctx, sceneDescription)
+		return err
+	})
+	return objects, err
+}
+
+// GenerateObjectSpecs asks the providers that can describe objects along with their names
+func (p *FallbackProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error) {
+	var objects []ObjectSpec
+	err := p.try(ctx, func(provider Provider) (err error) {
+		described, ok := provider.(ObjectSpecProvider)
+		if !ok {
+			return ErrStructuredUnsupported
+		}
+		objects, err = described.GenerateObjectSpecs(ctx, sceneDescription)
+		return err
+	})
+	return objects, err
+}
+
+// GenerateRoomSpec asks the providers that support structured rooms
+func (p *FallbackProvider) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	var spec *RoomSpec
+	err := p.try(ctx, func(provider Provider) (err error) {
+		structured, ok := provider.(StructuredProvider)
+		if !ok {
+			return ErrStructuredUnsupported
+		}
+		spec, err = structured.GenerateRoomSpec(ctx, req)
+		return err
+	})
+	return spec, err
+}
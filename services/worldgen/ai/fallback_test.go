@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"textadventureservices/services/usage"
+)
+
+// failingProvider fails every call
+type failingProvider struct {
+	MockProvider
+	calls int
+}
+
+func (p *failingProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	return "", errors.New("model overloaded")
+}
+
+func (p *failingProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	p.calls++
+	return nil, errors.New("model overloaded")
+}
+
+func TestFallbackProvider(t *testing.T) {
+	ctx := context.Background()
+	primary := &failingProvider{}
+	provider := NewFallbackProvider(primary, NewTemplateProvider(1))
+
+	desc, err := provider.GenerateDescription(ctx, "a flooded crypt")
+	if err != nil || desc == "" {
+		t.Fatalf("expected the fallback to describe the room, got %q, %v", desc, err)
+	}
+	objects, err := provider.GenerateObjects(ctx, desc)
+	if err != nil || len(objects) == 0 {
+		t.Errorf("expected the fallback to list objects, got %q, %v", objects, err)
+	}
+	if primary.calls != 2 {
+		t.Errorf("expected the primary provider to be asked first, got %d calls", primary.calls)
+	}
+	if spec, err := provider.GenerateRoomSpec(ctx, RoomRequest{Prompt: "a crypt", Exits: []string{"north"}}); err != nil || spec == nil {
+		t.Errorf("expected the structured fallback to answer, got %+v, %v", spec, err)
+	}
+
+	both := NewFallbackProvider(&failingProvider{}, &failingProvider{})
+	if _, err := both.GenerateDescription(ctx, "a crypt"); err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("expected both failures to be reported, got %v", err)
+	}
+}
+
+func TestOpenFallbackChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	provider, err := Open(ProviderConfig{
+		Type:     ProviderOpenAI,
+		Endpoint: server.URL,
+		Model:    "gpt-4o",
+		APIKey:   "test-key",
+		Fallback: &ProviderConfig{Type: ProviderTemplate, Model: TemplateModel},
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, ok := provider.(*FallbackProvider); !ok {
+		t.Fatalf("expected a fallback provider, got %T", provider)
+	}
+	if desc, err := provider.GenerateDescription(context.Background(), "a quiet library"); err != nil || desc == "" {
+		t.Errorf("expected the template provider to answer, got %q, %v", desc, err)
+	}
+}
+
+// brokeProvider is out of budget
+type brokeProvider struct {
+	MockProvider
+}
+
+func (p *brokeProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	return "", fmt.Errorf("%w: session player-1", usage.ErrBudgetExceeded)
+}
+
+func TestFallbackProviderStopsAtBudget(t *testing.T) {
+	fallback := &failingProvider{}
+	provider := NewFallbackProvider(&brokeProvider{}, fallback)
+	if _, err := provider.GenerateDescription(context.Background(), "a crypt"); !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Errorf("expected the budget error, got %v", err)
+	}
+	if fallback.calls != 0 {
+		t.Errorf("expected the fallback not to be asked, got %d calls", fallback.calls)
+	}
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+)
+
+// mockObjects are the objects the mock provider picks from
+var mockObjects = []string{"lantern", "coil of rope", "wooden crate", "brass key", "dusty book", "iron chest", "candle", "old map"}
+
+// MockProvider answers instantly with canned text derived from the prompt.
+// It needs no network or configuration, which makes it useful for tests and demos.
+type MockProvider struct {
+	model string
+}
+
+// NewMockProvider creates a mock provider
+func NewMockProvider() *MockProvider {
+	return &MockProvider{model: "mock"}
+}
+
+func (p *MockProvider) Initialize(config ProviderConfig) error {
+	if config.Model != "" {
+		p.model = config.Model
+	}
+	return nil
+}
+
+// Model returns the configured model name
+func (p *MockProvider) Model() string {
+	return p.model
+}
+
+func (p *MockProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	return fmt.Sprintf("You find yourself in %s. The air is still, and everything here waits to be explored.", prompt), nil
+}
+
+func (p *MockProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	return basePrompt, nil
+}
+
+// GenerateObjects picks three objects based on a hash of the scene description
+func (p *MockProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(sceneDescription))
+	start := int(h.Sum32() % uint32(len(mockObjects)))
+
+	objects := make([]string, 3)
+	for i := range objects {
+		objects[i] = mockObjects[(start+i)%len(mockObjects)]
+	}
+	return objects, nil
+}
@@ -1,85 +1,115 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
+
+	"textadventureservices/services/llm"
 )
 
+// DefaultOllamaEndpoint is where a local Ollama server listens by default
+const DefaultOllamaEndpoint = llm.DefaultOllamaEndpoint
+
 type OllamaProvider struct {
 	endpoint string
 	model    string
-	client   *http.Client
-}
-
-type ollamaRequest struct {
-	Model    string                 `json:"model"`
-	Prompt   string                 `json:"prompt"`
-	Stream   bool                   `json:"stream"`
-	Options  map[string]interface{} `json:"options,omitempty"`
-}
-
-type ollamaResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	stream   bool
+	options  map[string]interface{}
+	retry    llm.RetryPolicy
+	client   llm.Client
 }
 
 func NewOllamaProvider() *OllamaProvider {
 	return &OllamaProvider{
-		client: &http.Client{},
+		endpoint: DefaultOllamaEndpoint,
+		stream:   true,
+		retry:    llm.DefaultRetryPolicy,
 	}
 }
 
+// Initialize configures the provider. Parameters are passed to Ollama as model
+// options (e.g. temperature, num_predict, top_k), except "stream", which turns
+// streaming on or off and defaults to on, and "max_retries", which bounds the
+// retries of failed requests.
 func (p *OllamaProvider) Initialize(config ProviderConfig) error {
-	if config.Endpoint == "" {
-		return fmt.Errorf("ollama endpoint is required")
-	}
 	if config.Model == "" {
 		return fmt.Errorf("ollama model is required")
 	}
-	
-	p.endpoint = config.Endpoint
+
+	if config.Endpoint != "" {
+		p.endpoint = strings.TrimRight(config.Endpoint, "/")
+	}
 	p.model = config.Model
+
+	p.options = nil
+	for key, value := range config.Parameters {
+		if key == "stream" {
+			stream, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("ollama parameter stream must be a boolean, got %T", value)
+			}
+			p.stream = stream
+			continue
+		}
+		if key == "max_retries" {
+			retries, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("ollama parameter max_retries must be a number, got %T", value)
+			}
+			p.retry.MaxRetries = int(retries)
+			continue
+		}
+		if p.options == nil {
+			p.options = make(map[string]interface{})
+		}
+		p.options[key] = value
+	}
+
+	client := llm.NewOllamaClient(p.endpoint)
+	client.Stream = p.stream
+	// Server and network errors are retried like OpenAI's
+	p.client = tracked(llm.WithRetry(rateLimited(client, config.RateLimiter), p.retry), config.Tracker)
 	return nil
 }
 
+// Model returns the configured model name
+func (p *OllamaProvider) Model() string {
+	return p.model
+}
+
+type chunkKey struct{}
+
+// WithChunks returns a context whose streamed responses are passed to fn
+// piece by piece as they arrive, e.g. to show a room's text while it is being
+// generated. Each request gets the function of its own context, so rooms
+// generated at the same time do not mix their pieces.
+func WithChunks(ctx context.Context, fn func(string)) context.Context {
+	return context.WithValue(ctx, chunkKey{}, fn)
+}
+
+// chunksFrom returns the function set by WithChunks, or nil
+func chunksFrom(ctx context.Context) func(string) {
+	fn, _ := ctx.Value(chunkKey{}).(func(string))
+	return fn
+}
+
 func (p *OllamaProvider) makeRequest(ctx context.Context, prompt string) (string, error) {
-	reqBody := ollamaRequest{
-		Model:  p.model,
-		Prompt: prompt,
-		Stream: false,
-	}
-	
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-	
-	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/api/generate", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	req.Header.Set("Content-Type", "application/json")
-	
-	resp, err := p.client.Do(req)
+	return p.chat(ctx, []Message{{Role: llm.RoleUser, Content: prompt}}, false)
+}
+
+// chat sends a conversation to the model; jsonReply makes Ollama constrain the reply to JSON
+func (p *OllamaProvider) chat(ctx context.Context, messages []Message, jsonReply bool) (string, error) {
+	resp, err := p.client.Complete(ctx, llm.Request{
+		Model:    p.model,
+		Messages: messages,
+		Options:  llm.Options{JSON: jsonReply, Extra: p.options},
+		OnChunk:  chunksFrom(ctx),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-	
-	var result ollamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-	
-	if result.Error != "" {
-		return "", fmt.Errorf("ollama error: %s", result.Error)
-	}
-	
-	return result.Response, nil
+	return resp.Content, nil
 }
 
 func (p *OllamaProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
@@ -99,12 +129,12 @@ func (p *OllamaProvider) GenerateObjects(ctx context.Context, sceneDescription s
 			"Format the response as a comma-separated list of simple object names.",
 		sceneDescription,
 	)
-	
+
 	response, err := p.makeRequest(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	// Split the comma-separated response into a slice
 	objects := splitAndTrim(response)
 	return objects, nil
@@ -120,8 +150,25 @@ func (p *OllamaProvider) EnhancePrompt(ctx context.Context, basePrompt string) (
 	return p.makeRequest(ctx, enhancedPrompt)
 }
 
-// Helper function to split and trim a comma-separated string
+// GenerateRoomSpec generates a room as a JSON document, re-prompting when the
+// model's reply does not match RoomSpecSchema
+func (p *OllamaProvider) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	return RequestRoomSpec(ctx, func(ctx context.Context, messages []Message) (string, error) {
+		return p.chat(ctx, messages, true)
+	}, req, DefaultStructuredAttempts)
+}
+
+// GenerateObjectSpecs lists the objects of a scene together with their
+// descriptions in a single JSON request
+func (p *OllamaProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error) {
+	return RequestObjectSpecs(ctx, func(ctx context.Context, messages []Message) (string, error) {
+		return p.chat(ctx, messages, true)
+	}, sceneDescription, DefaultStructuredAttempts)
+}
+
+// splitAndTrim splits a list answer into trimmed, non-empty items. Besides
+// comma-separated lists it copes with numbered or bulleted lines and a
+// leading sentence, which local models often add despite the instructions.
 func splitAndTrim(input string) []string {
-	// Implementation omitted for brevity - splits on commas and trims whitespace
-	return []string{"TODO: Implement splitting logic"}
+	return ParseList(input)
 }
@@ -0,0 +1,248 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// streamChunks writes an NDJSON stream the way Ollama's /api/chat does
+func streamChunks(w http.ResponseWriter, chunks ...string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, chunk := range chunks {
+		fmt.Fprintf(w, "{\"model\":\"llama3\",\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", chunk)
+		w.(http.Flusher).Flush()
+	}
+	fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true}`)
+}
+
+// ollamaRequest is the part of a /api/chat request the tests look at
+type ollamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+func newTestOllama(t *testing.T, handler http.HandlerFunc, params map[string]interface{}) *OllamaProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider, err := NewProvider(ProviderConfig{Type: ProviderOllama})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if err := provider.Initialize(ProviderConfig{Endpoint: server.URL, Model: "llama3", Parameters: params}); err != nil {
+		t.Fatalf("Failed to initialize provider: %v", err)
+	}
+	return provider.(*OllamaProvider)
+}
+
+func TestOllamaProvider(t *testing.T) {
+	t.Run("NewOllamaProvider uses the local endpoint", func(t *testing.T) {
+		provider := NewOllamaProvider()
+		if err := provider.Initialize(ProviderConfig{Model: "llama3"}); err != nil {
+			t.Fatalf("Failed to initialize provider: %v", err)
+		}
+		if provider.endpoint != DefaultOllamaEndpoint {
+			t.Errorf("expected default endpoint, got %s", provider.endpoint)
+		}
+		if err := NewOllamaProvider().Initialize(ProviderConfig{}); err == nil {
+			t.Error("expected error without a model")
+		}
+	})
+
+	t.Run("GenerateDescription joins a streamed response", func(t *testing.T) {
+		var req ollamaRequest
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.URL.Path != "/api/chat" {
+				t.Errorf("expected POST /api/chat, got %s %s", r.Method, r.URL.Path)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("failed to decode request body: %v", err)
+			}
+			streamChunks(w, "A damp ", "cellar ", "smelling of moss.")
+		}, nil)
+
+		var chunks []string
+		ctx := WithChunks(context.Background(), func(chunk string) { chunks = append(chunks, chunk) })
+
+		description, err := provider.GenerateDescription(ctx, "a cellar")
+		if err != nil {
+			t.Fatalf("GenerateDescription failed: %v", err)
+		}
+		if description != "A damp cellar smelling of moss." {
+			t.Errorf("unexpected description %q", description)
+		}
+		if len(chunks) != 3 {
+			t.Errorf("expected 3 streamed chunks, got %q", chunks)
+		}
+		if !req.Stream || req.Model != "llama3" || len(req.Messages) != 1 || !strings.Contains(req.Messages[0].Content, "a cellar") {
+			t.Errorf("unexpected request %+v", req)
+		}
+	})
+
+	t.Run("Chunks go to the function of each request's context", func(t *testing.T) {
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			var req ollamaRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			room := "cellar"
+			if strings.Contains(req.Messages[0].Content, "attic") {
+				room = "attic"
+			}
+			streamChunks(w, "A dusty ", room+".")
+		}, nil)
+
+		rooms := []string{"cellar", "attic"}
+		received := make([][]string, len(rooms))
+		var wg sync.WaitGroup
+		for i, room := range rooms {
+			i, room := i, room
+			ctx := WithChunks(context.Background(), func(chunk string) { received[i] = append(received[i], chunk) })
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := provider.GenerateDescription(ctx, "an "+room); err != nil {
+					t.Errorf("GenerateDescription failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		chunks := map[string][]string{rooms[0]: received[0], rooms[1]: received[1]}
+		want := map[string][]string{"cellar": {"A dusty ", "cellar."}, "attic": {"A dusty ", "attic."}}
+		if !reflect.DeepEqual(chunks, want) {
+			t.Errorf("expected %q, got %q", want, chunks)
+		}
+
+		if _, err := provider.GenerateDescription(context.Background(), "a cellar"); err != nil {
+			t.Errorf("expected a request without chunk function to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Server errors are retried", func(t *testing.T) {
+		var calls int32
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintln(w, `{"error":"server busy"}`)
+				return
+			}
+			streamChunks(w, "A quiet room.")
+		}, nil)
+
+		description, err := provider.GenerateDescription(context.Background(), "a room")
+		if err != nil || description != "A quiet room." {
+			t.Fatalf("expected the retry to succeed, got %q, %v", description, err)
+		}
+		if calls != 2 {
+			t.Errorf("expected 2 requests, got %d", calls)
+		}
+
+		calls = 0
+		once := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, map[string]interface{}{"max_retries": 0.0})
+		if _, err := once.GenerateDescription(context.Background(), "a room"); err == nil {
+			t.Error("expected error without retries")
+		}
+		if calls != 1 {
+			t.Errorf("expected max_retries 0 to send 1 request, got %d", calls)
+		}
+	})
+
+	t.Run("Parameters become model options", func(t *testing.T) {
+		var req ollamaRequest
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"role": "assistant", "content": "A quiet room."}, "done": true})
+		}, map[string]interface{}{"stream": false, "temperature": 0.2, "num_predict": 128.0})
+
+		if _, err := provider.GenerateDescription(context.Background(), "a room"); err != nil {
+			t.Fatalf("GenerateDescription failed: %v", err)
+		}
+		want := map[string]interface{}{"temperature": 0.2, "num_predict": 128.0}
+		if req.Stream || !reflect.DeepEqual(req.Options, want) {
+			t.Errorf("expected non-streaming request with options %v, got stream=%v options=%v", want, req.Stream, req.Options)
+		}
+	})
+
+	t.Run("GenerateObjects parses a numbered list", func(t *testing.T) {
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			streamChunks(w, "Here are some objects:\n", "1. Rusty lantern\n", "2. Coil of rope\n3. Wooden crate")
+		}, nil)
+
+		objects, err := provider.GenerateObjects(context.Background(), "a cellar")
+		if err != nil {
+			t.Fatalf("GenerateObjects failed: %v", err)
+		}
+		want := []string{"Rusty lantern", "Coil of rope", "Wooden crate"}
+		if !reflect.DeepEqual(objects, want) {
+			t.Errorf("expected %q, got %q", want, objects)
+		}
+	})
+
+	t.Run("GenerateRoomSpec asks the chat endpoint for JSON", func(t *testing.T) {
+		var req ollamaRequest
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/chat" {
+				t.Errorf("expected /api/chat, got %s", r.URL.Path)
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			room := `{"name": "Cellar", "description": "Damp.", "objects": [], "exits": [], "ambient": []}`
+			fmt.Fprintf(w, "{\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":true}\n", room)
+		}, nil)
+
+		spec, err := provider.GenerateRoomSpec(context.Background(), RoomRequest{Prompt: "a cellar"})
+		if err != nil {
+			t.Fatalf("GenerateRoomSpec failed: %v", err)
+		}
+		if spec.Name != "Cellar" || req.Format != "json" {
+			t.Errorf("unexpected room %+v for request %+v", spec, req)
+		}
+	})
+
+	t.Run("Errors are reported", func(t *testing.T) {
+		provider := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"error":"model 'llama3' not found"}`)
+		}, nil)
+		if _, err := provider.GenerateDescription(context.Background(), "a room"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected model not found error, got %v", err)
+		}
+
+		truncated := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"message":{"content":"A dark"},"done":false}`)
+		}, nil)
+		if _, err := truncated.GenerateDescription(context.Background(), "a room"); err == nil {
+			t.Error("expected error for a stream that never completes")
+		}
+
+		midStream := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"message":{"content":"A dark"},"done":false}`)
+			fmt.Fprintln(w, `{"error":"out of memory"}`)
+		}, nil)
+		if _, err := midStream.GenerateDescription(context.Background(), "a room"); err == nil || !strings.Contains(err.Error(), "out of memory") {
+			t.Errorf("expected streamed error, got %v", err)
+		}
+	})
+}
+
+func TestSplitAndTrim(t *testing.T) {
+	want := []string{"lantern", "rope", "crate"}
+	for _, input := range []string{"lantern, rope, crate", " lantern ,rope,  crate\n", "- lantern\n- rope\n- crate"} {
+		if got := splitAndTrim(input); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitAndTrim(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
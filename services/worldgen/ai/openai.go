@@ -1,51 +1,34 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"strings"
+	"time"
+
+	"textadventureservices/services/llm"
 )
 
+// Message is a message of a conversation with a model
+type Message = llm.Message
+
 type OpenAIProvider struct {
-	endpoint    string
+	client      llm.Client
 	model       string
-	apiKey      string
-	client      *http.Client
 	temperature float64
 	maxTokens   int
-}
-
-type openAIRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-}
-
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type openAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+	// fallbackModel answers when model fails
+	fallbackModel string
+	retry         llm.RetryPolicy
+	// timeout bounds each request to the API
+	timeout time.Duration
 }
 
 func NewOpenAIProvider() *OpenAIProvider {
 	return &OpenAIProvider{
-		client:      &http.Client{},
-		temperature: 0.8,  // Increased for more creativity
-		maxTokens:   500,  // Increased for longer descriptions
+		temperature: 0.8, // Increased for more creativity
+		maxTokens:   500, // Increased for longer descriptions
+		retry:       llm.DefaultRetryPolicy,
+		timeout:     llm.DefaultTimeout,
 	}
 }
 
@@ -60,9 +43,7 @@ func (p *OpenAIProvider) Initialize(config ProviderConfig) error {
 		return fmt.Errorf("OpenAI model is required")
 	}
 
-	p.endpoint = config.Endpoint
 	p.model = config.Model
-	p.apiKey = config.APIKey
 
 	// Apply custom parameters if provided
 	if config.Parameters != nil {
@@ -72,52 +53,58 @@ func (p *OpenAIProvider) Initialize(config ProviderConfig) error {
 		if tokens, ok := config.Parameters["max_tokens"].(float64); ok {
 			p.maxTokens = int(tokens)
 		}
+		if model, ok := config.Parameters["fallback_model"].(string); ok {
+			p.fallbackModel = model
+		}
+		if retries, ok := config.Parameters["max_retries"].(float64); ok {
+			p.retry.MaxRetries = int(retries)
+		}
+		if seconds, ok := config.Parameters["timeout_seconds"].(float64); ok && seconds > 0 {
+			p.timeout = time.Duration(seconds * float64(time.Second))
+		}
 	}
 
+	// Rate limits and server errors are retried with each model before the
+	// fallback model is asked
+	api := llm.NewOpenAIClient(config.Endpoint, config.APIKey)
+	api.HTTPClient.Timeout = p.timeout
+	client := llm.WithRetry(rateLimited(api, config.RateLimiter), p.retry)
+	hops := []llm.Hop{{Name: string(ProviderOpenAI), Client: client}}
+	if p.fallbackModel != "" && p.fallbackModel != p.model {
+		hops = append(hops, llm.Hop{Name: string(ProviderOpenAI), Client: client, Model: p.fallbackModel})
+	}
+	p.client = tracked(llm.Fallback(hops...), config.Tracker)
 	return nil
 }
 
-func (p *OpenAIProvider) makeRequest(ctx context.Context, messages []Message) (string, error) {
-	reqBody := openAIRequest{
-		Model:       p.model,
-		Messages:    messages,
-		Temperature: p.temperature,
-		MaxTokens:   p.maxTokens,
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
+// Model returns the configured model name
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/chat/completions", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
+func (p *OpenAIProvider) makeRequest(ctx context.Context, messages []Message) (string, error) {
+	return p.request(ctx, messages, false)
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+// makeJSONRequest asks the model to reply with a JSON object
+func (p *OpenAIProvider) makeJSONRequest(ctx context.Context, messages []Message) (string, error) {
+	return p.request(ctx, messages, true)
+}
 
-	resp, err := p.client.Do(req)
+func (p *OpenAIProvider) request(ctx context.Context, messages []Message, jsonReply bool) (string, error) {
+	resp, err := p.client.Complete(ctx, llm.Request{
+		Model:    p.model,
+		Messages: messages,
+		Options: llm.Options{
+			Temperature: p.temperature,
+			MaxTokens:   p.maxTokens,
+			JSON:        jsonReply,
+		},
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var result openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", err
 	}
-
-	if result.Error != nil {
-		return "", fmt.Errorf("OpenAI error: %s", result.Error.Message)
-	}
-
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
-	}
-
-	return result.Choices[0].Message.Content, nil
+	return resp.Content, nil
 }
 
 func (p *OpenAIProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
@@ -165,13 +152,19 @@ func (p *OpenAIProvider) GenerateObjects(ctx context.Context, sceneDescription s
 		return nil, err
 	}
 
-	// Clean and split the response
-	objects := strings.Split(response, ",")
-	for i := range objects {
-		objects[i] = strings.TrimSpace(objects[i])
-	}
+	return ParseList(response), nil
+}
+
+// GenerateRoomSpec generates a room as a JSON document, re-prompting when the
+// model's reply does not match RoomSpecSchema
+func (p *OpenAIProvider) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	return RequestRoomSpec(ctx, p.makeJSONRequest, req, DefaultStructuredAttempts)
+}
 
-	return objects, nil
+// GenerateObjectSpecs lists the objects of a scene together with their
+// descriptions in a single JSON request
+func (p *OpenAIProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error) {
+	return RequestObjectSpecs(ctx, p.makeJSONRequest, sceneDescription, DefaultStructuredAttempts)
 }
 
 func (p *OpenAIProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
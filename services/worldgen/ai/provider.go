@@ -3,12 +3,22 @@ package ai
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
+
+	"textadventureservices/services/llm"
+	"textadventureservices/services/usage"
 )
 
 type ProviderType string
 
 const (
 	ProviderOpenAI ProviderType = "openai"
+	ProviderOllama ProviderType = "ollama"
+	ProviderMock   ProviderType = "mock"
+	ProviderReplay ProviderType = "replay"
+	// ProviderTemplate generates text from grammars, without a model
+	ProviderTemplate ProviderType = "template"
 )
 
 // Provider defines the interface for AI providers
@@ -21,19 +31,121 @@ type Provider interface {
 
 // ProviderConfig holds the configuration for an AI provider
 type ProviderConfig struct {
-	Type       ProviderType
-	Endpoint   string
-	Model      string
-	APIKey     string
-	Parameters map[string]interface{}
+	Type       ProviderType           `json:"type"`
+	Endpoint   string                 `json:"endpoint,omitempty"`
+	Model      string                 `json:"model,omitempty"`
+	APIKey     string                 `json:"api_key,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Inner configures the provider wrapped by decorating providers such as replay
+	Inner *ProviderConfig `json:"inner,omitempty"`
+	// Fallback configures the provider asked when this one fails; it may have a fallback of its own
+	Fallback *ProviderConfig `json:"fallback,omitempty"`
+	// RateLimiter, when set, paces the requests of model providers. Share one
+	// limiter between the providers of an endpoint; decorating providers pass
+	// it on to the provider they wrap.
+	RateLimiter *llm.RateLimiter `json:"-"`
+	// Tracker, when set, records the usage of model providers and enforces
+	// session budgets. Decorating providers and fallbacks pass it on.
+	Tracker *usage.Tracker `json:"-"`
+}
+
+// rateLimited wraps a client in the limiter when one is set
+func rateLimited(client llm.Client, limiter *llm.RateLimiter) llm.Client {
+	if limiter == nil {
+		return client
+	}
+	return llm.WithRateLimit(client, limiter)
+}
+
+// tracked wraps a client in the usage tracker when one is set
+func tracked(client llm.Client, tracker *usage.Tracker) llm.Client {
+	if tracker == nil {
+		return client
+	}
+	return usage.Track(client, tracker)
+}
+
+// Factory creates an uninitialized provider for a configuration
+type Factory func(config ProviderConfig) (Provider, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[ProviderType]Factory
+}{factories: make(map[ProviderType]Factory)}
+
+func init() {
+	builtin := map[ProviderType]Factory{
+		ProviderOpenAI:   func(ProviderConfig) (Provider, error) { return NewOpenAIProvider(), nil },
+		ProviderOllama:   func(ProviderConfig) (Provider, error) { return NewOllamaProvider(), nil },
+		ProviderMock:     func(ProviderConfig) (Provider, error) { return NewMockProvider(), nil },
+		ProviderReplay:   newReplayFromConfig,
+		ProviderTemplate: func(ProviderConfig) (Provider, error) { return NewTemplateProvider(0), nil },
+	}
+	for name, factory := range builtin {
+		if err := Register(name, factory); err != nil {
+			panic(err)
+		}
+	}
 }
 
-// NewProvider creates a new AI provider based on the configuration
+// Register makes a provider implementation available by name
+func Register(name ProviderType, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("provider name cannot be empty")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, exists := registry.factories[name]; exists {
+		return fmt.Errorf("provider %q is already registered", name)
+	}
+	registry.factories[name] = factory
+	return nil
+}
+
+// Registered returns the names of all registered providers in alphabetical order
+func Registered() []ProviderType {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]ProviderType, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// NewProvider creates a new AI provider based on the configuration.
+// The provider still has to be initialized; use Open to do both.
 func NewProvider(config ProviderConfig) (Provider, error) {
-	switch config.Type {
-	case ProviderOpenAI:
-		return NewOpenAIProvider(), nil
-	default:
+	registry.RLock()
+	factory, ok := registry.factories[config.Type]
+	registry.RUnlock()
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider type: %s", config.Type)
 	}
+	return factory(config)
+}
+
+// Open creates and initializes the provider described by the configuration,
+// wrapped in a FallbackProvider when it has a fallback
+func Open(config ProviderConfig) (Provider, error) {
+	provider, err := NewProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.Initialize(config); err != nil {
+		return nil, fmt.Errorf("failed to initialize %s provider: %w", config.Type, err)
+	}
+	if config.Fallback != nil {
+		fallbackConfig := *config.Fallback
+		if fallbackConfig.Tracker == nil {
+			fallbackConfig.Tracker = config.Tracker
+		}
+		fallback, err := Open(fallbackConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open fallback of %s provider: %w", config.Type, err)
+		}
+		provider = NewFallbackProvider(provider, fallback)
+	}
+	return provider, nil
 }
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"textadventureservices/services/llm"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	t.Run("Built-in providers are registered", func(t *testing.T) {
+		registered := map[ProviderType]bool{}
+		for _, name := range Registered() {
+			registered[name] = true
+		}
+		for _, name := range []ProviderType{ProviderOpenAI, ProviderOllama, ProviderMock, ProviderReplay} {
+			if !registered[name] {
+				t.Errorf("expected %s to be registered", name)
+			}
+		}
+	})
+
+	t.Run("Register rejects duplicates and empty names", func(t *testing.T) {
+		factory := func(ProviderConfig) (Provider, error) { return NewMockProvider(), nil }
+		if err := Register(ProviderMock, factory); err == nil {
+			t.Error("expected error registering mock twice")
+		}
+		if err := Register("", factory); err == nil {
+			t.Error("expected error for an empty name")
+		}
+	})
+
+	t.Run("Custom providers can be opened by name", func(t *testing.T) {
+		if err := Register("test-custom", func(ProviderConfig) (Provider, error) { return NewMockProvider(), nil }); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		provider, err := Open(ProviderConfig{Type: "test-custom", Model: "custom"})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if model := provider.(*MockProvider).Model(); model != "custom" {
+			t.Errorf("expected the provider to be initialized, got model %q", model)
+		}
+	})
+
+	t.Run("Unknown providers are rejected", func(t *testing.T) {
+		if _, err := Open(ProviderConfig{Type: "nonexistent"}); err == nil || !strings.Contains(err.Error(), "unsupported") {
+			t.Errorf("expected unsupported provider error, got %v", err)
+		}
+	})
+}
+
+func TestMockProvider(t *testing.T) {
+	provider, err := Open(ProviderConfig{Type: ProviderMock})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	description, err := provider.GenerateDescription(context.Background(), "a cellar")
+	if err != nil || !strings.Contains(description, "a cellar") {
+		t.Errorf("unexpected description %q, %v", description, err)
+	}
+	first, _ := provider.GenerateObjects(context.Background(), "a cellar")
+	second, _ := provider.GenerateObjects(context.Background(), "a cellar")
+	if len(first) != 3 || strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("expected three stable objects, got %q and %q", first, second)
+	}
+}
+
+func TestReplayFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.json")
+	config := ProviderConfig{
+		Type:       ProviderReplay,
+		Parameters: map[string]interface{}{"path": path, "mode": "record"},
+		Inner:      &ProviderConfig{Type: ProviderMock},
+	}
+
+	provider, err := Open(config)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	replay := provider.(*ReplayProvider)
+	recorded, err := replay.WithSeed(7).GenerateDescription(context.Background(), "a cellar")
+	if err != nil {
+		t.Fatalf("GenerateDescription failed: %v", err)
+	}
+	if err := replay.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Replaying the store needs no wrapped provider
+	replayed, err := Open(ProviderConfig{
+		Type:       ProviderReplay,
+		Model:      "mock",
+		Parameters: map[string]interface{}{"path": path, "mode": "replay"},
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	description, err := replayed.(*ReplayProvider).WithSeed(7).GenerateDescription(context.Background(), "a cellar")
+	if err != nil || description != recorded {
+		t.Errorf("expected recorded description %q, got %q (%v)", recorded, description, err)
+	}
+
+	if _, err := Open(ProviderConfig{Type: ProviderReplay}); err == nil {
+		t.Error("expected error without a path")
+	}
+	if _, err := Open(ProviderConfig{Type: ProviderReplay, Parameters: map[string]interface{}{"path": path, "mode": "bogus"}}); err == nil {
+		t.Error("expected error for an invalid mode")
+	}
+}
+
+func TestProvidersShareRateLimiter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"A quiet room."},"done":true}`)
+	}))
+	defer server.Close()
+
+	limiter := llm.NewRateLimiter(2, 0)
+	var providers []Provider
+	for i := 0; i < 2; i++ {
+		provider, err := Open(ProviderConfig{Type: ProviderOllama, Endpoint: server.URL, Model: "llama3", RateLimiter: limiter})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		providers = append(providers, provider)
+	}
+
+	for _, provider := range providers {
+		if _, err := provider.GenerateDescription(context.Background(), "a room"); err != nil {
+			t.Fatalf("GenerateDescription failed: %v", err)
+		}
+	}
+	// The limit of two requests a minute is used up by both providers together
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := providers[0].GenerateDescription(ctx, "a room"); !errors.Is(err, llm.ErrRequestLimit) {
+		t.Errorf("expected the shared request limit, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests to reach the server, got %d", calls)
+	}
+}
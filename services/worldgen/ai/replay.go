@@ -0,0 +1,414 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReplayMode controls how a ReplayProvider uses its response store
+type ReplayMode string
+
+const (
+	// ReplayRecord always calls the wrapped provider and records the response
+	ReplayRecord ReplayMode = "record"
+	// ReplayReplay only serves recorded responses and never calls the wrapped provider
+	ReplayReplay ReplayMode = "replay"
+	// ReplayAuto serves recorded responses and records any that are missing
+	ReplayAuto ReplayMode = "auto"
+)
+
+// ErrReplayMiss is returned in replay mode when no recorded response exists
+var ErrReplayMiss = errors.New("no recorded response")
+
+// ErrStructuredUnsupported is returned when the wrapped provider cannot generate structured rooms
+var ErrStructuredUnsupported = errors.New("provider does not support structured rooms")
+
+// Response kinds used as part of the replay key
+const (
+	kindDescription = "description"
+	kindObjects     = "objects"
+	kindEnhance     = "enhance"
+	kindRoom        = "room"
+	kindObjectSpecs = "object specs"
+)
+
+// ReplayEntry is a single recorded provider response
+type ReplayEntry struct {
+	Seed   int64  `json:"seed"`
+	Model  string `json:"model"`
+	Kind   string `json:"kind"`
+	Prompt string `json:"prompt"`
+	// Room is the ID of the room the response was generated for, if any
+	Room     string   `json:"room,omitempty"`
+	Response string   `json:"response,omitempty"`
+	Objects  []string `json:"objects,omitempty"`
+}
+
+// ResponseStore holds recorded provider responses keyed by seed, model, prompt and room
+type ResponseStore struct {
+	mu      sync.RWMutex
+	Entries map[string]ReplayEntry `json:"entries"`
+}
+
+// NewResponseStore creates an empty response store
+func NewResponseStore() *ResponseStore {
+	return &ResponseStore{
+		Entries: make(map[string]ReplayEntry),
+	}
+}
+
+// LoadResponseStore reads a response store from a JSON file.
+// A missing file yields an empty store so that recording can start from scratch.
+func LoadResponseStore(filename string) (*ResponseStore, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewResponseStore(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response store: %w", err)
+	}
+
+	store := NewResponseStore()
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response store: %w", err)
+	}
+	if store.Entries == nil {
+		store.Entries = make(map[string]ReplayEntry)
+	}
+	return store, nil
+}
+
+// Save writes the response store to a JSON file. The file is written next
+// to filename and renamed over it, so readers never see a partial store.
+func (s *ResponseStore) Save(filename string) error {
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal response store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".replay-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write response store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write response store: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write response store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace response store: %w", err)
+	}
+	return nil
+}
+
+// ReplayKey derives the store key for a response. Responses for different
+// rooms get different keys even when their prompts are the same.
+func ReplayKey(seed int64, model, kind, prompt, room string) string {
+	h := sha256.New()
+	for _, part := range []string{strconv.FormatInt(seed, 10), model, kind, prompt, room} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup returns the recorded entry for the given key parts
+func (s *ResponseStore) Lookup(seed int64, model, kind, prompt, room string) (ReplayEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.Entries[ReplayKey(seed, model, kind, prompt, room)]
+	return entry, ok
+}
+
+// Record stores an entry, replacing any previous response for the same key
+func (s *ResponseStore) Record(entry ReplayEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Entries[ReplayKey(entry.Seed, entry.Model, entry.Kind, entry.Prompt, entry.Room)] = entry
+}
+
+type roomKey struct{}
+
+// WithRoom returns a context whose provider calls are made for the room with
+// the given ID. Replay keeps the responses of rooms apart by it, so rooms
+// with the same prompt replay their own text whatever order they were
+// generated in.
+func WithRoom(ctx context.Context, roomID string) context.Context {
+	return context.WithValue(ctx, roomKey{}, roomID)
+}
+
+// roomFrom returns the room ID of a context, or "" outside a room
+func roomFrom(ctx context.Context) string {
+	room, _ := ctx.Value(roomKey{}).(string)
+	return room
+}
+
+// Len returns the number of recorded entries
+func (s *ResponseStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.Entries)
+}
+
+// ReplayProvider wraps a Provider and records or replays its responses
+type ReplayProvider struct {
+	inner Provider
+	store *ResponseStore
+	seed  int64
+	model string
+	mode  ReplayMode
+	// path is where Save writes the store; set when created from a ProviderConfig
+	path string
+}
+
+// newReplayFromConfig creates a replay provider from the registry. The store
+// file and mode come from the "path" and "mode" parameters, the wrapped
+// provider from config.Inner. Initialize loads the store.
+func newReplayFromConfig(config ProviderConfig) (Provider, error) {
+	path, _ := config.Parameters["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("replay provider needs a path parameter")
+	}
+	mode := ReplayAuto
+	if value, ok := config.Parameters["mode"].(string); ok && value != "" {
+		mode = ReplayMode(value)
+	}
+	switch mode {
+	case ReplayRecord, ReplayReplay, ReplayAuto:
+	default:
+		return nil, fmt.Errorf("invalid replay mode: %s", mode)
+	}
+
+	var inner Provider
+	if config.Inner != nil {
+		var err error
+		if inner, err = NewProvider(*config.Inner); err != nil {
+			return nil, fmt.Errorf("failed to create wrapped provider: %w", err)
+		}
+	}
+	return &ReplayProvider{inner: inner, store: NewResponseStore(), mode: mode, path: path}, nil
+}
+
+// NewReplayProvider creates a provider that records responses from inner into store,
+// or replays them from it, depending on mode. inner may be nil in ReplayReplay mode.
+// If model is empty it is taken from inner when inner reports its model.
+func NewReplayProvider(inner Provider, store *ResponseStore, seed int64, model string, mode ReplayMode) *ReplayProvider {
+	if model == "" {
+		if named, ok := inner.(interface{ Model() string }); ok {
+			model = named.Model()
+		}
+	}
+	return &ReplayProvider{
+		inner: inner,
+		store: store,
+		seed:  seed,
+		model: model,
+		mode:  mode,
+	}
+}
+
+// Initialize loads the store file, if the provider was created from a
+// configuration, and initializes the wrapped provider, if any
+func (p *ReplayProvider) Initialize(config ProviderConfig) error {
+	if p.path != "" {
+		store, err := LoadResponseStore(p.path)
+		if err != nil {
+			return err
+		}
+		p.store = store
+	}
+
+	innerConfig := config
+	if config.Inner != nil {
+		innerConfig = *config.Inner
+		if innerConfig.RateLimiter == nil {
+			innerConfig.RateLimiter = config.RateLimiter
+		}
+		if innerConfig.Tracker == nil {
+			innerConfig.Tracker = config.Tracker
+		}
+	}
+	if p.model == "" {
+		p.model = config.Model
+	}
+	if p.model == "" {
+		p.model = innerConfig.Model
+	}
+	if p.inner == nil {
+		return nil
+	}
+	if err := p.inner.Initialize(innerConfig); err != nil {
+		return err
+	}
+	if named, ok := p.inner.(interface{ Model() string }); ok && p.model == "" {
+		p.model = named.Model()
+	}
+	return nil
+}
+
+// WithSeed returns a provider sharing the same store and wrapped provider
+// whose responses are keyed by another seed, e.g. the seed of a new world
+func (p *ReplayProvider) WithSeed(seed int64) *ReplayProvider {
+	clone := *p
+	clone.seed = seed
+	return &clone
+}
+
+// Save writes the store back to the file it was loaded from. It does nothing
+// in replay mode or when the provider was not created from a configuration.
+func (p *ReplayProvider) Save() error {
+	if p.path == "" || p.mode == ReplayReplay {
+		return nil
+	}
+	return p.store.Save(p.path)
+}
+
+// Model returns the model name used in replay keys
+func (p *ReplayProvider) Model() string {
+	return p.model
+}
+
+// Store returns the underlying response store
+func (p *ReplayProvider) Store() *ResponseStore {
+	return p.store
+}
+
+// lookup returns a recorded entry if the mode allows serving from the store.
+// It returns ErrReplayMiss when the entry is required but missing.
+func (p *ReplayProvider) lookup(ctx context.Context, kind, prompt string) (ReplayEntry, bool, error) {
+	room := roomFrom(ctx)
+	if p.mode != ReplayRecord {
+		if entry, ok := p.store.Lookup(p.seed, p.model, kind, prompt, room); ok {
+			return entry, true, nil
+		}
+	}
+	if p.mode == ReplayReplay || p.inner == nil {
+		return ReplayEntry{}, false, fmt.Errorf("%w for %s prompt %q (seed %d, model %s, room %q)", ErrReplayMiss, kind, prompt, p.seed, p.model, room)
+	}
+	return ReplayEntry{}, false, nil
+}
+
+func (p *ReplayProvider) text(ctx context.Context, kind, prompt string, call func(context.Context, string) (string, error)) (string, error) {
+	entry, ok, err := p.lookup(ctx, kind, prompt)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return entry.Response, nil
+	}
+
+	response, err := call(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	p.store.Record(ReplayEntry{Seed: p.seed, Model: p.model, Kind: kind, Prompt: prompt, Room: roomFrom(ctx), Response: response})
+	return response, nil
+}
+
+// GenerateDescription returns the recorded description or records a new one
+func (p *ReplayProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	return p.text(ctx, kindDescription, prompt, func(ctx context.Context, prompt string) (string, error) {
+		return p.inner.GenerateDescription(ctx, prompt)
+	})
+}
+
+// EnhancePrompt returns the recorded prompt or records a new one
+func (p *ReplayProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	return p.text(ctx, kindEnhance, basePrompt, func(ctx context.Context, prompt string) (string, error) {
+		return p.inner.EnhancePrompt(ctx, prompt)
+	})
+}
+
+// GenerateObjects returns the recorded objects or records new ones
+func (p *ReplayProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	entry, ok, err := p.lookup(ctx, kindObjects, sceneDescription)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return append([]string(nil), entry.Objects...), nil
+	}
+
+	objects, err := p.inner.GenerateObjects(ctx, sceneDescription)
+	if err != nil {
+		return nil, err
+	}
+	p.store.Record(ReplayEntry{Seed: p.seed, Model: p.model, Kind: kindObjects, Prompt: sceneDescription, Room: roomFrom(ctx), Objects: objects})
+	return objects, nil
+}
+
+// GenerateRoomSpec returns the recorded room or records a new one.
+// The wrapped provider must implement StructuredProvider to record rooms.
+func (p *ReplayProvider) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	prompt := req.Prompt + "\nexits: " + strings.Join(req.Exits, ", ")
+	structured, ok := p.inner.(StructuredProvider)
+	if !ok && p.mode != ReplayReplay {
+		// Without a structured provider only recorded rooms can be served
+		entry, found := p.store.Lookup(p.seed, p.model, kindRoom, prompt, roomFrom(ctx))
+		if !found || p.mode == ReplayRecord {
+			return nil, ErrStructuredUnsupported
+		}
+		return ParseRoomSpec(entry.Response, req.Exits)
+	}
+
+	response, err := p.text(ctx, kindRoom, prompt, func(ctx context.Context, prompt string) (string, error) {
+		spec, err := structured.GenerateRoomSpec(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(spec)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal room: %w", err)
+		}
+		return string(data), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseRoomSpec(response, req.Exits)
+}
+
+// GenerateObjectSpecs returns the recorded objects or records new ones.
+// Without recorded objects or a wrapped ObjectSpecProvider it returns
+// ErrStructuredUnsupported, so callers fall back to GenerateObjects.
+func (p *ReplayProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error) {
+	if p.mode != ReplayRecord {
+		if entry, found := p.store.Lookup(p.seed, p.model, kindObjectSpecs, sceneDescription, roomFrom(ctx)); found {
+			return ParseObjectSpecs(entry.Response)
+		}
+	}
+	described, ok := p.inner.(ObjectSpecProvider)
+	if !ok || p.mode == ReplayReplay {
+		return nil, ErrStructuredUnsupported
+	}
+
+	objects, err := described.GenerateObjectSpecs(ctx, sceneDescription)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(struct {
+		Objects []ObjectSpec `json:"objects"`
+	}{objects})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal objects: %w", err)
+	}
+	p.store.Record(ReplayEntry{Seed: p.seed, Model: p.model, Kind: kindObjectSpecs, Prompt: sceneDescription, Room: roomFrom(ctx), Response: string(data)})
+	return objects, nil
+}
@@ -0,0 +1,421 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultStructuredAttempts is how often a provider is asked for a room before giving up
+const DefaultStructuredAttempts = 3
+
+// RoomSpec is the structured description of a room returned by a provider
+type RoomSpec struct {
+	// Name is a short title such as "Flooded Crypt"
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Objects     []ObjectSpec `json:"objects"`
+	// Exits describe the room's exits, keyed by direction
+	Exits []ExitSpec `json:"exits"`
+	// Ambient lists background details such as sounds and smells
+	Ambient []string `json:"ambient"`
+}
+
+// ObjectSpec is an object inside a RoomSpec
+type ObjectSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+// ExitSpec is a suggested exit inside a RoomSpec
+type ExitSpec struct {
+	Direction   string `json:"direction"`
+	Description string `json:"description"`
+}
+
+// RoomSpecSchema is the JSON schema RoomSpec documents must match.
+// It is sent to the model as part of the prompt.
+const RoomSpecSchema = `{
+  "type": "object",
+  "required": ["name", "description", "objects", "exits", "ambient"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 60},
+    "description": {"type": "string", "minLength": 1},
+    "objects": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "description"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "description": {"type": "string", "minLength": 1},
+          "properties": {
+            "type": "object",
+            "properties": {
+              "portable": {"type": "boolean"},
+              "weight": {"type": "number", "minimum": 0},
+              "container": {"type": "boolean"}
+            }
+          }
+        }
+      }
+    },
+    "exits": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["direction", "description"],
+        "additionalProperties": false,
+        "properties": {
+          "direction": {"type": "string", "minLength": 1},
+          "description": {"type": "string"}
+        }
+      }
+    },
+    "ambient": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+// RoomRequest asks a structured provider for a room
+type RoomRequest struct {
+	Prompt string
+	// Exits are the directions the room must describe; other exits are rejected
+	Exits []string
+}
+
+// StructuredProvider is implemented by providers that can return rooms as
+// JSON documents matching RoomSpecSchema
+type StructuredProvider interface {
+	GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error)
+}
+
+// SchemaError lists the ways a document violates RoomSpecSchema
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "room does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// ErrMalformedOutput is returned when a provider keeps producing invalid rooms
+var ErrMalformedOutput = errors.New("provider returned malformed output")
+
+// extractJSONObject returns the outermost JSON object in text, skipping any
+// prose or code fences a model puts around it
+func extractJSONObject(text string) (string, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("no JSON object found in output")
+	}
+	return text[start : end+1], nil
+}
+
+// ParseRoomSpec extracts a RoomSpec from model output and validates it
+// against the schema. exits are the directions the room may describe.
+func ParseRoomSpec(text string, exits []string) (*RoomSpec, error) {
+	raw, err := extractJSONObject(text)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	var spec RoomSpec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := spec.validate(fields, exits); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// validate checks the constraints of RoomSpecSchema that decoding cannot
+func (s *RoomSpec) validate(fields map[string]json.RawMessage, exits []string) error {
+	var problems []string
+	for _, field := range []string{"name", "description", "objects", "exits", "ambient"} {
+		if _, ok := fields[field]; !ok {
+			problems = append(problems, fmt.Sprintf("missing required field %q", field))
+		}
+	}
+
+	s.Name = strings.TrimSpace(s.Name)
+	s.Description = strings.TrimSpace(s.Description)
+	if s.Name == "" {
+		problems = append(problems, "name must not be empty")
+	} else if len(s.Name) > 60 {
+		problems = append(problems, "name must be at most 60 characters")
+	}
+	if s.Description == "" {
+		problems = append(problems, "description must not be empty")
+	}
+
+	for i := range s.Objects {
+		object := &s.Objects[i]
+		object.Name = strings.TrimSpace(object.Name)
+		object.Description = strings.TrimSpace(object.Description)
+		if object.Name == "" {
+			problems = append(problems, fmt.Sprintf("objects[%d].name must not be empty", i))
+		}
+		if object.Description == "" {
+			problems = append(problems, fmt.Sprintf("objects[%d].description must not be empty", i))
+		}
+		for _, check := range []struct{ key, want string }{{"portable", "boolean"}, {"weight", "number"}, {"container", "boolean"}} {
+			key, want := check.key, check.want
+			value, ok := object.Properties[key]
+			if !ok {
+				continue
+			}
+			switch v := value.(type) {
+			case bool:
+				if want != "boolean" {
+					problems = append(problems, fmt.Sprintf("objects[%d].properties.%s must be a %s", i, key, want))
+				}
+			case float64:
+				if want != "number" || v < 0 {
+					problems = append(problems, fmt.Sprintf("objects[%d].properties.%s must be a non-negative %s", i, key, want))
+				}
+			default:
+				problems = append(problems, fmt.Sprintf("objects[%d].properties.%s must be a %s", i, key, want))
+			}
+		}
+	}
+
+	allowed := make(map[string]bool)
+	for _, exit := range exits {
+		allowed[strings.ToLower(exit)] = true
+	}
+	for i := range s.Exits {
+		exit := &s.Exits[i]
+		exit.Direction = strings.ToLower(strings.TrimSpace(exit.Direction))
+		if exit.Direction == "" {
+			problems = append(problems, fmt.Sprintf("exits[%d].direction must not be empty", i))
+		} else if len(allowed) > 0 && !allowed[exit.Direction] {
+			problems = append(problems, fmt.Sprintf("exits[%d].direction %q is not one of %s", i, exit.Direction, strings.Join(exits, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// roomSpecMessages builds the conversation asking for a room
+func roomSpecMessages(req RoomRequest) []Message {
+	exits := "none"
+	if len(req.Exits) > 0 {
+		exits = strings.Join(req.Exits, ", ")
+	}
+	return []Message{
+		{
+			Role: "system",
+			Content: "You are a creative writing AI generating rooms for text adventure games. " +
+				"Reply with a single JSON document matching this JSON schema and nothing else:\n" + RoomSpecSchema,
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("Create a room for this prompt: %s\n"+
+				"The room has these exits, describe each of them: %s\n"+
+				"Include 3-5 interactive objects.", req.Prompt, exits),
+		},
+	}
+}
+
+// RequestRoomSpec asks complete for a room and re-prompts with the validation
+// errors until it returns a document matching the schema, at most attempts times
+func RequestRoomSpec(ctx context.Context, complete func(context.Context, []Message) (string, error), req RoomRequest, attempts int) (*RoomSpec, error) {
+	var spec *RoomSpec
+	err := requestJSON(ctx, complete, roomSpecMessages(req), attempts, func(output string) (err error) {
+		spec, err = ParseRoomSpec(output, req.Exits)
+		return err
+	})
+	return spec, err
+}
+
+// requestJSON sends messages to complete and re-prompts with the error from
+// parse until parse accepts the reply, at most attempts times
+func requestJSON(ctx context.Context, complete func(context.Context, []Message) (string, error), messages []Message, attempts int, parse func(string) error) error {
+	if attempts <= 0 {
+		attempts = DefaultStructuredAttempts
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		output, err := complete(ctx, messages)
+		if err != nil {
+			return err
+		}
+		err = parse(output)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		// Show the model its mistake and ask for a corrected document
+		messages = append(messages,
+			Message{Role: "assistant", Content: output},
+			Message{Role: "user", Content: fmt.Sprintf("That reply was invalid: %v. Reply again with only the corrected JSON document.", err)},
+		)
+	}
+	return fmt.Errorf("%w after %d attempts: %v", ErrMalformedOutput, attempts, lastErr)
+}
+
+// ObjectListSchema is the JSON schema of the objects of a scene, each named
+// and described, as returned by an ObjectSpecProvider
+const ObjectListSchema = `{
+  "type": "object",
+  "required": ["objects"],
+  "properties": {
+    "objects": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "description"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "description": {"type": "string", "minLength": 1}
+        }
+      }
+    }
+  }
+}`
+
+// ObjectSpecProvider is implemented by providers that can list the objects of
+// a scene together with their descriptions in a single call
+type ObjectSpecProvider interface {
+	GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error)
+}
+
+// ParseObjectSpecs extracts the objects from model output matching ObjectListSchema
+func ParseObjectSpecs(text string) ([]ObjectSpec, error) {
+	raw, err := extractJSONObject(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Objects *[]ObjectSpec `json:"objects"`
+	}
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if list.Objects == nil {
+		return nil, &SchemaError{Problems: []string{`missing required field "objects"`}}
+	}
+
+	objects := *list.Objects
+	var problems []string
+	for i := range objects {
+		object := &objects[i]
+		object.Name = strings.TrimSpace(object.Name)
+		object.Description = strings.TrimSpace(object.Description)
+		if object.Name == "" {
+			problems = append(problems, fmt.Sprintf("objects[%d].name must not be empty", i))
+		}
+		if object.Description == "" {
+			problems = append(problems, fmt.Sprintf("objects[%d].description must not be empty", i))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &SchemaError{Problems: problems}
+	}
+	return objects, nil
+}
+
+// objectSpecMessages builds the conversation asking for the objects of a scene
+func objectSpecMessages(sceneDescription string) []Message {
+	return []Message{
+		{
+			Role: "system",
+			Content: "You are an AI that generates interactive objects for text adventure games. " +
+				"Reply with a single JSON document matching this JSON schema and nothing else:\n" + ObjectListSchema,
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("List 3-5 interactive objects that would naturally be found in this scene, "+
+				"and describe each of them in one or two sentences: %s", sceneDescription),
+		},
+	}
+}
+
+// RequestObjectSpecs asks complete for the described objects of a scene,
+// re-prompting like RequestRoomSpec when the reply does not match the schema
+func RequestObjectSpecs(ctx context.Context, complete func(context.Context, []Message) (string, error), sceneDescription string, attempts int) ([]ObjectSpec, error) {
+	var objects []ObjectSpec
+	err := requestJSON(ctx, complete, objectSpecMessages(sceneDescription), attempts, func(output string) (err error) {
+		objects, err = ParseObjectSpecs(output)
+		return err
+	})
+	return objects, err
+}
+
+// listMarker matches bullets and numbering in front of list items
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]+|\(?\d+[.):]|\(?[a-z][.)])\s+`)
+
+// TrimListMarker removes a bullet or numbering such as "-", "2." or "(b)"
+// from the start of a list item
+func TrimListMarker(item string) string {
+	return listMarker.ReplaceAllString(item, "")
+}
+
+// ParseList splits a model's list answer into items. It accepts comma-separated
+// lists as well as bulleted or numbered lines, and skips a leading sentence
+// such as "Here are some objects:".
+func ParseList(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	// Drop an introduction ending with a colon, e.g. "Here are the objects: a, b"
+	if i := strings.Index(text, ":"); i >= 0 && !strings.Contains(text[:i], ",") && !listMarker.MatchString(text[:i]) {
+		if rest := strings.TrimSpace(text[i+1:]); rest != "" {
+			text = rest
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var raw []string
+	if len(lines) > 1 {
+		for _, line := range lines {
+			// Skip prose lines around a bulleted or numbered list
+			if !listMarker.MatchString(line) && strings.HasSuffix(line, ":") {
+				continue
+			}
+			raw = append(raw, listMarker.ReplaceAllString(line, ""))
+		}
+	} else {
+		raw = strings.Split(text, ",")
+	}
+
+	var items []string
+	for _, item := range raw {
+		item = listMarker.ReplaceAllString(strings.TrimSpace(item), "")
+		item = strings.TrimPrefix(item, "and ")
+		item = strings.Trim(strings.TrimSpace(item), ".;\"'`*")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
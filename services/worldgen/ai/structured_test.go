@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validRoom = `{
+  "name": "Flooded Crypt",
+  "description": "Black water laps at the stone coffins.",
+  "objects": [{"name": "stone coffin", "description": "Cracked and damp.", "properties": {"portable": false, "weight": 500}}],
+  "exits": [{"direction": "North", "description": "A flooded stairway"}],
+  "ambient": ["dripping water"]
+}`
+
+func TestParseRoomSpecSkipsProse(t *testing.T) {
+	output := "Sure! Here is the room you asked for:\n```json\n" + validRoom + "\n```\nEnjoy!"
+	spec, err := ParseRoomSpec(output, []string{"north"})
+	if err != nil {
+		t.Fatalf("Failed to parse room: %v", err)
+	}
+	if spec.Name != "Flooded Crypt" || len(spec.Objects) != 1 || spec.Exits[0].Direction != "north" {
+		t.Errorf("Unexpected room: %+v", spec)
+	}
+}
+
+func TestParseRoomSpecRejectsSchemaViolations(t *testing.T) {
+	tests := map[string]string{
+		"no json":              "The crypt is dark.",
+		"missing fields":       `{"name": "Crypt", "description": "Dark."}`,
+		"empty name":           `{"name": "", "description": "Dark.", "objects": [], "exits": [], "ambient": []}`,
+		"unknown object field": `{"name": "Crypt", "description": "Dark.", "objects": [{"name": "urn", "description": "Cold.", "color": "grey"}], "exits": [], "ambient": []}`,
+		"unknown field":        `{"name": "Crypt", "description": "Dark.", "objects": [], "exits": [], "ambient": [], "mood": "grim"}`,
+		"bad property":         `{"name": "Crypt", "description": "Dark.", "objects": [{"name": "urn", "description": "", "properties": {"weight": "heavy"}}], "exits": [], "ambient": []}`,
+		"no object text":       `{"name": "Crypt", "description": "Dark.", "objects": [{"name": "urn", "description": " "}], "exits": [], "ambient": []}`,
+		"unknown exit":         `{"name": "Crypt", "description": "Dark.", "objects": [], "exits": [{"direction": "west", "description": ""}], "ambient": []}`,
+	}
+	for name, output := range tests {
+		if _, err := ParseRoomSpec(output, []string{"north"}); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestRequestRoomSpecReprompts(t *testing.T) {
+	replies := []string{"1. A crypt\n2. Some coffins", validRoom}
+	var conversations [][]Message
+	complete := func(ctx context.Context, messages []Message) (string, error) {
+		conversations = append(conversations, messages)
+		return replies[len(conversations)-1], nil
+	}
+
+	spec, err := RequestRoomSpec(context.Background(), complete, RoomRequest{Prompt: "A crypt", Exits: []string{"north"}}, 3)
+	if err != nil {
+		t.Fatalf("Failed to request room: %v", err)
+	}
+	if spec.Name != "Flooded Crypt" {
+		t.Errorf("Expected the corrected room, got %+v", spec)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(conversations))
+	}
+	retry := conversations[1]
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "invalid") {
+		t.Errorf("Expected the retry to explain the error, got %+v", last)
+	}
+
+	always := func(ctx context.Context, messages []Message) (string, error) { return "no", nil }
+	if _, err := RequestRoomSpec(context.Background(), always, RoomRequest{Prompt: "A crypt"}, 2); !errors.Is(err, ErrMalformedOutput) {
+		t.Errorf("Expected ErrMalformedOutput, got %v", err)
+	}
+}
+
+func TestRequestObjectSpecsReprompts(t *testing.T) {
+	replies := []string{
+		`{"objects": [{"name": "brass key", "description": ""}]}`,
+		`{"objects": [{"name": "brass key", "description": "Green with age."}, {"name": "urn", "description": "It rattles."}]}`,
+	}
+	calls := 0
+	complete := func(ctx context.Context, messages []Message) (string, error) {
+		calls++
+		return replies[calls-1], nil
+	}
+
+	objects, err := RequestObjectSpecs(context.Background(), complete, "A crypt", 3)
+	if err != nil {
+		t.Fatalf("Failed to request objects: %v", err)
+	}
+	want := []ObjectSpec{{Name: "brass key", Description: "Green with age."}, {Name: "urn", Description: "It rattles."}}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("Expected %+v, got %+v", want, objects)
+	}
+	if calls != 2 {
+		t.Errorf("Expected an empty description to be re-prompted once, got %d requests", calls)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := map[string][]string{
+		"lamp, rusty key, rope":                                {"lamp", "rusty key", "rope"},
+		"Here are some objects: lamp, rusty key, and rope.":    {"lamp", "rusty key", "rope"},
+		"1. Lamp\n2. Rusty key\n3. Rope":                       {"Lamp", "Rusty key", "Rope"},
+		"Objects in the scene:\n- lamp\n- rusty key\n* rope":   {"lamp", "rusty key", "rope"},
+		"Here are the objects:\n1) **lamp**\n2) rusty key\n\n": {"lamp", "rusty key"},
+		"": nil,
+	}
+	for input, want := range tests {
+		if got := ParseList(input); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseList(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestReplayProviderRecordsRooms(t *testing.T) {
+	store := NewResponseStore()
+	inner := &stubStructured{spec: &RoomSpec{Name: "Crypt", Description: "Dark.", Exits: []ExitSpec{}, Objects: []ObjectSpec{}, Ambient: []string{}}}
+	req := RoomRequest{Prompt: "A crypt", Exits: []string{"north"}}
+
+	recorder := NewReplayProvider(inner, store, 1, "test-model", ReplayRecord)
+	if _, err := recorder.GenerateRoomSpec(context.Background(), req); err != nil {
+		t.Fatalf("Failed to record room: %v", err)
+	}
+
+	replayer := NewReplayProvider(nil, store, 1, "test-model", ReplayReplay)
+	spec, err := replayer.GenerateRoomSpec(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Failed to replay room: %v", err)
+	}
+	if spec.Name != "Crypt" || inner.calls != 1 {
+		t.Errorf("Expected the recorded room without calling the provider again, got %+v after %d calls", spec, inner.calls)
+	}
+}
+
+type stubStructured struct {
+	Provider
+	spec  *RoomSpec
+	calls int
+}
+
+func (s *stubStructured) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	s.calls++
+	return s.spec, nil
+}
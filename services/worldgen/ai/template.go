@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"textadventureservices/services/template"
+)
+
+// TemplateModel is the model name reported by the template provider
+const TemplateModel = template.Model
+
+// TemplateProvider generates text from seeded grammars instead of a model,
+// see package template. Its answers are plausible, deterministic for a seed
+// and prompt, and need no network, which makes it suitable for development
+// and CI.
+type TemplateProvider struct {
+	seed      int64
+	generator *template.Generator
+}
+
+// NewTemplateProvider creates a template provider with the given seed
+func NewTemplateProvider(seed int64) *TemplateProvider {
+	return &TemplateProvider{seed: seed, generator: template.New(seed, nil)}
+}
+
+// Initialize reads the "seed" parameter and an optional "grammar" parameter
+// naming a JSON grammar file whose rules replace the built-in ones
+func (p *TemplateProvider) Initialize(config ProviderConfig) error {
+	switch seed := config.Parameters["seed"].(type) {
+	case nil:
+	case float64:
+		p.seed = int64(seed)
+	case int:
+		p.seed = int64(seed)
+	case int64:
+		p.seed = seed
+	default:
+		return fmt.Errorf("template seed must be a number, got %T", seed)
+	}
+
+	var grammar template.Grammar
+	if path, ok := config.Parameters["grammar"].(string); ok && path != "" {
+		loaded, err := template.LoadGrammar(path)
+		if err != nil {
+			return err
+		}
+		grammar = loaded
+	}
+	p.generator = template.New(p.seed, grammar)
+	return nil
+}
+
+// Model returns TemplateModel
+func (p *TemplateProvider) Model() string {
+	return TemplateModel
+}
+
+// generatorFor returns the generator for the room in ctx, see WithRoom, so
+// rooms with the same prompt get their own text
+func (p *TemplateProvider) generatorFor(ctx context.Context) *template.Generator {
+	return p.generator.ForRoom(roomFrom(ctx))
+}
+
+// GenerateDescription describes the room in the prompt, or the object when
+// the prompt asks for one, as in "Describe the 'lamp' in detail"
+func (p *TemplateProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	return p.generatorFor(ctx).Describe(prompt), nil
+}
+
+// EnhancePrompt adds an atmospheric clause to the prompt
+func (p *TemplateProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	return p.generatorFor(ctx).Enhance(basePrompt), nil
+}
+
+// GenerateObjects picks two to four distinct objects from the theme of the scene
+func (p *TemplateProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	return p.generatorFor(ctx).Objects(sceneDescription), nil
+}
+
+// GenerateObjectSpecs picks objects like GenerateObjects and describes each of them
+func (p *TemplateProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ObjectSpec, error) {
+	generator := p.generatorFor(ctx)
+	rng := generator.Rand(kindObjectSpecs, sceneDescription)
+	return describeObjects(generator.Objects(sceneDescription), generator.Grammar(sceneDescription), rng), nil
+}
+
+// describeObjects gives each named object a description from the grammar
+func describeObjects(names []string, grammar template.Grammar, rng *rand.Rand) []ObjectSpec {
+	objects := make([]ObjectSpec, 0, len(names))
+	for _, name := range names {
+		objects = append(objects, ObjectSpec{
+			Name:        name,
+			Description: grammar.With("item", name).Flatten(rng, "inspect"),
+		})
+	}
+	return objects
+}
+
+// GenerateRoomSpec builds a structured room that describes every requested exit
+func (p *TemplateProvider) GenerateRoomSpec(ctx context.Context, req RoomRequest) (*RoomSpec, error) {
+	generator := p.generatorFor(ctx)
+	rng := generator.Rand(kindRoom, req.Prompt+"\x00"+strings.Join(req.Exits, ","))
+	grammar := generator.Grammar(req.Prompt)
+
+	name := template.PromptLabel(req.Prompt)
+	if name == "" {
+		name = grammar.Flatten(rng, "name")
+	}
+	if len(name) > 60 {
+		name = strings.TrimSpace(name[:60])
+	}
+
+	description, err := p.GenerateDescription(ctx, req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	names, err := p.GenerateObjects(ctx, description)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &RoomSpec{
+		Name:        name,
+		Description: description,
+		Exits:       make([]ExitSpec, 0, len(req.Exits)),
+		Ambient:     []string{grammar.Flatten(rng, "ambient")},
+	}
+	spec.Objects = describeObjects(names, grammar, rng)
+	for _, direction := range req.Exits {
+		spec.Exits = append(spec.Exits, ExitSpec{
+			Direction:   direction,
+			Description: grammar.With("direction", direction).Flatten(rng, "exit"),
+		})
+	}
+	return spec, nil
+}
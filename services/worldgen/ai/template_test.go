@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"textadventureservices/services/template"
+)
+
+func TestTemplateProvider(t *testing.T) {
+	ctx := context.Background()
+	provider, err := Open(ProviderConfig{Type: ProviderTemplate, Parameters: map[string]interface{}{"seed": 7.0}})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	t.Run("Descriptions are deterministic and themed", func(t *testing.T) {
+		first, _ := provider.GenerateDescription(ctx, "A flooded cave - Western Wing")
+		second, _ := provider.GenerateDescription(ctx, "A flooded cave - Western Wing")
+		if first != second {
+			t.Errorf("expected the same description twice, got %q and %q", first, second)
+		}
+		if !strings.Contains(first, "flooded cave") {
+			t.Errorf("expected the subject in %q", first)
+		}
+
+		other, _ := NewTemplateProvider(8).GenerateDescription(ctx, "A flooded cave - Western Wing")
+		if other == first {
+			t.Errorf("expected another seed to change the description")
+		}
+
+		objects, _ := provider.GenerateObjects(ctx, first)
+		caveObjects := strings.Join(template.New(7, nil).Grammar("cave")["object"], "|")
+		for _, object := range objects {
+			if !strings.Contains(caveObjects, object) {
+				t.Errorf("expected cave objects, got %q", object)
+			}
+		}
+		if len(objects) < 2 || len(objects) > 4 {
+			t.Errorf("expected two to four objects, got %q", objects)
+		}
+	})
+
+	t.Run("Rooms with the same prompt get their own text", func(t *testing.T) {
+		prompt := "A flooded cave - Cavern"
+		descriptions := make(map[string]bool)
+		for _, room := range []string{"room_1", "room_2", "room_3", "room_4", "room_5", "room_6"} {
+			first, _ := provider.GenerateDescription(WithRoom(ctx, room), prompt)
+			second, _ := provider.GenerateDescription(WithRoom(ctx, room), prompt)
+			if first != second {
+				t.Errorf("expected room %s to be described the same twice, got %q and %q", room, first, second)
+			}
+			descriptions[first] = true
+		}
+		if len(descriptions) < 4 {
+			t.Errorf("expected rooms to be described differently, got %d descriptions for 6 rooms", len(descriptions))
+		}
+	})
+
+	t.Run("Objects are described when asked", func(t *testing.T) {
+		description, _ := provider.GenerateDescription(ctx, "Describe the 'brass key' in detail")
+		if !strings.Contains(description, "brass key") || strings.Contains(description, "Describe") {
+			t.Errorf("unexpected object description %q", description)
+		}
+	})
+
+	t.Run("Structured rooms pass validation", func(t *testing.T) {
+		exits := []string{"north", "east"}
+		spec, err := provider.(StructuredProvider).GenerateRoomSpec(ctx, RoomRequest{Prompt: "A space station - Eastern Wing", Exits: exits})
+		if err != nil {
+			t.Fatalf("GenerateRoomSpec failed: %v", err)
+		}
+		if spec.Name != "Eastern Wing" || len(spec.Exits) != 2 {
+			t.Errorf("unexpected room %+v", spec)
+		}
+		data, _ := json.Marshal(spec)
+		if _, err := ParseRoomSpec(string(data), exits); err != nil {
+			t.Errorf("generated room fails validation: %v", err)
+		}
+	})
+
+	t.Run("A grammar file replaces rules", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "grammar.json")
+		os.WriteFile(path, []byte(`{"object": ["rubber duck"]}`), 0644)
+		custom, err := Open(ProviderConfig{Type: ProviderTemplate, Parameters: map[string]interface{}{"grammar": path}})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		objects, _ := custom.GenerateObjects(ctx, "a bathroom")
+		if !reflect.DeepEqual(objects, []string{"rubber duck"}) {
+			t.Errorf("expected the custom object, got %q", objects)
+		}
+
+		os.WriteFile(path, []byte(`{"object": []}`), 0644)
+		if _, err := Open(ProviderConfig{Type: ProviderTemplate, Parameters: map[string]interface{}{"grammar": path}}); err == nil {
+			t.Error("expected error for a symbol without expansions")
+		}
+	})
+}
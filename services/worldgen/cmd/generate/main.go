@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 	"textadventureservices/services/worldgen"
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/config"
 )
 
 func main() {
@@ -14,6 +17,8 @@ func main() {
 	prompt := flag.String("prompt", "", "The prompt for world generation (e.g., 'A mysterious underwater city')")
 	exits := flag.String("exits", "north,south,east,west", "Comma-separated list of exits (e.g., 'north,south')")
 	output := flag.String("output", "generated_world.json", "Output file for the generated world")
+	numRooms := flag.Int("rooms", 1, "Number of rooms to generate; more than one lays out a whole map")
+	topologyName := flag.String("topology", "sprawl", "Map shape when -rooms is more than 1: "+strings.Join(worldgen.TopologyNames(), ", "))
 	flag.Parse()
 
 	if *prompt == "" {
@@ -22,28 +27,64 @@ func main() {
 		os.Exit(1)
 	}
 
+	// A single room has no map to shape
+	if *numRooms <= 1 && flagSet("topology") {
+		fmt.Println("The -topology flag needs -rooms greater than 1")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	topology, err := worldgen.TopologyByName(*topologyName)
+	if err != nil {
+		fmt.Printf("Invalid topology: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Parse exits
 	exitList := strings.Split(*exits, ",")
 	for i, exit := range exitList {
 		exitList[i] = strings.TrimSpace(exit)
 	}
 
-	// Create a new world with a random seed
-	world, err := worldgen.NewWorld(42) // You can change this seed for different variations
+	seed := int64(42) // You can change this seed for different variations
+
+	// Select the provider with WORLDGEN_PROVIDER; OpenAI is the default
+	provider, err := config.OpenProviderFromEnv(config.ProviderOpenAI)
 	if err != nil {
-		fmt.Printf("Failed to create world: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("Warning: No AI provider available, generating offline from templates: %v\n", err)
+		provider = ai.NewTemplateProvider(seed)
 	}
+	generator := worldgen.NewGenerator(provider)
 
-	// Generate a room with the given prompt
-	room, err := worldgen.GenerateRoom(*prompt, exitList)
+	// Create a new world with a fixed seed
+	world, err := generator.NewWorld(seed)
 	if err != nil {
-		fmt.Printf("Failed to generate room: %v\n", err)
+		fmt.Printf("Failed to create world: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Add the room to the world
-	world.AddRoom(room)
+	if *numRooms > 1 {
+		// Lay out a whole map in the chosen shape
+		opts := worldgen.DefaultGenerateOptions()
+		opts.Topology = topology
+		if err := world.GenerateWorldWithOptions(context.Background(), *prompt, *numRooms, opts); err != nil {
+			fmt.Printf("Failed to generate world: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Generate a room with the given prompt
+		room, err := generator.GenerateRoom(context.Background(), *prompt, exitList)
+		if err != nil {
+			fmt.Printf("Failed to generate room: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Add the room to the world
+		if err := world.AddRoom(room); err != nil {
+			fmt.Printf("Failed to add room: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Save the world to a file
 	if err := world.Save(*output); err != nil {
@@ -61,3 +102,14 @@ func main() {
 	fmt.Printf("\nGenerated World Preview:\n%s\n", string(data))
 	fmt.Printf("\nWorld saved to: %s\n", *output)
 }
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
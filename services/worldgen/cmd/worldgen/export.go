@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"textadventureservices/services/worldgen"
+)
+
+// runExport writes a world file as Inform 7 source or a Twee story
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "", "Export format: inform7 or twee (default from the -o extension)")
+	output := flags.String("o", "", "File to write to (default stdout)")
+	title := flags.String("title", "", "Story title (default from the world file name)")
+	flags.Usage = func() {
+		fmt.Println("Usage: worldgen export [flags] <world.json>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	exportFormat := strings.ToLower(*format)
+	if exportFormat == "" {
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".ni", ".i7":
+			exportFormat = "inform7"
+		case ".twee", ".tw":
+			exportFormat = "twee"
+		default:
+			fmt.Println("Set -format, or an -o file ending in .ni or .twee")
+			os.Exit(1)
+		}
+	}
+	if *title == "" {
+		name := strings.TrimSuffix(filepath.Base(flags.Arg(0)), filepath.Ext(flags.Arg(0)))
+		*title = strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }), " ")
+	}
+
+	world, err := worldgen.LoadWorld(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Failed to load world: %v\n", err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	switch exportFormat {
+	case "inform7":
+		data, err = world.ExportInform7(*title)
+	case "twee":
+		data, err = world.ExportTwee(*title)
+	default:
+		err = fmt.Errorf("unknown export format %q", exportFormat)
+	}
+	if err != nil {
+		fmt.Printf("Failed to export world: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Printf("Failed to write export: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("World exported to %s\n", *output)
+}
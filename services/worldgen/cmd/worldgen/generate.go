@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"textadventureservices/services/worldgen"
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/config"
+	"textadventureservices/services/worldgen/logging"
+)
+
+// runGenerate generates a world and saves it
+func runGenerate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	prompt := flags.String("prompt", "", "The prompt for world generation (e.g., 'A mysterious underwater city')")
+	numRooms := flags.Int("rooms", 5, "Number of rooms to generate")
+	output := flags.String("output", "generated_world.json", "Output file for the generated world")
+	seed := flags.Int64("seed", 42, "Seed for the world layout and room IDs")
+	replayFile := flags.String("replay", "", "File used to record and replay AI responses")
+	replayMode := flags.String("replay-mode", string(ai.ReplayAuto), "Replay mode: record, replay or auto")
+	topologyName := flags.String("topology", "sprawl", "Map shape: "+strings.Join(worldgen.TopologyNames(), ", "))
+	regionsFile := flags.String("regions", "", "JSON file with the themed regions to split the world into")
+	structured := flags.Bool("structured", false, "Ask the provider for rooms as validated JSON documents")
+	lazy := flags.Bool("lazy", false, "Only generate the start room; the rest are generated as players explore")
+	concurrency := flags.Int("concurrency", worldgen.DefaultConcurrency, "Number of rooms generated at once")
+	newLogger := logFlags(flags, "warn")
+	flags.Parse(args)
+	logger := newLogger()
+
+	if *prompt == "" {
+		fmt.Println("Please provide a prompt using the -prompt flag")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	topology, err := worldgen.TopologyByName(*topologyName)
+	if err != nil {
+		fmt.Printf("Invalid topology: %v\n", err)
+		os.Exit(1)
+	}
+
+	var regions []worldgen.Region
+	if *regionsFile != "" {
+		regions, err = worldgen.LoadRegions(*regionsFile)
+		if err != nil {
+			fmt.Printf("Failed to load regions: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Select the provider with WORLDGEN_PROVIDER; OpenAI is the default
+	provider, err := config.OpenProviderFromEnv(config.ProviderOpenAI)
+	if err != nil {
+		fmt.Printf("Warning: No AI provider available, generating offline from templates: %v\n", err)
+		provider = ai.NewTemplateProvider(*seed)
+	}
+
+	// Record or replay AI responses so the world can be reproduced exactly
+	var store *ai.ResponseStore
+	if *replayFile != "" {
+		store, err = ai.LoadResponseStore(*replayFile)
+		if err != nil {
+			fmt.Printf("Failed to load replay file: %v\n", err)
+			os.Exit(1)
+		}
+		provider = ai.NewReplayProvider(provider, store, *seed, "", ai.ReplayMode(*replayMode))
+	}
+
+	// Generate a multi-room world; the same seed always yields the same layout
+	opts := worldgen.DefaultGenerateOptions()
+	opts.Topology = topology
+	opts.Regions = regions
+	opts.Structured = *structured
+	opts.Lazy = *lazy
+	opts.Concurrency = *concurrency
+	opts.Progress = func(p worldgen.Progress) {
+		ctx := logging.WithFields(context.Background(), logging.Fields{"seed": *seed, "room": p.RoomID, "attempt": p.Attempt, "done": p.Done, "total": p.Total})
+		if p.Err != nil {
+			logger.Warn(logging.WithFields(ctx, logging.Fields{"error": p.Err.Error()}), "Room attempt failed")
+			return
+		}
+		logger.Info(ctx, "Generated room")
+	}
+	generator := worldgen.NewGenerator(provider)
+	generator.SetLogger(logger)
+	world, err := generator.GenerateWorld(context.Background(), *seed, *prompt, *numRooms, opts)
+	if err != nil {
+		fmt.Printf("Failed to generate world: %v\n", err)
+		os.Exit(1)
+	}
+
+	if store != nil && ai.ReplayMode(*replayMode) != ai.ReplayReplay {
+		if err := store.Save(*replayFile); err != nil {
+			fmt.Printf("Failed to save replay file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Preview the generated world
+	fmt.Println("\nGenerated World Preview:")
+	data, err := json.MarshalIndent(world, "", "  ")
+	if err != nil {
+		fmt.Printf("Failed to marshal world preview: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+
+	// Save the world to a file
+	if err := world.Save(*output); err != nil {
+		fmt.Printf("Failed to save world: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nWorld saved to: %s\n", *output)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"textadventureservices/services/worldgen"
+)
+
+// runImport reads a hand-written Twee story into a world file
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	output := flags.String("o", "imported_world.json", "World file to write")
+	flags.Usage = func() {
+		fmt.Println("Usage: worldgen import [flags] <story.twee>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Failed to read story: %v\n", err)
+		os.Exit(1)
+	}
+	world, err := worldgen.ImportTwee(data)
+	if err != nil {
+		fmt.Printf("Failed to import %s: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+
+	// Hand-written stories are easy to get wrong, so report problems right away
+	for _, diagnostic := range world.Validate() {
+		fmt.Printf("%s: %s\n", flags.Arg(0), diagnostic)
+	}
+	if err := world.Save(*output); err != nil {
+		fmt.Printf("Failed to save world: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d rooms to %s\n", len(world.Rooms), *output)
+}
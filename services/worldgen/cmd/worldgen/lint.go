@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"textadventureservices/services/worldgen"
+)
+
+// lintResult is the JSON output of lint for one file
+type lintResult struct {
+	File        string               `json:"file"`
+	Error       string               `json:"error,omitempty"`
+	Diagnostics worldgen.Diagnostics `json:"diagnostics"`
+}
+
+// runLint validates world files and exits non-zero when any has errors
+func runLint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "Treat warnings as errors")
+	asJSON := flags.Bool("json", false, "Print the diagnostics as JSON")
+	flags.Usage = func() {
+		fmt.Println("Usage: worldgen lint [flags] <world.json>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	failed := false
+	var results []lintResult
+	for _, filename := range flags.Args() {
+		result := lintResult{File: filename}
+		world, err := worldgen.LoadWorld(filename)
+		if err != nil {
+			result.Error = err.Error()
+			failed = true
+		} else {
+			result.Diagnostics = world.Validate()
+			if result.Diagnostics.HasErrors() || (*strict && len(result.Diagnostics) > 0) {
+				failed = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to marshal diagnostics: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, result := range results {
+			if result.Error != "" {
+				fmt.Printf("%s: %s\n", result.File, result.Error)
+				continue
+			}
+			for _, diagnostic := range result.Diagnostics {
+				fmt.Printf("%s: %s\n", result.File, diagnostic)
+			}
+			fmt.Printf("%s: %d errors, %d warnings\n", result.File,
+				result.Diagnostics.Count(worldgen.SeverityError), result.Diagnostics.Count(worldgen.SeverityWarning))
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
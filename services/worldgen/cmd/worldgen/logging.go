@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"textadventureservices/services/worldgen/logging"
+)
+
+// logFlags adds the -log-level and -log-format flags to a command. The
+// returned function builds the logger they describe once the flags are
+// parsed. Logs go to stderr, so they never mix with a command's output.
+func logFlags(flags *flag.FlagSet, defaultLevel string) func() logging.Logger {
+	level := flags.String("log-level", defaultLevel, "Least severe log entries to show: debug, info, warn or error")
+	format := flags.String("log-format", string(logging.FormatText), "Log format: text or json")
+	return func() logging.Logger {
+		parsedLevel, err := logging.ParseLevel(*level)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		parsedFormat, err := logging.ParseFormat(*format)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return logging.NewWriterLogger(os.Stderr, parsedLevel, parsedFormat)
+	}
+}
@@ -1,53 +1,50 @@
 package main
 
 import (
-	"encoding/json"
-	"flag"
 	"fmt"
 	"os"
-	"textadventureservices/services/worldgen"
+	"strings"
 )
 
-func main() {
-	// Parse command line flags
-	prompt := flag.String("prompt", "", "The prompt for world generation (e.g., 'A mysterious underwater city')")
-	numRooms := flag.Int("rooms", 5, "Number of rooms to generate")
-	output := flag.String("output", "generated_world.json", "Output file for the generated world")
-	flag.Parse()
-
-	if *prompt == "" {
-		fmt.Println("Please provide a prompt using the -prompt flag")
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	// Create a new world with a random seed
-	world, err := worldgen.NewWorld(42) // You can change this seed for different variations
-	if err != nil {
-		fmt.Printf("Failed to create world: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Generate a multi-room world
-	if err := world.GenerateWorld(*prompt, *numRooms); err != nil {
-		fmt.Printf("Failed to generate world: %v\n", err)
-		os.Exit(1)
-	}
+// commands are the worldgen subcommands; each parses its own flags
+var commands = map[string]func(args []string){
+	"export":   runExport,
+	"generate": runGenerate,
+	"import":   runImport,
+	"lint":     runLint,
+	"migrate":  runMigrate,
+	"render":   runRender,
+	"serve":    runServe,
+}
 
-	// Preview the generated world
-	fmt.Println("\nGenerated World Preview:")
-	data, err := json.MarshalIndent(world, "", "  ")
-	if err != nil {
-		fmt.Printf("Failed to marshal world preview: %v\n", err)
-		os.Exit(1)
+func main() {
+	// Without a subcommand the flags are for generate, as they always were
+	args := os.Args[1:]
+	name := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	fmt.Println(string(data))
 
-	// Save the world to a file
-	if err := world.Save(*output); err != nil {
-		fmt.Printf("Failed to save world: %v\n", err)
+	run, ok := commands[name]
+	if !ok {
+		fmt.Printf("Unknown command %q\n\n", name)
+		usage()
 		os.Exit(1)
 	}
+	run(args)
+}
 
-	fmt.Printf("\nWorld saved to: %s\n", *output)
+func usage() {
+	fmt.Println("Usage: worldgen [command] [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  generate  Generate a world (default)")
+	fmt.Println("  export    Write a world as Inform 7 source or a Twee story")
+	fmt.Println("  import    Read a hand-written Twee story into a world file")
+	fmt.Println("  lint      Validate world files; exits non-zero on errors")
+	fmt.Println("  migrate   Upgrade saved world files to the current format in place")
+	fmt.Println("  render    Draw a map of a world as ASCII, Graphviz DOT or SVG")
+	fmt.Println("  serve     Serve generation jobs over HTTP with progress events")
+	fmt.Println()
+	fmt.Println("Run 'worldgen <command> -h' for the flags of a command.")
 }
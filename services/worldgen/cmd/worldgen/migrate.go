@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"textadventureservices/services/worldgen"
+)
+
+// runMigrate upgrades saved world files to the current format version
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("Usage: worldgen migrate <world.json>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, filename := range flags.Args() {
+		version, err := worldgen.MigrateWorldFile(filename)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: %v\n", filename, err)
+			failed = true
+		case version == worldgen.FormatVersion:
+			fmt.Printf("%s: already at version %d\n", filename, version)
+		default:
+			fmt.Printf("%s: migrated from version %d to %d\n", filename, version, worldgen.FormatVersion)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
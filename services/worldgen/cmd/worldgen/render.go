@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"textadventureservices/services/worldgen"
+)
+
+// runRender draws a map of a world file
+func runRender(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	format := flags.String("format", "", "Map format: ascii, dot or svg (default from the -o extension, else ascii)")
+	output := flags.String("o", "", "File to write the map to (default stdout)")
+	rooms := flags.String("rooms", "", "Comma-separated room IDs to draw instead of the whole world")
+	flags.Usage = func() {
+		fmt.Println("Usage: worldgen render [flags] <world.json>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	renderFormat := worldgen.RenderFormat(strings.ToLower(*format))
+	if renderFormat == "" {
+		renderFormat = worldgen.RenderASCII
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".dot", ".gv":
+			renderFormat = worldgen.RenderDOT
+		case ".svg":
+			renderFormat = worldgen.RenderSVG
+		}
+	}
+
+	world, err := worldgen.LoadWorld(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Failed to load world: %v\n", err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	if *rooms != "" {
+		data, err = world.RenderRooms(renderFormat, strings.Split(*rooms, ","))
+	} else {
+		data, err = world.Render(renderFormat)
+	}
+	if err != nil {
+		fmt.Printf("Failed to render world: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Printf("Failed to write map: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Map written to %s\n", *output)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	usageapi "textadventureservices/services/usage"
+	"textadventureservices/services/worldgen"
+	"textadventureservices/services/worldgen/config"
+)
+
+// runServe serves generation jobs over HTTP
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configFile := flags.String("config", "", "Service configuration file (default: built-in defaults without a logging service)")
+	addr := flags.String("addr", "", "Address to listen on (default: the configured port)")
+	newLogger := logFlags(flags, "info")
+	flags.Parse(args)
+
+	cfg := config.DefaultConfig()
+	cfg.LoggingEndpoint = ""
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Printf("Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *addr == "" {
+		*addr = fmt.Sprintf(":%d", cfg.Server.Port)
+	}
+
+	service, err := worldgen.NewService(cfg)
+	if err != nil {
+		fmt.Printf("Failed to create service: %v\n", err)
+		fmt.Printf("Select a provider with %s, e.g. %s=template to generate offline\n", config.ProviderEnvVar, config.ProviderEnvVar)
+		os.Exit(1)
+	}
+	// Without a logging service, logs go to stderr
+	if cfg.LoggingEndpoint == "" {
+		service.SetLogger(newLogger())
+	}
+
+	mux := http.NewServeMux()
+	worldgen.NewJobHandler(worldgen.NewJobManager(service)).RegisterRoutes(mux)
+	usageapi.NewHandler(service.Usage()).RegisterRoutes(mux)
+	server := &http.Server{Addr: *addr, Handler: mux}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		server.Close()
+	}()
+
+	fmt.Printf("Worldgen server listening on %s\n", *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Printf("Server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
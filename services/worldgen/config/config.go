@@ -4,16 +4,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	
+	"strings"
+
 	"textadventureservices/services/ai"
+	wgai "textadventureservices/services/worldgen/ai"
 )
 
 // Config represents the configuration for the world generation service
 type Config struct {
-	DefaultRooms    int       `json:"default_rooms"`
-	LoggingEndpoint string    `json:"logging_endpoint"`
-	AIProvider      ai.Config `json:"ai_provider"`
-	Server          ServerConfig      `json:"server"`
+	DefaultRooms    int          `json:"default_rooms"`
+	LoggingEndpoint string       `json:"logging_endpoint"`
+	AIProvider      ai.Config    `json:"ai_provider"`
+	Server          ServerConfig `json:"server"`
+	// Seed fixes the world seed; zero picks a time-based seed per world
+	Seed   int64        `json:"seed,omitempty"`
+	Replay ReplayConfig `json:"replay,omitempty"`
+	// Provider names the world generation provider, e.g. "ollama". It is
+	// overridden by WORLDGEN_PROVIDER. When neither is set, worlds are
+	// described through the AI service configured in AIProvider.
+	Provider string `json:"provider,omitempty"`
+	// Providers holds one configuration section per provider name.
+	// Providers without a section are configured from the environment.
+	Providers map[string]wgai.ProviderConfig `json:"providers,omitempty"`
+}
+
+// ReplayConfig configures recording and replaying of AI responses.
+// With a fixed seed and a replay file the generated world is byte-identical across runs.
+type ReplayConfig struct {
+	// Mode is one of "record", "replay" or "auto"; empty disables the store
+	Mode string `json:"mode,omitempty"`
+	Path string `json:"path,omitempty"`
 }
 
 // ServerConfig holds the HTTP server configuration
@@ -27,16 +47,16 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
-	
+
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
-	
+
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
-	
+
 	return &config, nil
 }
 
@@ -51,20 +71,63 @@ func (c *Config) Validate() error {
 	if c.Server.Port <= 0 {
 		return fmt.Errorf("invalid server port")
 	}
+	switch c.Replay.Mode {
+	case "", "record", "replay", "auto":
+	default:
+		return fmt.Errorf("invalid replay mode: %s", c.Replay.Mode)
+	}
+	if c.Replay.Mode != "" && c.Replay.Path == "" {
+		return fmt.Errorf("replay path is required when replay mode is set")
+	}
+	if c.Provider != "" && !isRegistered(wgai.ProviderType(c.Provider)) {
+		return fmt.Errorf("unknown provider: %s", c.Provider)
+	}
 	return nil
 }
 
+func isRegistered(name wgai.ProviderType) bool {
+	for _, registered := range wgai.Registered() {
+		if registered == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderConfig returns the configuration of the selected world generation
+// provider. ok is false when no provider is selected.
+func (c *Config) ProviderConfig() (cfg wgai.ProviderConfig, ok bool, err error) {
+	name := c.Provider
+	if env := strings.TrimSpace(os.Getenv(ProviderEnvVar)); env != "" {
+		name = strings.ToLower(env)
+	}
+	if name == "" {
+		return wgai.ProviderConfig{}, false, nil
+	}
+
+	if section, exists := c.Providers[name]; exists {
+		if section.Type == "" {
+			section.Type = wgai.ProviderType(name)
+		}
+		return section, true, nil
+	}
+
+	cfg, err = LoadProviderFromEnv(wgai.ProviderType(name))
+	if err != nil {
+		return wgai.ProviderConfig{}, false, err
+	}
+	cfg, err = withFallbacksFromEnv(cfg)
+	if err != nil {
+		return wgai.ProviderConfig{}, false, err
+	}
+	return cfg, true, nil
+}
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		DefaultRooms: 5,
-		AIProvider: ai.Config{
-			Model:          "gpt-3.5-turbo",
-			MaxTokens:      150,
-			Temperature:    0.7,
-			RateLimit:      60,
-			TimeoutSeconds: 30,
-		},
+		AIProvider:   *ai.DefaultConfig(),
 		Server: ServerConfig{
 			Port: 8080,
 		},
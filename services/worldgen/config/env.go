@@ -10,32 +10,107 @@ import (
 	"textadventureservices/services/worldgen/ai"
 )
 
-// LoadProviderFromEnv loads AI provider configuration from environment files
+// Provider names accepted in WORLDGEN_PROVIDER and in the provider config section
+const (
+	ProviderOpenAI   = ai.ProviderOpenAI
+	ProviderOllama   = ai.ProviderOllama
+	ProviderMock     = ai.ProviderMock
+	ProviderReplay   = ai.ProviderReplay
+	ProviderTemplate = ai.ProviderTemplate
+)
+
+// ProviderEnvVar selects the provider when set
+const ProviderEnvVar = "WORLDGEN_PROVIDER"
+
+// FallbackEnvVar lists the providers asked in order when the selected one
+// fails, e.g. "ollama,template"
+const FallbackEnvVar = "WORLDGEN_FALLBACK"
+
+// ProviderFromEnv loads the configuration of the provider named by
+// WORLDGEN_PROVIDER, falling back to defaultType when the variable is unset
+func ProviderFromEnv(defaultType ai.ProviderType) (ai.ProviderConfig, error) {
+	providerType := defaultType
+	if name := strings.TrimSpace(os.Getenv(ProviderEnvVar)); name != "" {
+		providerType = ai.ProviderType(strings.ToLower(name))
+	}
+	cfg, err := LoadProviderFromEnv(providerType)
+	if err != nil {
+		return ai.ProviderConfig{}, err
+	}
+	return withFallbacksFromEnv(cfg)
+}
+
+// withFallbacksFromEnv chains the providers listed in WORLDGEN_FALLBACK
+// behind cfg, each configured from the environment
+func withFallbacksFromEnv(cfg ai.ProviderConfig) (ai.ProviderConfig, error) {
+	last := &cfg
+	for _, name := range strings.Split(os.Getenv(FallbackEnvVar), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		fallback, err := LoadProviderFromEnv(ai.ProviderType(name))
+		if err != nil {
+			return ai.ProviderConfig{}, fmt.Errorf("failed to load fallback provider %s: %w", name, err)
+		}
+		last.Fallback = &fallback
+		last = last.Fallback
+	}
+	return cfg, nil
+}
+
+// OpenProviderFromEnv creates and initializes the provider selected by
+// WORLDGEN_PROVIDER, or defaultType when the variable is unset
+func OpenProviderFromEnv(defaultType ai.ProviderType) (ai.Provider, error) {
+	cfg, err := ProviderFromEnv(defaultType)
+	if err != nil {
+		return nil, err
+	}
+	return ai.Open(cfg)
+}
+
+// LoadProviderFromEnv loads AI provider configuration from environment variables.
+// OpenAI settings may also come from a .env.openai file in the working
+// directory or one of its parents.
 func LoadProviderFromEnv(providerType ai.ProviderType) (ai.ProviderConfig, error) {
-	// Only support OpenAI configuration
-	if providerType != ai.ProviderOpenAI {
+	switch providerType {
+	case ProviderOpenAI:
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			if err := loadEnvFileFromParents(".env.openai"); err != nil {
+				return ai.ProviderConfig{}, err
+			}
+		}
+		return loadOpenAIConfig(), nil
+	case ProviderOllama:
+		return loadOllamaConfig(), nil
+	case ProviderMock:
+		return ai.ProviderConfig{Type: ProviderMock}, nil
+	case ProviderReplay:
+		return loadReplayConfig()
+	case ProviderTemplate:
+		return loadTemplateConfig(), nil
+	default:
 		return ai.ProviderConfig{}, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
+}
 
-	envFile := ".env.openai"
-
-	// Find the environment file by walking up directories
+// loadEnvFileFromParents finds an environment file by walking up from the
+// working directory and loads it
+func loadEnvFileFromParents(envFile string) error {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		return ai.ProviderConfig{}, fmt.Errorf("failed to get current directory: %w", err)
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	envPath := findEnvFile(currentDir, envFile)
 	if envPath == "" {
-		return ai.ProviderConfig{}, fmt.Errorf("environment file %s not found", envFile)
+		return fmt.Errorf("environment file %s not found", envFile)
 	}
 
-	// Load environment variables from file
 	if err := loadEnvFile(envPath); err != nil {
-		return ai.ProviderConfig{}, fmt.Errorf("failed to load environment file: %w", err)
+		return fmt.Errorf("failed to load environment file: %w", err)
 	}
-
-	return loadOpenAIConfig(), nil
+	return nil
 }
 
 // findEnvFile walks up the directory tree looking for the environment file
@@ -46,7 +121,7 @@ func findEnvFile(startDir, filename string) string {
 		if _, err := os.Stat(path); err == nil {
 			return path
 		}
-		
+
 		parent := filepath.Dir(dir)
 		if parent == dir {
 			return "" // Reached root directory
@@ -91,31 +166,113 @@ func loadEnvFile(path string) error {
 	return nil
 }
 
+// floatParams reads numeric parameters from environment variables
+func floatParams(vars map[string]string) map[string]interface{} {
+	params := make(map[string]interface{})
+	for name, key := range vars {
+		if value, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil {
+			params[key] = value
+		}
+	}
+	return params
+}
+
 func loadOpenAIConfig() ai.ProviderConfig {
 	// Load optional parameters
-	params := make(map[string]interface{})
-	
-	if temp, err := strconv.ParseFloat(os.Getenv("OPENAI_TEMPERATURE"), 64); err == nil {
-		params["temperature"] = temp
+	params := floatParams(map[string]string{
+		"OPENAI_TEMPERATURE":       "temperature",
+		"OPENAI_MAX_TOKENS":        "max_tokens",
+		"OPENAI_TOP_P":             "top_p",
+		"OPENAI_FREQUENCY_PENALTY": "frequency_penalty",
+		"OPENAI_PRESENCE_PENALTY":  "presence_penalty",
+		"OPENAI_MAX_RETRIES":       "max_retries",
+		"OPENAI_TIMEOUT_SECONDS":   "timeout_seconds",
+	})
+	if model := os.Getenv("OPENAI_FALLBACK_MODEL"); model != "" {
+		params["fallback_model"] = model
 	}
-	if tokens, err := strconv.ParseFloat(os.Getenv("OPENAI_MAX_TOKENS"), 64); err == nil {
-		params["max_tokens"] = tokens
+
+	endpoint := os.Getenv("OPENAI_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1"
 	}
-	if topP, err := strconv.ParseFloat(os.Getenv("OPENAI_TOP_P"), 64); err == nil {
-		params["top_p"] = topP
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4o"
+	}
+
+	return ai.ProviderConfig{
+		Type:       ProviderOpenAI,
+		Endpoint:   endpoint,
+		Model:      model,
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		Parameters: params,
 	}
-	if freqPen, err := strconv.ParseFloat(os.Getenv("OPENAI_FREQUENCY_PENALTY"), 64); err == nil {
-		params["frequency_penalty"] = freqPen
+}
+
+func loadOllamaConfig() ai.ProviderConfig {
+	params := floatParams(map[string]string{
+		"OLLAMA_TEMPERATURE": "temperature",
+		"OLLAMA_NUM_PREDICT": "num_predict",
+		"OLLAMA_TOP_K":       "top_k",
+		"OLLAMA_TOP_P":       "top_p",
+	})
+	if stream, err := strconv.ParseBool(os.Getenv("OLLAMA_STREAM")); err == nil {
+		params["stream"] = stream
 	}
-	if presPen, err := strconv.ParseFloat(os.Getenv("OPENAI_PRESENCE_PENALTY"), 64); err == nil {
-		params["presence_penalty"] = presPen
+
+	model := os.Getenv("OLLAMA_MODEL")
+	if model == "" {
+		model = "llama3"
 	}
-	
+
 	return ai.ProviderConfig{
-		Type:       ai.ProviderOpenAI,
-		Endpoint:   os.Getenv("OPENAI_ENDPOINT"),
-		Model:      os.Getenv("OPENAI_MODEL"),
-		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		Type:       ProviderOllama,
+		Endpoint:   os.Getenv("OLLAMA_ENDPOINT"),
+		Model:      model,
+		Parameters: params,
+	}
+}
+
+// loadReplayConfig configures a replay provider from WORLDGEN_REPLAY_PATH and
+// WORLDGEN_REPLAY_MODE. WORLDGEN_REPLAY_INNER names the provider whose
+// responses are recorded; without it only recorded responses can be served.
+func loadReplayConfig() (ai.ProviderConfig, error) {
+	path := os.Getenv("WORLDGEN_REPLAY_PATH")
+	if path == "" {
+		return ai.ProviderConfig{}, fmt.Errorf("WORLDGEN_REPLAY_PATH is required for the replay provider")
+	}
+
+	cfg := ai.ProviderConfig{
+		Type: ProviderReplay,
+		Parameters: map[string]interface{}{
+			"path": path,
+			"mode": os.Getenv("WORLDGEN_REPLAY_MODE"),
+		},
+	}
+	if name := os.Getenv("WORLDGEN_REPLAY_INNER"); name != "" {
+		if ai.ProviderType(name) == ProviderReplay {
+			return ai.ProviderConfig{}, fmt.Errorf("the replay provider cannot wrap itself")
+		}
+		inner, err := LoadProviderFromEnv(ai.ProviderType(name))
+		if err != nil {
+			return ai.ProviderConfig{}, fmt.Errorf("failed to load wrapped provider: %w", err)
+		}
+		cfg.Inner = &inner
+	}
+	return cfg, nil
+}
+
+// loadTemplateConfig reads WORLDGEN_TEMPLATE_SEED and WORLDGEN_TEMPLATE_GRAMMAR,
+// the path of a JSON grammar whose rules replace the built-in ones
+func loadTemplateConfig() ai.ProviderConfig {
+	params := floatParams(map[string]string{"WORLDGEN_TEMPLATE_SEED": "seed"})
+	if path := os.Getenv("WORLDGEN_TEMPLATE_GRAMMAR"); path != "" {
+		params["grammar"] = path
+	}
+	return ai.ProviderConfig{
+		Type:       ProviderTemplate,
+		Model:      ai.TemplateModel,
 		Parameters: params,
 	}
 }
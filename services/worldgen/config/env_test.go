@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+func TestProviderFromEnv(t *testing.T) {
+	t.Setenv(ProviderEnvVar, "Ollama")
+	t.Setenv("OLLAMA_MODEL", "mistral")
+	t.Setenv("OLLAMA_TEMPERATURE", "0.3")
+
+	cfg, err := ProviderFromEnv(ProviderOpenAI)
+	if err != nil {
+		t.Fatalf("ProviderFromEnv failed: %v", err)
+	}
+	if cfg.Type != ProviderOllama || cfg.Model != "mistral" || cfg.Parameters["temperature"] != 0.3 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv(ProviderEnvVar, "unknown")
+	if _, err := ProviderFromEnv(ProviderOpenAI); err == nil {
+		t.Error("expected error for an unknown provider")
+	}
+
+	t.Setenv(ProviderEnvVar, "replay")
+	t.Setenv("WORLDGEN_REPLAY_PATH", "")
+	if _, err := ProviderFromEnv(ProviderOpenAI); err == nil {
+		t.Error("expected error for replay without a path")
+	}
+}
+
+func TestConfigProviderSection(t *testing.T) {
+	t.Setenv(ProviderEnvVar, "")
+	cfg := &Config{
+		Provider: "mock",
+		Providers: map[string]ai.ProviderConfig{
+			"mock": {Type: ProviderMock, Model: "configured"},
+		},
+	}
+
+	providerCfg, selected, err := cfg.ProviderConfig()
+	if err != nil || !selected {
+		t.Fatalf("expected a selected provider, got %v, %v", selected, err)
+	}
+	if providerCfg.Model != "configured" {
+		t.Errorf("expected the config section to be used, got %+v", providerCfg)
+	}
+
+	// The environment variable overrides the configured provider
+	t.Setenv(ProviderEnvVar, "ollama")
+	providerCfg, _, err = cfg.ProviderConfig()
+	if err != nil || providerCfg.Type != ProviderOllama {
+		t.Errorf("expected WORLDGEN_PROVIDER to win, got %+v, %v", providerCfg, err)
+	}
+
+	// Without either, the legacy AI service is used
+	t.Setenv(ProviderEnvVar, "")
+	if _, selected, _ := (&Config{}).ProviderConfig(); selected {
+		t.Error("expected no provider to be selected")
+	}
+}
+
+func TestFallbacksFromEnv(t *testing.T) {
+	t.Setenv(ProviderEnvVar, "openai")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_FALLBACK_MODEL", "gpt-4o-mini")
+	t.Setenv(FallbackEnvVar, "ollama, template")
+
+	cfg, err := ProviderFromEnv(ProviderMock)
+	if err != nil {
+		t.Fatalf("ProviderFromEnv failed: %v", err)
+	}
+	if cfg.Parameters["fallback_model"] != "gpt-4o-mini" {
+		t.Errorf("expected the fallback model as a parameter, got %v", cfg.Parameters)
+	}
+	if cfg.Fallback == nil || cfg.Fallback.Type != ProviderOllama ||
+		cfg.Fallback.Fallback == nil || cfg.Fallback.Fallback.Type != ProviderTemplate {
+		t.Fatalf("expected openai, then ollama, then template, got %+v", cfg)
+	}
+
+	t.Setenv(FallbackEnvVar, "unknown")
+	if _, err := ProviderFromEnv(ProviderMock); err == nil {
+		t.Error("expected error for an unknown fallback provider")
+	}
+}
@@ -1,38 +1,260 @@
 package worldgen
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
 // Direction represents a possible direction of movement
 type Direction string
 
 // Direction constants
 const (
-	North Direction = "north"
-	South Direction = "south"
-	East  Direction = "east"
-	West  Direction = "west"
+	North     Direction = "north"
+	South     Direction = "south"
+	East      Direction = "east"
+	West      Direction = "west"
+	Northeast Direction = "northeast"
+	Northwest Direction = "northwest"
+	Southeast Direction = "southeast"
+	Southwest Direction = "southwest"
+	Up        Direction = "up"
+	Down      Direction = "down"
+	In        Direction = "in"
+	Out       Direction = "out"
+)
+
+// Direction sets that world generation can pick from
+var (
+	// CardinalDirections are the four compass points
+	CardinalDirections = []Direction{North, South, East, West}
+	// CompassDirections add the diagonals to the cardinal directions
+	CompassDirections = []Direction{North, South, East, West, Northeast, Northwest, Southeast, Southwest}
+	// SpatialDirections add vertical movement to the compass directions
+	SpatialDirections = []Direction{North, South, East, West, Northeast, Northwest, Southeast, Southwest, Up, Down}
 )
 
-// GetOppositeDirection returns the opposite direction
+// Offset is a displacement on the world grid.
+// North is +Y, east is +X and up is +Z.
+type Offset struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+// IsZero reports whether the offset does not move on the grid
+func (o Offset) IsZero() bool {
+	return o == Offset{}
+}
+
+// Add returns the sum of two offsets
+func (o Offset) Add(other Offset) Offset {
+	return Offset{X: o.X + other.X, Y: o.Y + other.Y, Z: o.Z + other.Z}
+}
+
+// Negate returns the opposite displacement
+func (o Offset) Negate() Offset {
+	return Offset{X: -o.X, Y: -o.Y, Z: -o.Z}
+}
+
+// DirectionInfo describes a registered direction
+type DirectionInfo struct {
+	Direction Direction
+	// Opposite is the direction leading back; empty only for one-way directions
+	Opposite Direction
+	// OneWay marks directions that never get a return exit
+	OneWay bool
+	// Aliases are alternative names accepted by the parser, such as "n" or "ne"
+	Aliases []string
+	// Offset is the grid displacement; zero for non-spatial exits like "in"
+	Offset Offset
+}
+
+// directionRegistry holds every known direction and its parser aliases
+type directionRegistry struct {
+	mu      sync.RWMutex
+	infos   map[Direction]DirectionInfo
+	aliases map[string]Direction
+	// implicit marks opposites registered automatically, which may be redefined later
+	implicit map[Direction]bool
+}
+
+var directions = newDirectionRegistry()
+
+func newDirectionRegistry() *directionRegistry {
+	r := &directionRegistry{
+		infos:    make(map[Direction]DirectionInfo),
+		aliases:  make(map[string]Direction),
+		implicit: make(map[Direction]bool),
+	}
+	builtin := []DirectionInfo{
+		{Direction: North, Opposite: South, Aliases: []string{"n"}, Offset: Offset{Y: 1}},
+		{Direction: South, Opposite: North, Aliases: []string{"s"}, Offset: Offset{Y: -1}},
+		{Direction: East, Opposite: West, Aliases: []string{"e"}, Offset: Offset{X: 1}},
+		{Direction: West, Opposite: East, Aliases: []string{"w"}, Offset: Offset{X: -1}},
+		{Direction: Northeast, Opposite: Southwest, Aliases: []string{"ne", "north-east", "north east"}, Offset: Offset{X: 1, Y: 1}},
+		{Direction: Northwest, Opposite: Southeast, Aliases: []string{"nw", "north-west", "north west"}, Offset: Offset{X: -1, Y: 1}},
+		{Direction: Southeast, Opposite: Northwest, Aliases: []string{"se", "south-east", "south east"}, Offset: Offset{X: 1, Y: -1}},
+		{Direction: Southwest, Opposite: Northeast, Aliases: []string{"sw", "south-west", "south west"}, Offset: Offset{X: -1, Y: -1}},
+		{Direction: Up, Opposite: Down, Aliases: []string{"u", "upstairs", "climb"}, Offset: Offset{Z: 1}},
+		{Direction: Down, Opposite: Up, Aliases: []string{"d", "downstairs", "descend"}, Offset: Offset{Z: -1}},
+		{Direction: In, Opposite: Out, Aliases: []string{"inside", "enter"}},
+		{Direction: Out, Opposite: In, Aliases: []string{"outside", "exit", "leave"}},
+	}
+	for _, info := range builtin {
+		if err := r.register(info); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// normalizeDirectionName lowercases a name and collapses its whitespace
+func normalizeDirectionName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func (r *directionRegistry) register(info DirectionInfo) error {
+	info.Direction = Direction(normalizeDirectionName(string(info.Direction)))
+	info.Opposite = Direction(normalizeDirectionName(string(info.Opposite)))
+	if info.Direction == "" {
+		return fmt.Errorf("direction name cannot be empty")
+	}
+	if info.OneWay {
+		info.Opposite = ""
+	} else if info.Opposite == "" {
+		return fmt.Errorf("direction %q needs an opposite or must be declared one-way", info.Direction)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.infos[info.Direction]; exists {
+		if !r.implicit[info.Direction] {
+			return fmt.Errorf("direction %q is already registered", info.Direction)
+		}
+		if existing.Opposite != info.Opposite {
+			return fmt.Errorf("direction %q was registered as the way back from %q", info.Direction, existing.Opposite)
+		}
+	}
+	if owner, exists := r.aliases[string(info.Direction)]; exists && owner != info.Direction {
+		return fmt.Errorf("direction %q is already an alias of %q", info.Direction, owner)
+	}
+	if info.Opposite != "" {
+		if owner, isAlias := r.aliases[string(info.Opposite)]; isAlias && owner != info.Opposite {
+			return fmt.Errorf("opposite %q of direction %q is an alias of %q", info.Opposite, info.Direction, owner)
+		}
+		// Opposites come in pairs, so a registered opposite must lead back
+		if existing, exists := r.infos[info.Opposite]; exists && existing.Opposite != info.Direction {
+			return fmt.Errorf("opposite %q of direction %q already leads back to %q", info.Opposite, info.Direction, existing.Opposite)
+		}
+	}
+
+	aliases := make([]string, 0, len(info.Aliases))
+	for _, alias := range info.Aliases {
+		alias = normalizeDirectionName(alias)
+		if alias == "" || alias == string(info.Direction) {
+			continue
+		}
+		if owner, exists := r.aliases[alias]; exists {
+			return fmt.Errorf("alias %q is already used by direction %q", alias, owner)
+		}
+		if _, exists := r.infos[Direction(alias)]; exists {
+			return fmt.Errorf("alias %q is already a direction", alias)
+		}
+		aliases = append(aliases, alias)
+	}
+	info.Aliases = aliases
+
+	delete(r.implicit, info.Direction)
+	r.infos[info.Direction] = info
+	r.aliases[string(info.Direction)] = info.Direction
+	for _, alias := range aliases {
+		r.aliases[alias] = info.Direction
+	}
+
+	// Register the way back automatically so custom exits come in pairs
+	if info.Opposite != "" {
+		if _, exists := r.infos[info.Opposite]; !exists {
+			back := DirectionInfo{Direction: info.Opposite, Opposite: info.Direction, Offset: info.Offset.Negate()}
+			r.infos[back.Direction] = back
+			r.aliases[string(back.Direction)] = back.Direction
+			r.implicit[back.Direction] = true
+		}
+	}
+	return nil
+}
+
+func (r *directionRegistry) info(dir Direction) (DirectionInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info, ok := r.infos[dir]
+	return info, ok
+}
+
+// RegisterDirection adds a custom direction, such as "through the portal".
+// Directions must either name an opposite or be declared one-way. If the
+// opposite is not registered yet it is registered as well, leading back.
+func RegisterDirection(info DirectionInfo) error {
+	return directions.register(info)
+}
+
+// LookupDirection resolves player input such as "n", "NE" or "through the portal"
+func LookupDirection(input string) (Direction, bool) {
+	directions.mu.RLock()
+	defer directions.mu.RUnlock()
+	dir, ok := directions.aliases[normalizeDirectionName(input)]
+	return dir, ok
+}
+
+// GetDirectionInfo returns the registered information for a direction
+func GetDirectionInfo(dir Direction) (DirectionInfo, bool) {
+	return directions.info(dir)
+}
+
+// RegisteredDirections returns all registered directions in alphabetical order
+func RegisteredDirections() []Direction {
+	directions.mu.RLock()
+	defer directions.mu.RUnlock()
+	result := make([]Direction, 0, len(directions.infos))
+	for dir := range directions.infos {
+		result = append(result, dir)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// GetOppositeDirection returns the opposite direction, or "" for one-way and unknown directions
 func (d Direction) GetOppositeDirection() Direction {
-	switch d {
-	case North:
-		return South
-	case South:
-		return North
-	case East:
-		return West
-	case West:
-		return East
-	default:
+	info, ok := directions.info(d)
+	if !ok {
 		return ""
 	}
+	return info.Opposite
 }
 
-// IsValidDirection checks if a direction is valid
+// IsOneWay reports whether the direction was declared one-way
+func (d Direction) IsOneWay() bool {
+	info, ok := directions.info(d)
+	return ok && info.OneWay
+}
+
+// Offset returns the grid displacement of the direction
+func (d Direction) Offset() Offset {
+	info, _ := directions.info(d)
+	return info.Offset
+}
+
+// Aliases returns the parser aliases of the direction
+func (d Direction) Aliases() []string {
+	info, _ := directions.info(d)
+	return append([]string(nil), info.Aliases...)
+}
+
+// IsValidDirection checks if a direction is registered
 func IsValidDirection(dir Direction) bool {
-	switch dir {
-	case North, South, East, West:
-		return true
-	default:
-		return false
-	}
+	_, ok := directions.info(dir)
+	return ok
 }
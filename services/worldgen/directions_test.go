@@ -0,0 +1,180 @@
+package worldgen
+
+import (
+	"context"
+	"testing"
+)
+
+// registerOnce registers a custom direction unless an earlier test run already did
+func registerOnce(t *testing.T, info DirectionInfo) {
+	t.Helper()
+	if IsValidDirection(info.Direction) {
+		return
+	}
+	if err := RegisterDirection(info); err != nil {
+		t.Fatalf("Failed to register direction %q: %v", info.Direction, err)
+	}
+}
+
+func TestBuiltinOppositeDirections(t *testing.T) {
+	for _, dir := range append(SpatialDirections, In, Out) {
+		opposite := dir.GetOppositeDirection()
+		if opposite == "" {
+			t.Errorf("Direction %s has no opposite", dir)
+			continue
+		}
+		if opposite.GetOppositeDirection() != dir {
+			t.Errorf("Opposite of %s is %s, whose opposite is %s", dir, opposite, opposite.GetOppositeDirection())
+		}
+		if dir.Offset() != opposite.Offset().Negate() {
+			t.Errorf("Offsets of %s and %s do not cancel out", dir, opposite)
+		}
+	}
+
+	if got := Direction("sideways").GetOppositeDirection(); got != "" {
+		t.Errorf("Expected no opposite for unknown direction, got %s", got)
+	}
+}
+
+func TestLookupDirection(t *testing.T) {
+	tests := map[string]Direction{
+		"n":          North,
+		"NE":         Northeast,
+		"north east": Northeast,
+		"u":          Up,
+		"Downstairs": Down,
+		"west":       West,
+		"  enter  ":  In,
+	}
+	for input, want := range tests {
+		got, ok := LookupDirection(input)
+		if !ok || got != want {
+			t.Errorf("LookupDirection(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+
+	if _, ok := LookupDirection("sideways"); ok {
+		t.Error("Expected unknown input to fail lookup")
+	}
+}
+
+func TestRegisterCustomDirection(t *testing.T) {
+	registerOnce(t, DirectionInfo{
+		Direction: "through the portal",
+		Opposite:  "back through the portal",
+		Aliases:   []string{"portal"},
+	})
+
+	dir, ok := LookupDirection("Portal")
+	if !ok || dir != "through the portal" {
+		t.Fatalf("Expected alias to resolve to custom direction, got %q", dir)
+	}
+	if dir.GetOppositeDirection() != "back through the portal" {
+		t.Errorf("Expected registered opposite, got %q", dir.GetOppositeDirection())
+	}
+	if Direction("back through the portal").GetOppositeDirection() != dir {
+		t.Error("Expected opposite to lead back to the portal")
+	}
+
+	if err := RegisterDirection(DirectionInfo{Direction: "through the portal", Opposite: "out"}); err == nil {
+		t.Error("Expected error when registering a direction twice")
+	}
+	if err := RegisterDirection(DirectionInfo{Direction: "jump"}); err == nil {
+		t.Error("Expected error when registering a direction without opposite")
+	}
+	if err := RegisterDirection(DirectionInfo{Direction: "dive", Opposite: "surface", Aliases: []string{"n"}}); err == nil {
+		t.Error("Expected error when reusing an alias")
+	}
+}
+
+func TestRegisterDirectionRejectsTakenOpposite(t *testing.T) {
+	// out already leads back in, so it cannot lead back into the mirror too
+	if err := RegisterDirection(DirectionInfo{Direction: "into the mirror", Opposite: Out}); err == nil {
+		t.Error("Expected error when the opposite leads back to another direction")
+	}
+	if IsValidDirection("into the mirror") {
+		t.Error("Expected rejected direction not to be registered")
+	}
+	if Out.GetOppositeDirection() != In {
+		t.Errorf("Expected out to still lead back in, got %q", Out.GetOppositeDirection())
+	}
+
+	registerOnce(t, DirectionInfo{Direction: "down the well", Opposite: "up the well"})
+	if err := RegisterDirection(DirectionInfo{Direction: "up the well", Opposite: "down the rope"}); err == nil {
+		t.Error("Expected error when redefining a way back with another opposite")
+	}
+}
+
+func TestConnectRoomsOneWayAndUnknown(t *testing.T) {
+	registerOnce(t, DirectionInfo{Direction: "down the chute", OneWay: true, Aliases: []string{"chute"}})
+
+	world, err := NewWorld(42)
+	if err != nil {
+		t.Fatalf("Failed to create new world: %v", err)
+	}
+	top, _ := world.NewRoom("Top of the chute", nil)
+	bottom, _ := world.NewRoom("Bottom of the chute", nil)
+	if err := world.AddRoom(top); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if err := world.AddRoom(bottom); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+
+	if err := world.ConnectRooms(top.ID, bottom.ID, "down the chute"); err != nil {
+		t.Fatalf("Failed to connect rooms: %v", err)
+	}
+	if top.Exits["down the chute"] != bottom.ID {
+		t.Error("Expected exit down the chute")
+	}
+	if len(bottom.Exits) != 0 {
+		t.Errorf("Expected no return exit for one-way direction, got %v", bottom.Exits)
+	}
+
+	if err := world.ConnectRooms(top.ID, bottom.ID, "sideways"); err == nil {
+		t.Error("Expected error when connecting through an unknown direction")
+	}
+	if _, ok := top.Exits[""]; ok {
+		t.Error("Unknown direction must not create an exit under an empty key")
+	}
+}
+
+func TestGenerateWorldWithSpatialDirections(t *testing.T) {
+	world, err := NewWorld(2024)
+	if err != nil {
+		t.Fatalf("Failed to create new world: %v", err)
+	}
+
+	opts := DefaultGenerateOptions()
+	opts.Directions = SpatialDirections
+	if err := world.GenerateWorldWithOptions(context.Background(), "A crooked tower", 12, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	if len(world.Rooms) != 12 {
+		t.Errorf("Expected 12 rooms, got %d", len(world.Rooms))
+	}
+	for _, room := range world.Rooms {
+		if room.Coord == nil {
+			t.Errorf("Room %s has no coordinate", room.ID)
+		}
+		for dir, targetID := range room.Exits {
+			if !IsValidDirection(Direction(dir)) {
+				t.Errorf("Room %s has unknown exit %q", room.ID, dir)
+			}
+			target := world.Rooms[targetID]
+			if target == nil {
+				t.Errorf("Room %s exit %s leads nowhere", room.ID, dir)
+				continue
+			}
+			if target.Exits[getOppositeDirection(dir)] != room.ID {
+				t.Errorf("Room %s -%s-> %s is not bidirectional", room.ID, dir, targetID)
+			}
+		}
+	}
+
+	opts.Directions = []Direction{North, "sideways"}
+	if err := world.GenerateWorldWithOptions(context.Background(), "A crooked tower", 3, opts); err == nil {
+		t.Error("Expected error for unknown generation direction")
+	}
+}
@@ -0,0 +1,198 @@
+package worldgen
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FormatVersion is the world file format written by Save. Bump it together
+// with a new entry in worldMigrations whenever the saved shape of World,
+// Room or Object changes, and update world.schema.json.
+const FormatVersion = 2
+
+// formatVersionKey is the header field holding a world file's format version
+const formatVersionKey = "formatVersion"
+
+// WorldSchema is the JSON Schema of the current world file format
+//
+//go:embed world.schema.json
+var WorldSchema []byte
+
+// worldMigration upgrades a decoded world document by one format version
+type worldMigration struct {
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+// worldMigrations[v] upgrades a document from version v to v+1. Migrations
+// work on the raw document rather than the Go types, so they keep working
+// when the types change later.
+var worldMigrations = []worldMigration{
+	{description: "merge the scenes map into rooms and fill in empty collections", apply: migrateUnversioned},
+	{description: "mark exits not connected to a room as unexplored", apply: migrateUnexploredExits},
+}
+
+// MigrateWorldJSON upgrades a world document to FormatVersion. It returns the
+// upgraded document and the version the input was written in. Files without a
+// formatVersion header are version 0.
+func MigrateWorldJSON(data []byte) ([]byte, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep 64-bit seeds exact
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse world document: %w", err)
+	}
+	if doc == nil {
+		return nil, 0, fmt.Errorf("world document must be a JSON object")
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > FormatVersion {
+		return nil, version, fmt.Errorf("world format version %d is newer than the supported version %d", version, FormatVersion)
+	}
+	if version == FormatVersion {
+		return data, version, nil
+	}
+
+	for v := version; v < FormatVersion; v++ {
+		if err := worldMigrations[v].apply(doc); err != nil {
+			return nil, version, fmt.Errorf("failed to migrate world from version %d (%s): %w", v, worldMigrations[v].description, err)
+		}
+	}
+	doc[formatVersionKey] = FormatVersion
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to marshal migrated world: %w", err)
+	}
+	return migrated, version, nil
+}
+
+// documentVersion reads the formatVersion header of a decoded world document
+func documentVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc[formatVersionKey]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number", formatVersionKey)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", formatVersionKey, number)
+	}
+	return int(version), nil
+}
+
+// migrateUnversioned upgrades files written before the format was versioned.
+// They may hold a "scenes" map duplicating the rooms, and null collections.
+func migrateUnversioned(doc map[string]interface{}) error {
+	rooms, _ := doc["rooms"].(map[string]interface{})
+	if rooms == nil {
+		rooms = make(map[string]interface{})
+	}
+	if scenes, ok := doc["scenes"].(map[string]interface{}); ok {
+		for id, scene := range scenes {
+			if _, exists := rooms[id]; !exists && scene != nil {
+				rooms[id] = scene
+			}
+		}
+	}
+	delete(doc, "scenes")
+	doc["rooms"] = rooms
+
+	for id, value := range rooms {
+		room, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("room %s is not an object", id)
+		}
+		fillMissing(room, "objects", []interface{}{})
+		fillMissing(room, "exits", map[string]interface{}{})
+		fillMissing(room, "properties", map[string]interface{}{})
+
+		objects, _ := room["objects"].([]interface{})
+		for _, value := range objects {
+			if object, ok := value.(map[string]interface{}); ok {
+				fillMissing(object, "properties", map[string]interface{}{})
+			}
+		}
+	}
+	return nil
+}
+
+// migrateUnexploredExits replaces the empty targets that version 1 used for
+// exits not connected to a room with UnexploredExit
+func migrateUnexploredExits(doc map[string]interface{}) error {
+	rooms, _ := doc["rooms"].(map[string]interface{})
+	for _, value := range rooms {
+		room, _ := value.(map[string]interface{})
+		exits, _ := room["exits"].(map[string]interface{})
+		for direction, target := range exits {
+			if target == "" {
+				exits[direction] = UnexploredExit
+			}
+		}
+	}
+	return nil
+}
+
+// fillMissing sets key to empty when it is absent or null
+func fillMissing(doc map[string]interface{}, key string, empty interface{}) {
+	if doc[key] == nil {
+		doc[key] = empty
+	}
+}
+
+// MigrateWorldFile upgrades a saved world file in place. It returns the
+// version the file was written in; files already at FormatVersion are left
+// untouched. The file is replaced atomically.
+func MigrateWorldFile(filename string) (int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read world file: %w", err)
+	}
+	_, version, err := MigrateWorldJSON(data)
+	if err != nil {
+		return version, err
+	}
+	if version == FormatVersion {
+		return version, nil
+	}
+
+	var world World
+	if err := json.Unmarshal(data, &world); err != nil {
+		return version, fmt.Errorf("failed to unmarshal world: %w", err)
+	}
+	out, err := json.MarshalIndent(&world, "", "  ")
+	if err != nil {
+		return version, fmt.Errorf("failed to marshal world: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".migrate-*.json")
+	if err != nil {
+		return version, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return version, fmt.Errorf("failed to write world file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return version, fmt.Errorf("failed to write world file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return version, fmt.Errorf("failed to write world file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return version, fmt.Errorf("failed to replace world file: %w", err)
+	}
+	return version, nil
+}
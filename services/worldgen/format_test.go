@@ -0,0 +1,145 @@
+package worldgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadSavedWorlds(t *testing.T) {
+	// Worlds saved before the format was versioned must keep loading
+	files, _ := filepath.Glob("test_output/*.json")
+	files = append(files, filepath.Join("..", "..", "generated_world.json"))
+	for _, file := range files {
+		world, err := LoadWorld(file)
+		if err != nil {
+			t.Errorf("Failed to load %s: %v", file, err)
+			continue
+		}
+		if len(world.Rooms) == 0 {
+			t.Errorf("Expected rooms in %s", file)
+		}
+		for id, room := range world.Rooms {
+			if room.Objects == nil || room.Exits == nil || room.Properties == nil {
+				t.Errorf("Expected room %s in %s to have its collections filled in", id, file)
+			}
+		}
+	}
+}
+
+func TestMigrateWorldJSON(t *testing.T) {
+	legacy := []byte(`{"seed": 1731786720077523900, "rooms": {"room_1": {"id": "room_1", "description": "A hall.", "objects": null, "exits": null}}}`)
+	migrated, version, err := MigrateWorldJSON(legacy)
+	if err != nil {
+		t.Fatalf("MigrateWorldJSON failed: %v", err)
+	}
+	if version != 0 {
+		t.Errorf("Expected version 0 for a file without a header, got %d", version)
+	}
+
+	var world World
+	if err := json.Unmarshal(migrated, &world); err != nil {
+		t.Fatalf("Failed to decode migrated world: %v", err)
+	}
+	if world.Seed != 1731786720077523900 {
+		t.Errorf("Expected the seed to survive migration exactly, got %d", world.Seed)
+	}
+
+	current, err := json.Marshal(&world)
+	if err != nil {
+		t.Fatalf("Failed to encode world: %v", err)
+	}
+	if !strings.HasPrefix(string(current), fmt.Sprintf(`{"formatVersion":%d,`, FormatVersion)) {
+		t.Errorf("Expected a formatVersion header, got %s", current)
+	}
+	if _, version, _ := MigrateWorldJSON(current); version != FormatVersion {
+		t.Errorf("Expected saved worlds at version %d, got %d", FormatVersion, version)
+	}
+
+	if _, _, err := MigrateWorldJSON([]byte(`{"formatVersion": 99, "seed": 1, "rooms": {}}`)); err == nil {
+		t.Error("Expected error for a newer format version")
+	}
+	if _, _, err := MigrateWorldJSON([]byte(`{"formatVersion": "one"}`)); err == nil {
+		t.Error("Expected error for a malformed format version")
+	}
+}
+
+func TestMigrateUnexploredExits(t *testing.T) {
+	v1 := []byte(`{"formatVersion": 1, "seed": 2, "rooms": {"hall": {"id": "hall", "description": "A hall.", "objects": [], "exits": {"north": "", "east": "hall"}, "properties": {}}}}`)
+	migrated, version, err := MigrateWorldJSON(v1)
+	if err != nil || version != 1 {
+		t.Fatalf("Expected migration from version 1, got %d, %v", version, err)
+	}
+
+	var world World
+	if err := json.Unmarshal(migrated, &world); err != nil {
+		t.Fatalf("Failed to decode migrated world: %v", err)
+	}
+	if exits := world.Rooms["hall"].Exits; exits["north"] != UnexploredExit || exits["east"] != "hall" {
+		t.Errorf("Expected only the empty exit to become unexplored, got %v", exits)
+	}
+}
+
+func TestMigrateWorldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.json")
+	legacy := `{"seed": 4, "rooms": {}, "scenes": {"room_1": {"id": "room_1", "description": "A hall.", "objects": [], "exits": {}, "properties": {}}}}`
+	os.WriteFile(path, []byte(legacy), 0644)
+
+	version, err := MigrateWorldFile(path)
+	if err != nil || version != 0 {
+		t.Fatalf("Expected migration from version 0, got %d, %v", version, err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `"scenes"`) || !strings.Contains(string(data), `"room_1"`) {
+		t.Errorf("Expected scenes to be moved into rooms, got %s", data)
+	}
+
+	if version, err := MigrateWorldFile(path); err != nil || version != FormatVersion {
+		t.Errorf("Expected a migrated file to be left alone, got %d, %v", version, err)
+	}
+}
+
+func TestWorldSchema(t *testing.T) {
+	var schema struct {
+		Properties struct {
+			FormatVersion struct {
+				Const int `json:"const"`
+			} `json:"formatVersion"`
+		} `json:"properties"`
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(WorldSchema, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema.Properties.FormatVersion.Const != FormatVersion {
+		t.Errorf("Schema describes version %d, want %d", schema.Properties.FormatVersion.Const, FormatVersion)
+	}
+
+	// The schema must list exactly the fields the Go types write
+	for def, value := range map[string]interface{}{
+		"room": Room{}, "object": Object{}, "coord": Coord{}, "region": Region{}, "frontier": Frontier{}, "pendingRoom": PendingRoom{},
+	} {
+		var fields, described []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			if name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+				fields = append(fields, name)
+			}
+		}
+		for name := range schema.Defs[def].Properties {
+			described = append(described, name)
+		}
+		sort.Strings(fields)
+		sort.Strings(described)
+		if !reflect.DeepEqual(fields, described) {
+			t.Errorf("Schema %s lists %v, but the type writes %v", def, described, fields)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package worldgen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/logging"
+)
+
+// Generator creates worlds and rooms with an explicitly chosen AI provider.
+// Build the provider with ai.Open, e.g. from config.ProviderFromEnv.
+type Generator struct {
+	provider ai.Provider
+	logger   logging.Logger
+}
+
+// NewGenerator creates a generator using the given provider.
+// A nil provider generates worlds offline, using the prompts as descriptions.
+func NewGenerator(provider ai.Provider) *Generator {
+	return &Generator{provider: provider}
+}
+
+// Provider returns the generator's provider
+func (g *Generator) Provider() ai.Provider {
+	return g.provider
+}
+
+// SetLogger sets the logger that receives the diagnostics of the generator
+// and its worlds. Without one nothing is logged.
+func (g *Generator) SetLogger(logger logging.Logger) {
+	g.logger = logger
+}
+
+// NewWorld creates an empty world with the given seed that uses the generator's provider.
+// Replay providers are rebound to the seed so recorded responses are keyed per world.
+func (g *Generator) NewWorld(seed int64) (*World, error) {
+	world, err := NewWorld(seed)
+	if err != nil {
+		return nil, err
+	}
+	provider := g.provider
+	if replay, ok := provider.(*ai.ReplayProvider); ok {
+		provider = replay.WithSeed(seed)
+	}
+	world.SetProvider(provider)
+	world.SetLogger(g.logger)
+	return world, nil
+}
+
+// GenerateWorld creates a world of numRooms rooms from a base prompt
+func (g *Generator) GenerateWorld(ctx context.Context, seed int64, basePrompt string, numRooms int, opts GenerateOptions) (*World, error) {
+	world, err := g.NewWorld(seed)
+	if err != nil {
+		return nil, err
+	}
+	if err := world.GenerateWorldWithOptions(ctx, basePrompt, numRooms, opts); err != nil {
+		return nil, err
+	}
+	if err := g.Save(); err != nil {
+		return nil, err
+	}
+	return world, nil
+}
+
+// GenerateRoom creates a standalone room whose description is enhanced by the provider.
+// Like the package-level GenerateRoom, the room ID depends only on the input.
+func (g *Generator) GenerateRoom(ctx context.Context, description string, exits []string) (*Room, error) {
+	enhancedDesc := description
+	if g.provider != nil && description != "" {
+		logger := g.logger
+		if logger == nil {
+			logger = logging.NewNoopLogger()
+		}
+		start := time.Now()
+		desc, err := g.provider.GenerateDescription(ctx, description)
+		logProviderCall(ctx, logger, g.provider, "describe room", start, err)
+		if err == nil && desc != "" {
+			enhancedDesc = desc
+		}
+	}
+	return newContentRoom(description, enhancedDesc, exits)
+}
+
+// Save persists recorded responses when the provider keeps any, e.g. a replay provider
+func (g *Generator) Save() error {
+	saver, ok := g.provider.(interface{ Save() error })
+	if !ok {
+		return nil
+	}
+	if err := saver.Save(); err != nil {
+		return fmt.Errorf("failed to save recorded responses: %w", err)
+	}
+	return nil
+}
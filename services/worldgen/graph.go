@@ -0,0 +1,264 @@
+package worldgen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNoPath is returned by PathBetween when the target cannot be reached
+var ErrNoPath = errors.New("no path between rooms")
+
+// graphEdge is an exit between two existing rooms
+type graphEdge struct {
+	direction Direction
+	target    string
+}
+
+// edges returns every room's exits into existing rooms, in direction order.
+// Exits are directed: an exit from a to b says nothing about the way back.
+func (w *World) edges() map[string][]graphEdge {
+	edges := make(map[string][]graphEdge, len(w.Rooms))
+	for _, id := range w.roomIDs() {
+		room := w.Rooms[id]
+		if room == nil {
+			continue
+		}
+		for _, direction := range sortedKeys(room.Exits) {
+			target := room.Exits[direction]
+			if _, ok := w.Rooms[target]; ok {
+				edges[id] = append(edges[id], graphEdge{direction: Direction(direction), target: target})
+			}
+		}
+	}
+	return edges
+}
+
+// undirected returns the neighbours of every room ignoring exit direction
+func (w *World) undirected() map[string][]string {
+	neighbours := make(map[string][]string, len(w.Rooms))
+	for id, edges := range w.edges() {
+		for _, edge := range edges {
+			neighbours[id] = append(neighbours[id], edge.target)
+			neighbours[edge.target] = append(neighbours[edge.target], id)
+		}
+	}
+	return neighbours
+}
+
+// distancesFrom returns the number of exits on the shortest path from id to
+// every reachable room, and the exit each room was first reached through
+func distancesFrom(edges map[string][]graphEdge, id string) (map[string]int, map[string]string, map[string]Direction) {
+	distance := map[string]int{id: 0}
+	previous := make(map[string]string)
+	via := make(map[string]Direction)
+	for queue := []string{id}; len(queue) > 0; queue = queue[1:] {
+		current := queue[0]
+		for _, edge := range edges[current] {
+			if _, seen := distance[edge.target]; !seen {
+				distance[edge.target] = distance[current] + 1
+				previous[edge.target] = current
+				via[edge.target] = edge.direction
+				queue = append(queue, edge.target)
+			}
+		}
+	}
+	return distance, previous, via
+}
+
+// PathBetween returns the directions to follow from one room to another along
+// the fewest exits. Ties are broken by direction name, so the path is stable.
+// The path from a room to itself is empty.
+func (w *World) PathBetween(from, to string) ([]Direction, error) {
+	if _, ok := w.GetRoom(from); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, from)
+	}
+	if _, ok := w.GetRoom(to); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, to)
+	}
+
+	distance, previous, via := distancesFrom(w.edges(), from)
+	if _, ok := distance[to]; !ok {
+		return nil, fmt.Errorf("%w: %s to %s", ErrNoPath, from, to)
+	}
+	path := make([]Direction, distance[to])
+	for current, i := to, len(path)-1; current != from; current, i = previous[current], i-1 {
+		path[i] = via[current]
+	}
+	return path, nil
+}
+
+// ReachableFrom returns the IDs of the rooms that can be reached from a room
+// by following exits, including the room itself, in sorted order
+func (w *World) ReachableFrom(id string) ([]string, error) {
+	if _, ok := w.GetRoom(id); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
+	}
+	distance, _, _ := distancesFrom(w.edges(), id)
+	reachable := make([]string, 0, len(distance))
+	for room := range distance {
+		reachable = append(reachable, room)
+	}
+	sort.Strings(reachable)
+	return reachable, nil
+}
+
+// Components returns the groups of rooms connected by exits in either
+// direction. Each group is sorted and groups are ordered by their first room.
+func (w *World) Components() [][]string {
+	neighbours := w.undirected()
+	seen := make(map[string]bool)
+	var components [][]string
+	for _, id := range w.roomIDs() {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		component := []string{id}
+		for queue := []string{id}; len(queue) > 0; queue = queue[1:] {
+			for _, next := range neighbours[queue[0]] {
+				if !seen[next] {
+					seen[next] = true
+					component = append(component, next)
+					queue = append(queue, next)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	return components
+}
+
+// StronglyConnectedComponents returns the groups of rooms that can all be
+// reached from each other by following exits. A world in which players can
+// always find their way back has a single group. Groups are sorted as in Components.
+func (w *World) StronglyConnectedComponents() [][]string {
+	edges := w.edges()
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	// Tarjan's algorithm
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, edge := range edges[id] {
+			if _, visited := index[edge.target]; !visited {
+				visit(edge.target)
+				low[id] = min(low[id], low[edge.target])
+			} else if onStack[edge.target] {
+				low[id] = min(low[id], index[edge.target])
+			}
+		}
+
+		if low[id] == index[id] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+	for _, id := range w.roomIDs() {
+		if _, visited := index[id]; !visited {
+			visit(id)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+	return components
+}
+
+// Diameter returns the longest shortest path, in exits, between any two rooms
+// where the second can be reached from the first
+func (w *World) Diameter() int {
+	edges := w.edges()
+	diameter := 0
+	for _, id := range w.roomIDs() {
+		distance, _, _ := distancesFrom(edges, id)
+		for _, d := range distance {
+			diameter = max(diameter, d)
+		}
+	}
+	return diameter
+}
+
+// DeadEnds returns the rooms with at most one exit into another room, in sorted order
+func (w *World) DeadEnds() []string {
+	edges := w.edges()
+	var deadEnds []string
+	for _, id := range w.roomIDs() {
+		targets := make(map[string]bool)
+		for _, edge := range edges[id] {
+			if edge.target != id {
+				targets[edge.target] = true
+			}
+		}
+		if len(targets) <= 1 {
+			deadEnds = append(deadEnds, id)
+		}
+	}
+	return deadEnds
+}
+
+// ArticulationRooms returns the rooms whose removal would split the rooms
+// around them into separate groups, ignoring exit direction. They are the
+// chokepoints every route between those groups passes through.
+func (w *World) ArticulationRooms() []string {
+	neighbours := w.undirected()
+	index := make(map[string]int)
+	low := make(map[string]int)
+	articulation := make(map[string]bool)
+
+	// Hopcroft and Tarjan's algorithm
+	var visit func(id, parent string)
+	visit = func(id, parent string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		children := 0
+		for _, next := range neighbours[id] {
+			if next == parent || next == id {
+				continue
+			}
+			if _, visited := index[next]; visited {
+				low[id] = min(low[id], index[next])
+				continue
+			}
+			children++
+			visit(next, id)
+			low[id] = min(low[id], low[next])
+			if parent != "" && low[next] >= index[id] {
+				articulation[id] = true
+			}
+		}
+		if parent == "" && children > 1 {
+			articulation[id] = true
+		}
+	}
+	for _, id := range w.roomIDs() {
+		if _, visited := index[id]; !visited {
+			visit(id, "")
+		}
+	}
+
+	rooms := make([]string, 0, len(articulation))
+	for id := range articulation {
+		rooms = append(rooms, id)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
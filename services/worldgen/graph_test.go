@@ -0,0 +1,113 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// graphWorld builds a small world:
+//
+//	a <-> b <-> c -> d     e (isolated)
+//	      ^     |
+//	      +- f <+
+func graphWorld(t *testing.T) *World {
+	t.Helper()
+	world, err := NewWorld(1)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		if err := world.AddRoom(namedScene(id, id, "Room "+id+".")); err != nil {
+			t.Fatalf("Failed to add room: %v", err)
+		}
+	}
+	world.ConnectRooms("a", "b", East)
+	world.ConnectRooms("b", "c", East)
+	world.ConnectOneWay("c", "d", East)
+	world.ConnectOneWay("c", "f", South)
+	world.ConnectOneWay("f", "b", North)
+	return world
+}
+
+func TestPathBetween(t *testing.T) {
+	world := graphWorld(t)
+
+	path, err := world.PathBetween("a", "d")
+	if err != nil {
+		t.Fatalf("PathBetween failed: %v", err)
+	}
+	if want := []Direction{East, East, East}; !reflect.DeepEqual(path, want) {
+		t.Errorf("Expected %v, got %v", want, path)
+	}
+
+	// Exits are directed: d has no way out
+	if _, err := world.PathBetween("d", "a"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("Expected ErrNoPath, got %v", err)
+	}
+	if path, err := world.PathBetween("f", "a"); err != nil || !reflect.DeepEqual(path, []Direction{North, West}) {
+		t.Errorf("Expected north then west, got %v, %v", path, err)
+	}
+	if path, err := world.PathBetween("a", "a"); err != nil || len(path) != 0 {
+		t.Errorf("Expected an empty path to the same room, got %v, %v", path, err)
+	}
+	if _, err := world.PathBetween("a", "missing"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+}
+
+func TestGraphAnalytics(t *testing.T) {
+	world := graphWorld(t)
+
+	if reachable, _ := world.ReachableFrom("c"); !reflect.DeepEqual(reachable, []string{"a", "b", "c", "d", "f"}) {
+		t.Errorf("Unexpected rooms reachable from c: %v", reachable)
+	}
+	if reachable, _ := world.ReachableFrom("d"); !reflect.DeepEqual(reachable, []string{"d"}) {
+		t.Errorf("Expected only d to be reachable from d, got %v", reachable)
+	}
+	if _, err := world.ReachableFrom("missing"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+
+	if components := world.Components(); !reflect.DeepEqual(components, [][]string{{"a", "b", "c", "d", "f"}, {"e"}}) {
+		t.Errorf("Unexpected components %v", components)
+	}
+	if components := world.StronglyConnectedComponents(); !reflect.DeepEqual(components, [][]string{{"a", "b", "c", "f"}, {"d"}, {"e"}}) {
+		t.Errorf("Unexpected strongly connected components %v", components)
+	}
+
+	// a to d and f to d both take three exits
+	if diameter := world.Diameter(); diameter != 3 {
+		t.Errorf("Expected diameter 3, got %d", diameter)
+	}
+	if deadEnds := world.DeadEnds(); !reflect.DeepEqual(deadEnds, []string{"a", "d", "e", "f"}) {
+		t.Errorf("Unexpected dead ends %v", deadEnds)
+	}
+	if articulation := world.ArticulationRooms(); !reflect.DeepEqual(articulation, []string{"b", "c"}) {
+		t.Errorf("Unexpected articulation rooms %v", articulation)
+	}
+}
+
+func TestGraphOfGeneratedWorld(t *testing.T) {
+	world, err := NewWorld(3)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	opts := DefaultGenerateOptions()
+	opts.Topology = LinearTopology{}
+	if err := world.GenerateWorldWithOptions(context.Background(), "A long road", 6, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	// A linear world is a chain: every inner room is a chokepoint
+	if components := world.StronglyConnectedComponents(); len(components) != 1 {
+		t.Errorf("Expected every room to reach every other, got %v", components)
+	}
+	if diameter := world.Diameter(); diameter != 5 {
+		t.Errorf("Expected diameter 5, got %d", diameter)
+	}
+	if len(world.DeadEnds()) != 2 || len(world.ArticulationRooms()) != 4 {
+		t.Errorf("Expected 2 dead ends and 4 chokepoints, got %v and %v", world.DeadEnds(), world.ArticulationRooms())
+	}
+}
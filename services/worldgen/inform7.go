@@ -0,0 +1,188 @@
+package worldgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// informDirections maps directions to their Inform 7 names. Exits through
+// other directions are left out of the export with a comment.
+var informDirections = map[Direction]string{
+	North:     "north",
+	South:     "south",
+	East:      "east",
+	West:      "west",
+	Northeast: "northeast",
+	Northwest: "northwest",
+	Southeast: "southeast",
+	Southwest: "southwest",
+	Up:        "up",
+	Down:      "down",
+	In:        "inside",
+	Out:       "outside",
+}
+
+// informReserved are words that Inform 7 would read as part of the sentence
+// rather than the name, so names containing them are replaced
+var informReserved = wordSet("a", "an", "and", "are", "called", "has", "have", "in", "inside", "is", "on",
+	"outside", "the", "was", "were", "with", "north", "south", "east", "west", "northeast", "northwest",
+	"southeast", "southwest", "up", "down", "container", "door", "person", "region", "room", "thing")
+
+// informNames hands out unique Inform 7 names for rooms, regions and things
+type informNames struct {
+	used map[string]bool
+}
+
+// name returns a unique Inform 7 name for a label. It keeps letters, digits
+// and spaces, falls back to the kind and a number when nothing usable is left,
+// and numbers repeated names.
+func (n *informNames) name(label, kind string) string {
+	words := strings.FieldsFunc(label, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	usable := len(words) > 0 && unicode.IsLetter([]rune(words[0])[0])
+	for _, word := range words {
+		if informReserved[strings.ToLower(word)] {
+			usable = false
+		}
+	}
+	base, i := strings.Join(words, " "), 1
+	name := base
+	if !usable {
+		base, name = kind, kind+" 1"
+	}
+	for n.used[strings.ToLower(name)] {
+		i++
+		name = fmt.Sprintf("%s %d", base, i)
+	}
+	n.used[strings.ToLower(name)] = true
+	return name
+}
+
+// informText quotes text as an Inform 7 string. Double quotes become single
+// quotes, brackets would start substitutions and line breaks end the string.
+func informText(text string) string {
+	text = strings.ReplaceAll(text, `"`, "'")
+	text = strings.NewReplacer("[", "[bracket]", "]", "[close bracket]").Replace(text)
+	return `"` + strings.Join(strings.Fields(text), " ") + `"`
+}
+
+// informEnd ends a sentence whose last word is a quoted string. Inform 7
+// already ends the sentence at the closing quote after ".", "!" or "?".
+func informEnd(quoted string) string {
+	if strings.HasSuffix(quoted, `."`) || strings.HasSuffix(quoted, `!"`) || strings.HasSuffix(quoted, `?"`) {
+		return quoted
+	}
+	return quoted + "."
+}
+
+// ExportInform7 writes the world as Inform 7 source text. Rooms, regions,
+// exits and objects are declared under their own headings; the start room
+// comes first so play begins there. Names Inform 7 cannot use, or that are
+// repeated, get a unique name and keep the original as the printed name.
+func (w *World) ExportInform7(title string) ([]byte, error) {
+	if title == "" {
+		title = "Generated World"
+	}
+
+	ids := w.roomIDs()
+	if _, ok := w.Rooms[w.Start]; ok {
+		for i, id := range ids {
+			if id == w.Start {
+				ids = append(append([]string{id}, ids[:i]...), ids[i+1:]...)
+				break
+			}
+		}
+	}
+
+	names := &informNames{used: make(map[string]bool)}
+	rooms := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if w.Rooms[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
+		}
+		rooms[id] = names.name(w.roomLabel(id), "Location")
+	}
+	regions := make(map[string]string)
+	for _, region := range w.roomRegions() {
+		regions[region] = names.name(region, "Area")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s by Worldgen\n\n", informText(title))
+	fmt.Fprintf(&b, "[Exported by worldgen from a world with seed %d.]\n", w.Seed)
+	chapters := 0
+	chapter := func(heading string) {
+		chapters++
+		fmt.Fprintf(&b, "\nChapter %d - %s\n", chapters, heading)
+	}
+
+	if len(regions) > 0 {
+		chapter("Regions")
+		b.WriteString("\n")
+		for _, region := range w.roomRegions() {
+			fmt.Fprintf(&b, "%s is a region.", regions[region])
+			if regions[region] != region {
+				fmt.Fprintf(&b, " The printed name of %s is %s", regions[region], informEnd(informText(region)))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	chapter("Rooms")
+	for _, id := range ids {
+		room := w.Rooms[id]
+		name := rooms[id]
+		fmt.Fprintf(&b, "\n%s is a room.", name)
+		if name != w.roomLabel(id) {
+			fmt.Fprintf(&b, " The printed name of %s is %s", name, informEnd(informText(w.roomLabel(id))))
+		}
+		if strings.TrimSpace(room.Description) != "" {
+			fmt.Fprintf(&b, " The description of %s is %s", name, informEnd(informText(room.Description)))
+		}
+		if room.Region != "" {
+			fmt.Fprintf(&b, " %s is in %s.", name, regions[room.Region])
+		}
+		b.WriteString("\n")
+	}
+
+	chapter("Map")
+	b.WriteString("\n")
+	// "Mapped" exits only lead one way, so each exit is declared on its own
+	edges := w.edges()
+	for _, id := range ids {
+		for _, edge := range edges[id] {
+			direction, ok := informDirections[edge.direction]
+			if !ok {
+				fmt.Fprintf(&b, "[The exit %s from %s to %s has no Inform 7 direction.]\n", edge.direction, rooms[id], rooms[edge.target])
+				continue
+			}
+			fmt.Fprintf(&b, "%s is mapped %s of %s.\n", rooms[edge.target], direction, rooms[id])
+		}
+	}
+
+	chapter("Things")
+	for _, id := range ids {
+		for _, object := range w.Rooms[id].Objects {
+			name := names.name(object.Name, "Item")
+			kind := "thing"
+			if container, _ := object.Properties[PropContainer].(bool); container {
+				kind = "container"
+			}
+			fmt.Fprintf(&b, "\n%s is a %s in %s.", name, kind, rooms[id])
+			if name != object.Name {
+				fmt.Fprintf(&b, " The printed name of %s is %s Understand %s as %s.", name, informEnd(informText(object.Name)), informText(strings.ToLower(object.Name)), name)
+			}
+			if strings.TrimSpace(object.Description) != "" {
+				fmt.Fprintf(&b, " The description of %s is %s", name, informEnd(informText(object.Description)))
+			}
+			if portable, ok := object.Properties[PropPortable].(bool); ok && !portable {
+				fmt.Fprintf(&b, " %s is fixed in place.", name)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package worldgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportInform7(t *testing.T) {
+	world := renderWorld(t)
+	hall := world.Rooms["hall"]
+	hall.Description = `Banners read "Welcome" [faded].`
+	hall.AddObject(Object{ID: "obj_chest", Name: "oak chest", Description: "A heavy chest.", Properties: defaultObjectProperties("oak chest")})
+	hall.AddObject(Object{ID: "obj_statue", Name: "statue", Description: "A marble statue.", Properties: defaultObjectProperties("statue")})
+	world.Rooms["kitchen"].AddObject(Object{ID: "obj_chest_2", Name: "oak chest", Properties: defaultObjectProperties("oak chest")})
+	tower := namedScene("tower", "North Tower", "A draughty tower.")
+	if err := world.AddRoom(tower); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	world.ConnectRooms("tower", "garden", Southwest)
+	world.Rooms["garden"].AddExit("sideways", "hall")
+
+	data, err := world.ExportInform7("Manor House")
+	if err != nil {
+		t.Fatalf("ExportInform7 failed: %v", err)
+	}
+	source := string(data)
+
+	for _, want := range []string{
+		`"Manor House" by Worldgen`,
+		`Grounds is a region.`,
+		`Great Hall is a room. The description of Great Hall is "Banners read 'Welcome' [bracket]faded[close bracket]." Great Hall is in Manor.`,
+		`Pantry Stores is a room. The printed name of Pantry Stores is "Pantry & Stores".`,
+		`Location 1 is a room. The printed name of Location 1 is "North Tower".`,
+		"Kitchen is mapped east of Great Hall.",
+		"Great Hall is mapped west of Kitchen.",
+		"Pantry Stores is mapped inside of Kitchen.",
+		"Attic is mapped up of Great Hall.",
+		"Old Well is mapped east of Rose Garden.",
+		"[The exit sideways from Rose Garden to Great Hall has no Inform 7 direction.]",
+		"oak chest is a container in Great Hall.",
+		"statue is a thing in Great Hall.",
+		"statue is fixed in place.",
+		`oak chest 2 is a container in Kitchen. The printed name of oak chest 2 is "oak chest". Understand "oak chest" as oak chest 2.`,
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("Expected source to contain %q, got:\n%s", want, source)
+		}
+	}
+
+	// Play begins in the first room declared
+	if strings.Index(source, "Great Hall is a room.") > strings.Index(source, "Attic is a room.") {
+		t.Error("Expected the start room to be declared first")
+	}
+	// Mapped exits lead one way only, so a one-way exit has no way back
+	if strings.Contains(source, "Rose Garden is mapped west of Old Well.") {
+		t.Error("One-way exit should not be mapped back")
+	}
+}
@@ -2,6 +2,7 @@ package worldgen
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -122,6 +123,7 @@ func TestWorldPersistenceWithLogging(t *testing.T) {
 	defer logger.Shutdown()
 
 	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test_world_with_logging.json")
 
 	// Create world with logging
 	worldGen, err := NewWorldGenLogger(12345, logger)
@@ -147,7 +149,7 @@ func TestWorldPersistenceWithLogging(t *testing.T) {
 			t.Errorf("Failed to log save attempt: %v", err)
 		}
 
-		err = worldGen.Save("test_world_with_logging.json")
+		err = worldGen.Save(path)
 		if err != nil {
 			t.Fatalf("Failed to save world: %v", err)
 		}
@@ -165,7 +167,7 @@ func TestWorldPersistenceWithLogging(t *testing.T) {
 			t.Errorf("Failed to log load attempt: %v", err)
 		}
 
-		loadedWorld, err := LoadWorld("test_world_with_logging.json")
+		loadedWorld, err := LoadWorld(path)
 		if err != nil {
 			t.Fatalf("Failed to load world: %v", err)
 		}
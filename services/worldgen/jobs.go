@@ -0,0 +1,361 @@
+package worldgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"textadventureservices/services/usage"
+)
+
+// JobStatus is the state of a generation job
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job event types, in the order they occur. A job sends one event per
+// generated room and ends with exactly one completed, failed or cancelled event.
+const (
+	EventStarted       = "started"
+	EventRoom          = "room"
+	EventAttemptFailed = "attempt_failed"
+	EventCompleted     = "completed"
+	EventFailed        = "failed"
+	EventCancelled     = "cancelled"
+)
+
+// MaxJobRooms caps the size of the worlds generation jobs are started for
+const MaxJobRooms = 500
+
+// MaxRunningJobs caps the number of jobs a job manager runs at once
+const MaxRunningJobs = 8
+
+// JobRetention is how long finished jobs are kept before they are forgotten
+const JobRetention = time.Hour
+
+var (
+	// ErrJobNotFound is returned for job IDs that are unknown or forgotten
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that has already finished
+	ErrJobFinished = errors.New("job already finished")
+	// ErrInvalidRequest is returned when a generation request cannot be started
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrTooManyJobs is returned when starting a job while MaxRunningJobs are running
+	ErrTooManyJobs = errors.New("too many running jobs")
+)
+
+// GenerateRequest describes a world to generate in a job
+type GenerateRequest struct {
+	Prompt string `json:"prompt"`
+	// NumRooms is the number of rooms; 0 uses the service's default
+	NumRooms int `json:"num_rooms,omitempty"`
+	// Seed fixes the world seed; 0 uses the configured seed or the clock
+	Seed int64 `json:"seed,omitempty"`
+	// Topology names the shape of the map; empty uses sprawl
+	Topology string `json:"topology,omitempty"`
+	// Structured asks the provider for structured rooms where supported
+	Structured bool `json:"structured,omitempty"`
+	// Session attributes the job's usage to a game session and holds the
+	// job to the session's budget
+	Session string `json:"session,omitempty"`
+}
+
+// JobEvent is a progress event of a job
+type JobEvent struct {
+	// ID numbers the events of a job from 1
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	// Done counts the rooms generated so far, out of Total
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	RoomID  string `json:"room_id,omitempty"`
+	Attempt int    `json:"attempt,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// JobSnapshot is the state of a job at one moment
+type JobSnapshot struct {
+	ID         string       `json:"id"`
+	Status     JobStatus    `json:"status"`
+	Prompt     string       `json:"prompt"`
+	Seed       int64        `json:"seed"`
+	Done       int          `json:"done"`
+	Total      int          `json:"total"`
+	Error      string       `json:"error,omitempty"`
+	Session    string       `json:"session,omitempty"`
+	Usage      usage.Totals `json:"usage"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	// World is set once the job has completed
+	World *World `json:"world,omitempty"`
+}
+
+// Job is a world being generated in the background
+type Job struct {
+	id      string
+	prompt  string
+	seed    int64
+	session string
+	created time.Time
+	cancel  context.CancelFunc
+	// tracker has the usage of the job, recorded under its ID
+	tracker *usage.Tracker
+
+	mu       sync.Mutex
+	status   JobStatus
+	done     int
+	total    int
+	err      error
+	world    *World
+	finished time.Time
+	events   []JobEvent
+	// changed is closed and replaced whenever an event is added
+	changed chan struct{}
+}
+
+// ID returns the job's ID
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Snapshot returns the job's current state
+func (j *Job) Snapshot() JobSnapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	snapshot := JobSnapshot{
+		ID:        j.id,
+		Status:    j.status,
+		Prompt:    j.prompt,
+		Seed:      j.seed,
+		Done:      j.done,
+		Total:     j.total,
+		Session:   j.session,
+		CreatedAt: j.created,
+		World:     j.world,
+	}
+	if j.tracker != nil {
+		snapshot.Usage = j.tracker.Job(j.id)
+	}
+	if j.err != nil {
+		snapshot.Error = j.err.Error()
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		snapshot.FinishedAt = &finished
+	}
+	return snapshot
+}
+
+// Events returns the events after the one with ID after, a channel that is
+// closed when the next event is added, and whether the job has finished. A
+// finished job adds no more events.
+func (j *Job) Events(after int) ([]JobEvent, <-chan struct{}, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var events []JobEvent
+	if after < len(j.events) {
+		events = append(events, j.events[max(after, 0):]...)
+	}
+	return events, j.changed, j.status != JobRunning
+}
+
+// addEvent records an event; the caller holds the lock
+func (j *Job) addEvent(event JobEvent) {
+	event.ID = len(j.events) + 1
+	event.Done, event.Total = j.done, j.total
+	j.events = append(j.events, event)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// progress turns the generation progress of a room into an event
+func (j *Job) progress(p Progress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if p.Err != nil {
+		j.addEvent(JobEvent{Type: EventAttemptFailed, RoomID: p.RoomID, Attempt: p.Attempt, Error: p.Err.Error()})
+		return
+	}
+	j.done = p.Done
+	j.addEvent(JobEvent{Type: EventRoom, RoomID: p.RoomID, Attempt: p.Attempt})
+}
+
+// finish records the outcome of the job. A job that failed after being
+// cancelled through its context counts as cancelled, while a world generated
+// before a cancel arrived still completes the job. A finished job keeps its
+// outcome.
+func (j *Job) finish(ctx context.Context, world *World, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != JobRunning {
+		return
+	}
+	j.finished = time.Now()
+	switch {
+	case err == nil:
+		j.status = JobCompleted
+		j.world = world
+		j.addEvent(JobEvent{Type: EventCompleted})
+	case errors.Is(ctx.Err(), context.Canceled):
+		j.status = JobCancelled
+		j.err = context.Canceled
+		j.addEvent(JobEvent{Type: EventCancelled})
+	default:
+		j.status = JobFailed
+		j.err = err
+		j.addEvent(JobEvent{Type: EventFailed, Error: err.Error()})
+	}
+}
+
+// JobManager runs generation jobs on a service and keeps them until they
+// have been finished for JobRetention
+type JobManager struct {
+	service *Service
+	// maxRunning is the number of jobs that may run at once
+	maxRunning int
+	mu         sync.Mutex
+	jobs       map[string]*Job
+}
+
+// NewJobManager creates a job manager generating worlds with the service,
+// running at most MaxRunningJobs at once
+func NewJobManager(service *Service) *JobManager {
+	return &JobManager{service: service, maxRunning: MaxRunningJobs, jobs: make(map[string]*Job)}
+}
+
+// Start checks the request and starts generating the world in the background.
+// It returns ErrTooManyJobs while the manager is running as many jobs as it may.
+func (m *JobManager) Start(req GenerateRequest) (*Job, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, fmt.Errorf("%w: prompt is required", ErrInvalidRequest)
+	}
+	numRooms := req.NumRooms
+	if numRooms == 0 {
+		numRooms = m.service.config.DefaultRooms
+	}
+	if numRooms < 1 || numRooms > MaxJobRooms {
+		return nil, fmt.Errorf("%w: num_rooms must be between 1 and %d", ErrInvalidRequest, MaxJobRooms)
+	}
+	opts := DefaultGenerateOptions()
+	if req.Topology != "" {
+		topology, err := TopologyByName(req.Topology)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		opts.Topology = topology
+	}
+	opts.Structured = req.Structured
+	seed := req.Seed
+	if seed == 0 {
+		seed = m.service.defaultSeed()
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(usage.With(context.Background(), usage.Scope{Session: req.Session, Job: id, Command: "generate-world"}))
+	job := &Job{
+		id:      id,
+		prompt:  req.Prompt,
+		seed:    seed,
+		session: req.Session,
+		created: time.Now(),
+		cancel:  cancel,
+		tracker: m.service.tracker,
+		status:  JobRunning,
+		total:   numRooms,
+		changed: make(chan struct{}),
+	}
+	job.addEvent(JobEvent{Type: EventStarted})
+	opts.Progress = job.progress
+
+	m.mu.Lock()
+	m.prune()
+	if running := m.running(); running >= m.maxRunning {
+		m.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("%w: %d of %d are running", ErrTooManyJobs, running, m.maxRunning)
+	}
+	m.jobs[id] = job
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		world, err := m.service.generate(ctx, req.Prompt, seed, numRooms, opts)
+		job.finish(ctx, world, err)
+	}()
+	return job, nil
+}
+
+// Get returns a job by ID
+func (m *JobManager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job, nil
+}
+
+// Cancel stops a running job. The job reports that it was cancelled once
+// the rooms being generated have stopped.
+func (m *JobManager) Cancel(id string) (*Job, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	job.mu.Lock()
+	running := job.status == JobRunning
+	job.mu.Unlock()
+	if !running {
+		return job, fmt.Errorf("%w: %s", ErrJobFinished, id)
+	}
+	job.cancel()
+	return job, nil
+}
+
+// prune forgets jobs finished more than JobRetention ago; the caller holds the lock
+func (m *JobManager) prune() {
+	for id, job := range m.jobs {
+		job.mu.Lock()
+		expired := !job.finished.IsZero() && time.Since(job.finished) > JobRetention
+		job.mu.Unlock()
+		if expired {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// running counts the jobs that have not finished; the caller holds the lock
+func (m *JobManager) running() int {
+	count := 0
+	for _, job := range m.jobs {
+		job.mu.Lock()
+		if job.status == JobRunning {
+			count++
+		}
+		job.mu.Unlock()
+	}
+	return count
+}
+
+// newJobID returns a random job ID, so IDs cannot be guessed
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create job ID: %w", err)
+	}
+	return "job_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,231 @@
+package worldgen
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Coord is the position of a room on the world grid.
+// North is +Y, east is +X and up is +Z.
+type Coord struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+// Move returns the coordinate reached by applying an offset
+func (c Coord) Move(o Offset) Coord {
+	return Coord{X: c.X + o.X, Y: c.Y + o.Y, Z: c.Z + o.Z}
+}
+
+// String formats the coordinate as (x, y, z)
+func (c Coord) String() string {
+	return fmt.Sprintf("(%d, %d, %d)", c.X, c.Y, c.Z)
+}
+
+// LayoutNode is a room slot in a layout
+type LayoutNode struct {
+	Coord Coord
+	// Parent is the node this one was grown from, or -1 for the first node
+	Parent int
+	// Via is the direction from Parent to this node
+	Via Direction
+	// Kind is an optional hint from the topology, such as "corridor", used in room prompts
+	Kind string
+	// Region is the name of the region the node was assigned to, if any
+	Region string
+}
+
+// LayoutEdge is a directed exit between two layout nodes.
+// Edges through directions with an opposite are materialized in both directions.
+type LayoutEdge struct {
+	From      int
+	To        int
+	Direction Direction
+}
+
+// Layout places rooms on a 3D grid before any room is generated.
+// It refuses to put two rooms on the same coordinate and only connects
+// rooms whose positions match the direction of the exit.
+type Layout struct {
+	Nodes    []LayoutNode
+	Edges    []LayoutEdge
+	occupied map[Coord]int
+	exits    map[int]map[Direction]int
+}
+
+// NewLayout creates an empty layout
+func NewLayout() *Layout {
+	return &Layout{
+		occupied: make(map[Coord]int),
+		exits:    make(map[int]map[Direction]int),
+	}
+}
+
+// Len returns the number of nodes in the layout
+func (l *Layout) Len() int {
+	return len(l.Nodes)
+}
+
+// At returns the node at a coordinate
+func (l *Layout) At(c Coord) (int, bool) {
+	index, ok := l.occupied[c]
+	return index, ok
+}
+
+// AddNode places a node on a free coordinate
+func (l *Layout) AddNode(c Coord, parent int, via Direction) (int, error) {
+	if existing, ok := l.occupied[c]; ok {
+		return -1, fmt.Errorf("coordinate %s is already occupied by node %d", c, existing)
+	}
+	index := len(l.Nodes)
+	l.Nodes = append(l.Nodes, LayoutNode{Coord: c, Parent: parent, Via: via})
+	l.occupied[c] = index
+	return index, nil
+}
+
+// Exit returns the node reached from a node through a direction
+func (l *Layout) Exit(from int, dir Direction) (int, bool) {
+	to, ok := l.exits[from][dir]
+	return to, ok
+}
+
+// Connect links two nodes through a direction. For spatial directions the
+// target must sit exactly one step away in that direction.
+func (l *Layout) Connect(from, to int, dir Direction) error {
+	info, ok := GetDirectionInfo(dir)
+	if !ok {
+		return fmt.Errorf("unknown direction: %q", dir)
+	}
+	if from < 0 || from >= len(l.Nodes) || to < 0 || to >= len(l.Nodes) {
+		return fmt.Errorf("node index out of range")
+	}
+	if !info.Offset.IsZero() && l.Nodes[from].Coord.Move(info.Offset) != l.Nodes[to].Coord {
+		return fmt.Errorf("node %d at %s is not %s of node %d at %s",
+			to, l.Nodes[to].Coord, dir, from, l.Nodes[from].Coord)
+	}
+	if existing, ok := l.Exit(from, dir); ok {
+		return fmt.Errorf("node %d already has a %s exit to node %d", from, dir, existing)
+	}
+	if !info.OneWay {
+		if existing, ok := l.Exit(to, info.Opposite); ok {
+			return fmt.Errorf("node %d already has a %s exit to node %d", to, info.Opposite, existing)
+		}
+	}
+
+	l.setExit(from, dir, to)
+	if !info.OneWay {
+		l.setExit(to, info.Opposite, from)
+	}
+	l.Edges = append(l.Edges, LayoutEdge{From: from, To: to, Direction: dir})
+	return nil
+}
+
+func (l *Layout) setExit(from int, dir Direction, to int) {
+	if l.exits[from] == nil {
+		l.exits[from] = make(map[Direction]int)
+	}
+	l.exits[from][dir] = to
+}
+
+// openDirections returns the directions from a node that lead to a free coordinate
+func (l *Layout) openDirections(index int, directions []Direction) []Direction {
+	var open []Direction
+	for _, dir := range directions {
+		if _, used := l.Exit(index, dir); used {
+			continue
+		}
+		if _, taken := l.At(l.Nodes[index].Coord.Move(dir.Offset())); taken {
+			continue
+		}
+		open = append(open, dir)
+	}
+	return open
+}
+
+// closeLoops connects a node to already placed neighbours it is not linked to yet,
+// each with the given probability
+func (l *Layout) closeLoops(rng *rand.Rand, index int, directions []Direction, chance float64) {
+	if chance <= 0 {
+		return
+	}
+	for _, dir := range directions {
+		neighbour, ok := l.At(l.Nodes[index].Coord.Move(dir.Offset()))
+		if !ok || neighbour == index {
+			continue
+		}
+		if _, linked := l.Exit(index, dir); linked {
+			continue
+		}
+		if _, linked := l.Exit(neighbour, dir.GetOppositeDirection()); linked {
+			continue
+		}
+		if rng.Float64() < chance {
+			// Both sides were checked above, so this cannot fail
+			_ = l.Connect(index, neighbour, dir)
+		}
+	}
+}
+
+// spatialDirections filters out directions that do not move on the grid
+func spatialDirections(directions []Direction) []Direction {
+	var spatial []Direction
+	for _, dir := range directions {
+		if !dir.Offset().IsZero() {
+			spatial = append(spatial, dir)
+		}
+	}
+	return spatial
+}
+
+// growLayout grows a random tree of numRooms nodes from the origin, attaching
+// each new node on a free neighbouring coordinate, then closes loops between
+// adjacent nodes with probability loopChance
+func growLayout(rng *rand.Rand, numRooms int, directions []Direction, loopChance float64) (*Layout, error) {
+	directions = spatialDirections(directions)
+	if len(directions) == 0 {
+		return nil, fmt.Errorf("layout needs at least one spatial direction")
+	}
+
+	layout := NewLayout()
+	if _, err := layout.AddNode(Coord{}, -1, ""); err != nil {
+		return nil, err
+	}
+
+	for layout.Len() < numRooms {
+		// Pick a random existing node that still has room to grow
+		var candidates []int
+		for index := range layout.Nodes {
+			if len(layout.openDirections(index, directions)) > 0 {
+				candidates = append(candidates, index)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no free coordinate left after placing %d rooms", layout.Len())
+		}
+
+		source := candidates[rng.Intn(len(candidates))]
+		open := layout.openDirections(source, directions)
+		dir := open[rng.Intn(len(open))]
+		index, err := layout.AddNode(layout.Nodes[source].Coord.Move(dir.Offset()), source, dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := layout.Connect(source, index, dir); err != nil {
+			return nil, err
+		}
+		layout.closeLoops(rng, index, directions, loopChance)
+	}
+
+	return layout, nil
+}
+
+// RoomAt returns the room placed at a coordinate
+func (w *World) RoomAt(c Coord) (*Room, bool) {
+	for _, room := range w.Rooms {
+		if room.Coord != nil && *room.Coord == c {
+			return room, true
+		}
+	}
+	return nil, false
+}
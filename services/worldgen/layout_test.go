@@ -0,0 +1,111 @@
+package worldgen
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestLayoutRefusesOverlapAndBadGeometry(t *testing.T) {
+	layout := NewLayout()
+	origin, err := layout.AddNode(Coord{}, -1, "")
+	if err != nil {
+		t.Fatalf("Failed to add node: %v", err)
+	}
+	north, err := layout.AddNode(Coord{Y: 1}, origin, North)
+	if err != nil {
+		t.Fatalf("Failed to add node: %v", err)
+	}
+
+	if _, err := layout.AddNode(Coord{Y: 1}, origin, North); err == nil {
+		t.Error("Expected error when placing two nodes on the same coordinate")
+	}
+	if err := layout.Connect(origin, north, East); err == nil {
+		t.Error("Expected error when the exit direction does not match the geometry")
+	}
+	if err := layout.Connect(origin, north, North); err != nil {
+		t.Fatalf("Failed to connect nodes: %v", err)
+	}
+	if err := layout.Connect(origin, north, North); err == nil {
+		t.Error("Expected error when reusing an exit")
+	}
+	if back, ok := layout.Exit(north, South); !ok || back != origin {
+		t.Error("Expected a return exit south")
+	}
+}
+
+func TestGrowLayoutIsSpatiallyConsistent(t *testing.T) {
+	for _, directions := range [][]Direction{CardinalDirections, CompassDirections, SpatialDirections} {
+		layout, err := growLayout(rand.New(rand.NewSource(5)), 40, directions, 0.5)
+		if err != nil {
+			t.Fatalf("Failed to grow layout: %v", err)
+		}
+		if layout.Len() != 40 {
+			t.Errorf("Expected 40 nodes, got %d", layout.Len())
+		}
+
+		seen := make(map[Coord]bool)
+		for _, node := range layout.Nodes {
+			if seen[node.Coord] {
+				t.Errorf("Coordinate %s used twice", node.Coord)
+			}
+			seen[node.Coord] = true
+		}
+
+		for _, edge := range layout.Edges {
+			from, to := layout.Nodes[edge.From].Coord, layout.Nodes[edge.To].Coord
+			if from.Move(edge.Direction.Offset()) != to {
+				t.Errorf("Edge %s from %s does not reach %s", edge.Direction, from, to)
+			}
+		}
+		if len(layout.Edges) < layout.Len()-1 {
+			t.Errorf("Expected at least %d edges to connect the layout, got %d", layout.Len()-1, len(layout.Edges))
+		}
+	}
+}
+
+func TestGrowLayoutClosesLoops(t *testing.T) {
+	treeOnly, err := growLayout(rand.New(rand.NewSource(11)), 30, CardinalDirections, 0)
+	if err != nil {
+		t.Fatalf("Failed to grow layout: %v", err)
+	}
+	if len(treeOnly.Edges) != 29 {
+		t.Errorf("Expected a tree with 29 edges without loops, got %d", len(treeOnly.Edges))
+	}
+
+	looped, err := growLayout(rand.New(rand.NewSource(11)), 30, CardinalDirections, 1)
+	if err != nil {
+		t.Fatalf("Failed to grow layout: %v", err)
+	}
+	if len(looped.Edges) <= 29 {
+		t.Errorf("Expected loops to add edges beyond the tree, got %d", len(looped.Edges))
+	}
+}
+
+func TestGeneratedWorldPathsAgreeWithCoordinates(t *testing.T) {
+	world, err := NewWorld(77)
+	if err != nil {
+		t.Fatalf("Failed to create new world: %v", err)
+	}
+	opts := DefaultGenerateOptions()
+	opts.LoopChance = 1
+	if err := world.GenerateWorldWithOptions(context.Background(), "A walled garden", 15, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	for _, room := range world.Rooms {
+		if room.Coord == nil {
+			t.Fatalf("Room %s has no coordinate", room.ID)
+		}
+		if found, ok := world.RoomAt(*room.Coord); !ok || found != room {
+			t.Errorf("RoomAt(%s) did not return room %s", room.Coord, room.ID)
+		}
+		for dir, targetID := range room.Exits {
+			target := world.Rooms[targetID]
+			want := room.Coord.Move(Direction(dir).Offset())
+			if target == nil || target.Coord == nil || *target.Coord != want {
+				t.Errorf("Room %s at %s: exit %s should reach %s", room.ID, room.Coord, dir, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"textadventureservices/services/worldgen/logging"
+)
+
+// UnexploredExit is the target of an exit whose room does not exist yet. In
+// a lazy world Explore generates the room the first time the exit is used.
+const UnexploredExit = "unexplored"
+
+// ErrNoExit is returned by Explore when a room has no usable exit in a direction
+var ErrNoExit = errors.New("no exit in that direction")
+
+// Frontier is the part of a lazily generated world that is laid out but not
+// generated yet. It is saved with the world, so exploring can continue later.
+type Frontier struct {
+	// MaxObjects and Structured are the options the rooms are generated with
+	MaxObjects int  `json:"maxObjects"`
+	Structured bool `json:"structured,omitempty"`
+	// Rooms are the rooms waiting to be generated, by ID
+	Rooms map[string]*PendingRoom `json:"rooms"`
+}
+
+// PendingRoom is a room of a lazy world that has a place on the map and a
+// prompt, but no description yet
+type PendingRoom struct {
+	ID string `json:"id"`
+	// Prompt is the room prompt worked out when the world was laid out
+	Prompt string `json:"prompt"`
+	Coord  Coord  `json:"coord"`
+	Region string `json:"region,omitempty"`
+	// Exits lead to generated or pending rooms by ID
+	Exits map[string]string `json:"exits"`
+}
+
+// linkLazy records a layout edge of a lazy world in both directions. Exits of
+// generated rooms into the frontier are unexplored, while pending rooms keep
+// the target ID until they are generated.
+func (w *World) linkLazy(from, to string, direction Direction) {
+	link := func(source, target string, direction Direction) {
+		if pending, ok := w.Frontier.Rooms[source]; ok {
+			pending.Exits[string(direction)] = target
+		} else if room, ok := w.Rooms[source]; ok {
+			if _, generated := w.Rooms[target]; generated {
+				room.AddExit(string(direction), target)
+			} else {
+				room.AddExit(string(direction), UnexploredExit)
+			}
+		}
+	}
+	link(from, to, direction)
+	if opposite := direction.GetOppositeDirection(); opposite != "" {
+		link(to, from, opposite)
+	}
+}
+
+// Explore follows an exit and returns the room it leads to. The first time an
+// unexplored exit of a lazy world is followed, the room behind it is generated
+// with the descriptions of its generated neighbours as context, connected to
+// them and added to the world. Set the world's provider after loading a lazy
+// world, or the rooms are described by their prompts.
+func (w *World) Explore(ctx context.Context, roomID string, direction Direction) (*Room, error) {
+	room, ok := w.GetRoom(roomID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, roomID)
+	}
+	target, ok := room.Exits[string(direction)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s from %s", ErrNoExit, direction, roomID)
+	}
+	if target != UnexploredExit && target != "" {
+		next, ok := w.GetRoom(target)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, target)
+		}
+		return next, nil
+	}
+
+	pending := w.pendingBehind(roomID, direction)
+	if pending == nil {
+		return nil, fmt.Errorf("%w: %s from %s is not connected to a room", ErrNoExit, direction, roomID)
+	}
+	return w.generatePending(ctx, pending)
+}
+
+// pendingBehind returns the frontier room an unexplored exit leads to. Lazy
+// worlds are laid out with two-way exits, so it is the pending room whose
+// opposite exit leads back.
+func (w *World) pendingBehind(roomID string, direction Direction) *PendingRoom {
+	opposite := direction.GetOppositeDirection()
+	if w.Frontier == nil || opposite == "" {
+		return nil
+	}
+	for _, pending := range w.Frontier.Rooms {
+		if pending.Exits[string(opposite)] == roomID {
+			return pending
+		}
+	}
+	return nil
+}
+
+// generatePending generates a frontier room and swaps it into the world
+func (w *World) generatePending(ctx context.Context, pending *PendingRoom) (*Room, error) {
+	directions := sortedKeys(pending.Exits)
+	var nearby []string
+	for _, direction := range directions {
+		if neighbour, ok := w.Rooms[pending.Exits[direction]]; ok {
+			nearby = append(nearby, fmt.Sprintf("%s: %s", direction, firstSentence(neighbour.Description)))
+		}
+	}
+	prompt := pending.Prompt
+	if len(nearby) > 0 {
+		prompt = fmt.Sprintf("%s. Neighbouring rooms by exit: %s", prompt, strings.Join(nearby, "; "))
+	}
+	ctx = logging.WithFields(ctx, logging.Fields{"seed": w.Seed, "room": pending.ID})
+	w.log().Info(logging.WithFields(ctx, logging.Fields{"coord": pending.Coord.String(), "prompt": prompt}), "Exploring room")
+
+	job := roomJob{id: pending.ID, prompt: prompt, exits: directions, coord: pending.Coord, region: pending.Region}
+	room, err := w.generatePlacedRoom(ctx, job, w.Frontier.MaxObjects, w.Frontier.Structured, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate room %s: %w", pending.ID, err)
+	}
+
+	delete(w.Frontier.Rooms, pending.ID)
+	if err := w.AddRoom(room); err != nil {
+		w.Frontier.Rooms[pending.ID] = pending
+		return nil, err
+	}
+	for _, direction := range directions {
+		target := pending.Exits[direction]
+		neighbour, ok := w.Rooms[target]
+		if !ok {
+			room.AddExit(direction, UnexploredExit)
+			continue
+		}
+		room.AddExit(direction, target)
+		back := string(Direction(direction).GetOppositeDirection())
+		if neighbour.Exits[back] == UnexploredExit {
+			neighbour.AddExit(back, room.ID)
+		}
+	}
+	if len(w.Frontier.Rooms) == 0 {
+		w.Frontier = nil
+	}
+	return room, nil
+}
+
+// firstSentence returns the first sentence of a description, shortened for prompts
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.IndexAny(text, ".!?"); i >= 0 {
+		text = text[:i+1]
+	}
+	return truncate(text, 160)
+}
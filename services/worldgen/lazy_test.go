@@ -0,0 +1,155 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generateLazy lays out a lazy world of numRooms rooms in a line
+func generateLazy(t *testing.T, seed int64, numRooms int, lazy bool) *World {
+	t.Helper()
+	world, err := NewWorld(seed)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&countingProvider{})
+	opts := DefaultGenerateOptions()
+	opts.Topology = LinearTopology{}
+	opts.Lazy = lazy
+	if err := world.GenerateWorldWithOptions(context.Background(), "A sunken temple", numRooms, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+	return world
+}
+
+// exploreAll follows every exit until the frontier is empty
+func exploreAll(t *testing.T, world *World) {
+	t.Helper()
+	for queue := []string{world.Start}; len(queue) > 0; queue = queue[1:] {
+		room := world.Rooms[queue[0]]
+		for _, direction := range sortedKeys(room.Exits) {
+			unexplored := room.Exits[direction] == UnexploredExit
+			next, err := world.Explore(context.Background(), room.ID, Direction(direction))
+			if err != nil {
+				t.Fatalf("Explore %s from %s failed: %v", direction, room.ID, err)
+			}
+			if unexplored {
+				queue = append(queue, next.ID)
+			}
+		}
+	}
+}
+
+func TestLazyGeneration(t *testing.T) {
+	world := generateLazy(t, 7, 4, true)
+
+	if len(world.Rooms) != 1 || world.Frontier == nil || len(world.Frontier.Rooms) != 3 {
+		t.Fatalf("Expected only the start room to be generated, got %d rooms and frontier %+v", len(world.Rooms), world.Frontier)
+	}
+	start := world.Rooms[world.Start]
+	direction := sortedKeys(start.Exits)[0]
+	if start.Exits[direction] != UnexploredExit {
+		t.Fatalf("Expected an unexplored exit, got %v", start.Exits)
+	}
+	if _, ok := start.GetExit(direction); ok {
+		t.Error("Expected GetExit to report no target for an unexplored exit")
+	}
+	if diagnostics := world.Validate(); len(diagnostics) != 0 {
+		t.Errorf("Expected a lazy world to validate clean, got %v", diagnostics)
+	}
+
+	next, err := world.Explore(context.Background(), start.ID, Direction(direction))
+	if err != nil {
+		t.Fatalf("Explore failed: %v", err)
+	}
+	if len(world.Rooms) != 2 || len(world.Frontier.Rooms) != 2 {
+		t.Errorf("Expected one more room, got %d rooms and %d pending", len(world.Rooms), len(world.Frontier.Rooms))
+	}
+	if start.Exits[direction] != next.ID || next.Exits[string(Direction(direction).GetOppositeDirection())] != start.ID {
+		t.Errorf("Expected the rooms to be connected both ways, got %v and %v", start.Exits, next.Exits)
+	}
+	if next.Coord == nil || len(next.Objects) == 0 {
+		t.Errorf("Expected the explored room to be placed and furnished, got %+v", next)
+	}
+	// The new room is described knowing what lies next to it
+	if !strings.Contains(next.Description, "Neighbouring rooms by exit: "+string(Direction(direction).GetOppositeDirection())+": ") {
+		t.Errorf("Expected the neighbour in the prompt, got %q", next.Description)
+	}
+
+	again, err := world.Explore(context.Background(), start.ID, Direction(direction))
+	if err != nil || again != next || len(world.Rooms) != 2 {
+		t.Errorf("Expected the explored room to be reused, got %v, %v", again, err)
+	}
+	if diagnostics := world.Validate(); len(diagnostics) != 0 {
+		t.Errorf("Expected a partly explored world to validate clean, got %v", diagnostics)
+	}
+}
+
+func TestLazyWorldMatchesEagerMap(t *testing.T) {
+	eager := generateLazy(t, 11, 5, false)
+	lazy := generateLazy(t, 11, 5, true)
+	exploreAll(t, lazy)
+
+	if lazy.Frontier != nil {
+		t.Errorf("Expected the frontier to be empty, got %+v", lazy.Frontier)
+	}
+	if len(lazy.Rooms) != len(eager.Rooms) {
+		t.Fatalf("Expected %d rooms, got %d", len(eager.Rooms), len(lazy.Rooms))
+	}
+	for id, room := range eager.Rooms {
+		explored, ok := lazy.Rooms[id]
+		if !ok {
+			t.Errorf("Room %s was never explored", id)
+			continue
+		}
+		if !reflect.DeepEqual(explored.Exits, room.Exits) || *explored.Coord != *room.Coord {
+			t.Errorf("Room %s differs: %v at %v, want %v at %v", id, explored.Exits, explored.Coord, room.Exits, room.Coord)
+		}
+	}
+}
+
+func TestLazyWorldSaveAndResume(t *testing.T) {
+	world := generateLazy(t, 3, 3, true)
+	path := filepath.Join(t.TempDir(), "lazy.json")
+	if err := world.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadWorld(path)
+	if err != nil {
+		t.Fatalf("LoadWorld failed: %v", err)
+	}
+	if loaded.Frontier == nil || len(loaded.Frontier.Rooms) != 2 || loaded.Frontier.MaxObjects != DefaultMaxObjects {
+		t.Fatalf("Expected the frontier to be saved, got %+v", loaded.Frontier)
+	}
+	loaded.SetProvider(&countingProvider{})
+	exploreAll(t, loaded)
+	if len(loaded.Rooms) != 3 || loaded.Frontier != nil {
+		t.Errorf("Expected every room to be explored, got %d rooms", len(loaded.Rooms))
+	}
+}
+
+func TestExploreErrors(t *testing.T) {
+	world := generateLazy(t, 5, 2, true)
+	ctx := context.Background()
+
+	if _, err := world.Explore(ctx, "missing", North); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+	if _, err := world.Explore(ctx, world.Start, "sideways"); !errors.Is(err, ErrNoExit) {
+		t.Errorf("Expected ErrNoExit, got %v", err)
+	}
+
+	// Unexplored exits outside a lazy world have nothing behind them
+	room, _ := world.NewRoom("A closet.", []string{"north"})
+	if err := world.AddRoom(room); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if _, err := world.Explore(ctx, room.ID, North); !errors.Is(err, ErrNoExit) {
+		t.Errorf("Expected ErrNoExit for an exit with no room behind it, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package logging
+
+import "context"
+
+// Fields are key-value pairs attached to log entries, such as the world seed or a room ID
+type Fields map[string]interface{}
+
+type fieldsKey struct{}
+
+// WithFields returns a context carrying fields in addition to those already
+// in ctx. Loggers add the fields of the context to every entry logged with it.
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	merged := make(Fields, len(fields))
+	for key, value := range FieldsFrom(ctx) {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFrom returns the fields carried by a context
+func FieldsFrom(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+	return fields
+}
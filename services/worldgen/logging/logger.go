@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 )
@@ -60,18 +61,22 @@ func (l *HTTPLogger) log(ctx context.Context, level LogLevel, msg string) {
 			"timestamp": time.Now().Format(time.RFC3339),
 		},
 	}
+	// The fields of the context, such as the world seed, travel as metadata
+	for key, value := range FieldsFrom(ctx) {
+		logReq.Metadata[key] = value
+	}
 
 	// Send log asynchronously to avoid blocking
 	go func() {
 		jsonData, err := json.Marshal(logReq)
 		if err != nil {
-			fmt.Printf("Failed to marshal log request: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Failed to marshal log request: %v\n", err)
 			return
 		}
 
 		resp, err := l.client.Post(fmt.Sprintf("%s/api/v1/logs", l.endpoint), "application/json", bytes.NewBuffer(jsonData))
 		if err != nil {
-			fmt.Printf("Failed to send log: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Failed to send log: %v\n", err)
 			return
 		}
 		defer resp.Body.Close()
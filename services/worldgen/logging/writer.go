@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is the layout of the entries a WriterLogger writes
+type Format string
+
+const (
+	// FormatText writes "time LEVEL message key=value ..." lines
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line with time, level and msg keys
+	FormatJSON Format = "json"
+)
+
+// levelRanks orders the levels from least to most severe
+var levelRanks = map[LogLevel]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// ParseLevel reads a level name such as "info" or "WARN"
+func ParseLevel(name string) (LogLevel, error) {
+	level := LogLevel(strings.ToUpper(strings.TrimSpace(name)))
+	if _, ok := levelRanks[level]; !ok {
+		return "", fmt.Errorf("unknown log level: %q", name)
+	}
+	return level, nil
+}
+
+// ParseFormat reads a format name, "text" or "json"
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format: %q", name)
+	}
+}
+
+// WriterLogger writes entries at or above a level to a writer, one per line,
+// with the fields of the context. It is safe for concurrent use.
+type WriterLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  LogLevel
+	format Format
+}
+
+// NewWriterLogger creates a logger writing entries at level or above to w
+func NewWriterLogger(w io.Writer, level LogLevel, format Format) *WriterLogger {
+	return &WriterLogger{w: w, level: level, format: format}
+}
+
+func (l *WriterLogger) Info(ctx context.Context, msg string) {
+	l.log(ctx, LogLevelInfo, msg)
+}
+
+func (l *WriterLogger) Warn(ctx context.Context, msg string) {
+	l.log(ctx, LogLevelWarn, msg)
+}
+
+func (l *WriterLogger) Error(ctx context.Context, msg string) {
+	l.log(ctx, LogLevelError, msg)
+}
+
+func (l *WriterLogger) Debug(ctx context.Context, msg string) {
+	l.log(ctx, LogLevelDebug, msg)
+}
+
+func (l *WriterLogger) log(ctx context.Context, level LogLevel, msg string) {
+	if levelRanks[level] < levelRanks[l.level] {
+		return
+	}
+	fields := FieldsFrom(ctx)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	var line []byte
+	if l.format == FormatJSON {
+		entry := make(map[string]interface{}, len(fields)+3)
+		for key, value := range fields {
+			entry[key] = value
+		}
+		entry["time"], entry["level"], entry["msg"] = now, string(level), msg
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"time": now, "level": string(level), "msg": msg, "error": err.Error()})
+		}
+		line = append(data, '\n')
+	} else {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s %s", now, level, msg)
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%s", key, textValue(fields[key]))
+		}
+		b.WriteByte('\n')
+		line = []byte(b.String())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
+
+// textValue formats a field value, quoting it when it contains spaces or quotes
+func textValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriterLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf, LogLevelWarn, FormatText)
+	ctx := context.Background()
+
+	logger.Debug(ctx, "debug message")
+	logger.Info(ctx, "info message")
+	logger.Warn(ctx, "warn message")
+	logger.Error(ctx, "error message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries at WARN or above, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "WARN warn message") || !strings.Contains(lines[1], "ERROR error message") {
+		t.Errorf("Unexpected entries: %q", lines)
+	}
+}
+
+func TestWriterLoggerTextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf, LogLevelDebug, FormatText)
+	ctx := WithFields(context.Background(), Fields{"seed": 42})
+	ctx = WithFields(ctx, Fields{"room": "room_1", "prompt": "A dark cave"})
+
+	logger.Info(ctx, "Generated room")
+
+	line := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(line, `INFO Generated room prompt="A dark cave" room=room_1 seed=42`) {
+		t.Errorf("Unexpected entry: %q", line)
+	}
+}
+
+func TestWriterLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf, LogLevelInfo, FormatJSON)
+	ctx := WithFields(context.Background(), Fields{"seed": 42, "attempt": 2})
+
+	logger.Warn(ctx, "Room attempt failed")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Entry is not JSON: %v: %q", err, buf.String())
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "Room attempt failed" {
+		t.Errorf("Unexpected level or message: %v", entry)
+	}
+	if entry["seed"] != float64(42) || entry["attempt"] != float64(2) {
+		t.Errorf("Expected the context fields in the entry, got %v", entry)
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Errorf("Expected a time in the entry, got %v", entry)
+	}
+}
+
+func TestWithFieldsDoesNotChangeParent(t *testing.T) {
+	parent := WithFields(context.Background(), Fields{"seed": 1})
+	child := WithFields(parent, Fields{"seed": 2, "room": "room_1"})
+
+	if got := FieldsFrom(parent); len(got) != 1 || got["seed"] != 1 {
+		t.Errorf("Parent fields changed: %v", got)
+	}
+	if got := FieldsFrom(child); got["seed"] != 2 || got["room"] != "room_1" {
+		t.Errorf("Unexpected child fields: %v", got)
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	if level, err := ParseLevel("warn"); err != nil || level != LogLevelWarn {
+		t.Errorf("ParseLevel(warn) = %q, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if format, err := ParseFormat("JSON"); err != nil || format != FormatJSON {
+		t.Errorf("ParseFormat(JSON) = %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,196 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+// DefaultMaxObjects is the number of objects a generated room holds at most
+const DefaultMaxObjects = 5
+
+// Object property keys set on generated objects
+const (
+	PropPortable  = "portable"
+	PropWeight    = "weight"
+	PropContainer = "container"
+)
+
+// Keyword tables used to guess sensible default properties from an object's name
+var (
+	fixedObjectWords = wordSet("altar", "anvil", "bed", "boulder", "bookshelf", "bookcase", "cabinet", "column",
+		"counter", "desk", "door", "forge", "fountain", "furnace", "gate", "loom", "machine", "organ", "pillar",
+		"pool", "shelf", "shelves", "shrine", "stove", "statue", "table", "throne", "tree", "wardrobe", "well",
+		"window", "workbench")
+	containerObjectWords = wordSet("backpack", "bag", "barrel", "basket", "bottle", "box", "bucket", "cabinet",
+		"casket", "chest", "coffer", "crate", "drawer", "jar", "locker", "pouch", "safe", "sack", "trunk", "urn",
+		"vase", "wardrobe")
+	lightObjectWords = wordSet("amulet", "candle", "card", "coin", "feather", "gem", "key", "letter", "map",
+		"note", "pen", "photo", "photograph", "ring", "scroll", "ticket")
+)
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// normalizeObjectName cleans up an object name returned by a provider,
+// dropping list markers, surrounding punctuation and quotes, and leading
+// articles. Normalizing a name twice gives the same name.
+func normalizeObjectName(name string) string {
+	for {
+		cleaned := trimObjectName(name)
+		if cleaned == name {
+			return name
+		}
+		name = cleaned
+	}
+}
+
+// trimObjectName drops one layer of list marker, punctuation and article
+func trimObjectName(name string) string {
+	name = ai.TrimListMarker(name)
+	name = strings.Trim(strings.TrimSpace(name), ".;:\"'")
+	words := strings.Fields(name)
+	if len(words) > 1 {
+		switch strings.ToLower(words[0]) {
+		case "a", "an", "the", "some":
+			words = words[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// dedupeObjectNames normalizes names and drops empty and repeated ones,
+// comparing without regard to case, and keeps at most max names
+func dedupeObjectNames(names []string, max int) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, name := range names {
+		name = normalizeObjectName(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+		if len(result) == max {
+			break
+		}
+	}
+	return result
+}
+
+// objectID derives a stable object ID from the room and the object name
+func objectID(roomID, name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(roomID))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.ToLower(name)))
+
+	slug := strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), "_")
+	return fmt.Sprintf("obj_%s_%08x", slug, h.Sum32())
+}
+
+// defaultObjectProperties guesses whether an object can be carried, roughly
+// how heavy it is in kilograms and whether it can hold other objects
+func defaultObjectProperties(name string) map[string]interface{} {
+	portable, weight, container := true, 1.0, false
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		word = strings.Trim(word, ",.;:'\"")
+		switch {
+		case fixedObjectWords[word]:
+			portable, weight = false, 100
+		case lightObjectWords[word] && portable:
+			weight = 0.1
+		}
+		if containerObjectWords[word] {
+			container = true
+		}
+	}
+	if container && portable && weight < 1 {
+		weight = 1
+	}
+	return map[string]interface{}{
+		PropPortable:  portable,
+		PropWeight:    weight,
+		PropContainer: container,
+	}
+}
+
+// plainObjectDescription is the sentence used for an object nobody described,
+// such as "An ancient altar." or "Some keys."
+func plainObjectDescription(name string) string {
+	words := strings.Fields(strings.ToLower(name))
+	last := words[len(words)-1]
+	if strings.HasSuffix(last, "s") && !strings.HasSuffix(last, "ss") && !strings.HasSuffix(last, "us") && !strings.HasSuffix(last, "is") {
+		return "Some " + name + "."
+	}
+	if strings.ContainsRune("aeiou", rune(words[0][0])) {
+		return "An " + name + "."
+	}
+	return "A " + name + "."
+}
+
+// generateObjects asks the provider for the objects found in a room, named
+// and described in one call when it can. When the provider fails or returns
+// nothing, objects are drawn from the region's table with rng.
+func (w *World) generateObjects(ctx context.Context, room *Room, region Region, max int, rng *rand.Rand) []Object {
+	var names []string
+	descriptions := make(map[string]string)
+	if provider := w.aiProvider(); provider != nil {
+		var err error
+		if described, ok := provider.(ai.ObjectSpecProvider); ok {
+			start := time.Now()
+			var specs []ai.ObjectSpec
+			specs, err = described.GenerateObjectSpecs(ctx, room.Description)
+			w.logCall(ctx, "generate object specs", start, err)
+			for _, spec := range specs {
+				name := normalizeObjectName(spec.Name)
+				if _, seen := descriptions[strings.ToLower(name)]; !seen {
+					descriptions[strings.ToLower(name)] = spec.Description
+				}
+				names = append(names, name)
+			}
+		}
+		// Providers that cannot describe objects only name them
+		if len(names) == 0 && (err == nil || errors.Is(err, ai.ErrStructuredUnsupported)) {
+			start := time.Now()
+			names, err = provider.GenerateObjects(ctx, room.Description)
+			w.logCall(ctx, "generate objects", start, err)
+		}
+	}
+
+	names = dedupeObjectNames(names, max)
+	if len(names) == 0 && len(region.Objects) > 0 {
+		table := append([]string(nil), region.Objects...)
+		rng.Shuffle(len(table), func(i, j int) { table[i], table[j] = table[j], table[i] })
+		names = dedupeObjectNames(table[:1+rng.Intn(len(table))], max)
+	}
+
+	objects := make([]Object, 0, len(names))
+	for _, name := range names {
+		description := descriptions[strings.ToLower(name)]
+		if description == "" {
+			description = plainObjectDescription(name)
+		}
+		objects = append(objects, Object{
+			ID:          objectID(room.ID, name),
+			Name:        name,
+			Description: description,
+			Properties:  defaultObjectProperties(name),
+		})
+	}
+	return objects
+}
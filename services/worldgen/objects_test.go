@@ -0,0 +1,214 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+// objectlessProvider describes rooms but fails to list objects
+type objectlessProvider struct {
+	countingProvider
+}
+
+func (p *objectlessProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	return nil, errors.New("object generation unavailable")
+}
+
+// describingProvider names and describes objects in one call
+type describingProvider struct {
+	countingProvider
+	specCalls int
+}
+
+func (p *describingProvider) GenerateObjectSpecs(ctx context.Context, sceneDescription string) ([]ai.ObjectSpec, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.specCalls++
+	return []ai.ObjectSpec{
+		{Name: "1. brass key", Description: "Green with age."},
+		{Name: "urn", Description: "It rattles when shaken."},
+		{Name: "Brass key", Description: "A second key."},
+	}, nil
+}
+
+func TestDedupeObjectNames(t *testing.T) {
+	names := []string{"1. A rusty key", "- rusty key", "The Lantern.", "", "  lantern ", "old map", "coin", "rope", "bell"}
+	got := dedupeObjectNames(names, 4)
+	want := []string{"rusty key", "Lantern", "old map", "coin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNormalizeObjectNameIsStable(t *testing.T) {
+	tests := map[string]string{
+		"the 'lamp'":            "lamp",
+		`1. The "brass key".`:   "brass key",
+		"the the lamp":          "lamp",
+		"- a 'coil of rope'":    "coil of rope",
+		"  Some  wild berries ": "wild berries",
+	}
+	for input, want := range tests {
+		got := normalizeObjectName(input)
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", input, want, got)
+		}
+		if again := normalizeObjectName(got); again != got {
+			t.Errorf("%q: normalizing again changed %q to %q", input, got, again)
+		}
+	}
+}
+
+func TestDefaultObjectProperties(t *testing.T) {
+	tests := []struct {
+		name      string
+		portable  bool
+		container bool
+	}{
+		{"marble statue", false, false},
+		{"oak wardrobe", false, true},
+		{"leather pouch", true, true},
+		{"brass key", true, false},
+		{"lantern", true, false},
+	}
+	for _, tt := range tests {
+		props := defaultObjectProperties(tt.name)
+		if props[PropPortable] != tt.portable {
+			t.Errorf("%s: expected portable %v, got %v", tt.name, tt.portable, props[PropPortable])
+		}
+		if props[PropContainer] != tt.container {
+			t.Errorf("%s: expected container %v, got %v", tt.name, tt.container, props[PropContainer])
+		}
+		if _, ok := props[PropWeight].(float64); !ok {
+			t.Errorf("%s: expected a numeric weight, got %v", tt.name, props[PropWeight])
+		}
+	}
+	if defaultObjectProperties("brass key")[PropWeight].(float64) >= defaultObjectProperties("marble statue")[PropWeight].(float64) {
+		t.Error("Expected a key to weigh less than a statue")
+	}
+}
+
+func TestPlainObjectDescription(t *testing.T) {
+	tests := map[string]string{
+		"rusty key":     "A rusty key.",
+		"ancient altar": "An ancient altar.",
+		"keys":          "Some keys.",
+		"wild berries":  "Some wild berries.",
+		"brass compass": "A brass compass.",
+		"cactus":        "A cactus.",
+	}
+	for name, want := range tests {
+		if got := plainObjectDescription(name); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestObjectIDIsStable(t *testing.T) {
+	if objectID("room_1", "Rusty Key") != objectID("room_1", "rusty key") {
+		t.Error("Expected object IDs to ignore case")
+	}
+	if objectID("room_1", "rusty key") == objectID("room_2", "rusty key") {
+		t.Error("Expected the same object in different rooms to get different IDs")
+	}
+}
+
+func TestGenerateWorldPopulatesObjects(t *testing.T) {
+	world, err := NewWorld(12)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&countingProvider{})
+	if err := world.GenerateWorld("An abandoned observatory", 4); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	for _, room := range world.Rooms {
+		if len(room.Objects) == 0 {
+			t.Fatalf("Expected room %s to contain objects", room.ID)
+		}
+		for _, object := range room.Objects {
+			if object.ID == "" || object.Name == "" || object.Description == "" {
+				t.Errorf("Expected a complete object, got %+v", object)
+			}
+			if object.ID != objectID(room.ID, object.Name) {
+				t.Errorf("Expected stable object ID, got %s", object.ID)
+			}
+			if _, ok := object.Properties[PropPortable]; !ok {
+				t.Errorf("Expected default properties on %s", object.Name)
+			}
+		}
+	}
+}
+
+func TestGenerateObjectsDescribesInOneCall(t *testing.T) {
+	world, err := NewWorld(5)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	provider := &describingProvider{}
+	world.SetProvider(provider)
+
+	room := &Room{ID: "room_1", Description: "A dusty crypt"}
+	objects := world.generateObjects(context.Background(), room, Region{}, DefaultMaxObjects, world.rng)
+	if provider.specCalls != 1 || provider.calls != 0 {
+		t.Errorf("Expected a single call for names and descriptions, got %d spec calls and %d other calls", provider.specCalls, provider.calls)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("Expected 2 distinct objects, got %+v", objects)
+	}
+	if objects[0].Name != "brass key" || objects[0].Description != "Green with age." {
+		t.Errorf("Expected the first brass key and its description, got %+v", objects[0])
+	}
+
+	// Providers that only name objects are not asked to describe each one
+	counting := &countingProvider{}
+	world.SetProvider(counting)
+	objects = world.generateObjects(context.Background(), room, Region{}, DefaultMaxObjects, world.rng)
+	if counting.calls != 1 {
+		t.Errorf("Expected one call to list the objects, got %d", counting.calls)
+	}
+	if len(objects) != 1 || objects[0].Description != "A lamp 1." {
+		t.Errorf("Expected a plain description, got %+v", objects)
+	}
+}
+
+func TestGenerateObjectsFallsBackToRegionTable(t *testing.T) {
+	world, err := NewWorld(3)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&objectlessProvider{})
+
+	opts := DefaultGenerateOptions()
+	opts.Regions = []Region{{Name: "Crypt", Prompt: "cold stone", Objects: []string{"skull", "candle", "iron chest"}}}
+	if err := world.GenerateWorldWithOptions(context.Background(), "A ruined abbey", 3, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	table := map[string]bool{"skull": true, "candle": true, "iron chest": true}
+	for _, room := range world.Rooms {
+		if len(room.Objects) == 0 {
+			t.Errorf("Expected room %s to be filled from the region table", room.ID)
+		}
+		for _, object := range room.Objects {
+			if !table[object.Name] {
+				t.Errorf("Unexpected object %q", object.Name)
+			}
+		}
+	}
+
+	opts.MaxObjects = -1
+	if err := world.GenerateWorldWithOptions(context.Background(), "A ruined abbey", 3, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+	for _, room := range world.Rooms {
+		if len(room.Objects) != 0 {
+			t.Errorf("Expected no objects when disabled, got %d", len(room.Objects))
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"textadventureservices/services/usage"
+	"textadventureservices/services/worldgen/logging"
+)
+
+// DefaultConcurrency is the number of rooms generated at once when
+// GenerateOptions.Concurrency is 0
+const DefaultConcurrency = 4
+
+// DefaultRoomRetries is how often a room is retried when GenerateOptions.Retries is 0
+const DefaultRoomRetries = 2
+
+// DefaultRetryDelay is the wait before the first retry of a room. It doubles
+// with every further retry.
+const DefaultRetryDelay = 500 * time.Millisecond
+
+// Limiter paces requests to the AI provider. The AI service's ai.RateLimiter
+// is one.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Progress reports on a room of a world being generated. It is sent after
+// every attempt at a room, successful or not.
+type Progress struct {
+	// Done counts the rooms generated so far, out of Total
+	Done  int
+	Total int
+	// RoomID is the room the attempt was for
+	RoomID  string
+	Attempt int
+	// Err is why the attempt failed. The room is retried unless it was the
+	// last attempt, in which case generation stops.
+	Err error
+}
+
+// roomJob is a laid out room waiting to be generated
+type roomJob struct {
+	id     string
+	prompt string
+	exits  []string
+	coord  Coord
+	region string
+}
+
+// generateRooms generates the rooms of jobs with up to opts.Concurrency
+// workers and returns them in the order of jobs, whatever order they finish
+// in. Every attempt at a room first waits for opts.RateLimiter, and failed
+// attempts are retried after a growing delay. The first room that cannot be
+// generated cancels the others.
+func (w *World) generateRooms(ctx context.Context, jobs []roomJob, opts GenerateOptions) ([]*Room, error) {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	workers = min(workers, len(jobs))
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Progress is reported under a lock, so the callback need not be safe
+	// for concurrent use and Done only ever grows
+	var mu sync.Mutex
+	done := 0
+	report := func(job roomJob, attempt int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			done++
+		}
+		if opts.Progress != nil {
+			opts.Progress(Progress{Done: done, Total: len(jobs), RoomID: job.id, Attempt: attempt, Err: err})
+		}
+	}
+
+	rooms := make([]*Room, len(jobs))
+	errs := make([]error, len(jobs))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				rooms[index], errs[index] = w.generateJob(workCtx, jobs[index], opts, report)
+				if errs[index] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+feed:
+	for index := range jobs {
+		select {
+		case indices <- index:
+		case <-workCtx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	// Rooms cancelled because another room failed are not the cause
+	for index, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("room %s: %w", jobs[index].id, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// generateJob generates a room, retrying failed attempts up to opts.Retries times
+func (w *World) generateJob(ctx context.Context, job roomJob, opts GenerateOptions, report func(roomJob, int, error)) (*Room, error) {
+	retries := opts.Retries
+	if retries == 0 {
+		retries = DefaultRoomRetries
+	}
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	for attempt := 1; ; attempt++ {
+		last := attempt > retries
+		room, err := w.attemptJob(logging.WithFields(ctx, logging.Fields{"room": job.id, "attempt": attempt}), job, opts, last)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		report(job, attempt, err)
+		if err == nil {
+			return room, nil
+		}
+		// Retrying cannot help a session that is out of budget
+		if last || errors.Is(err, usage.ErrBudgetExceeded) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// attemptJob makes one attempt at generating a room. Only the last attempt
+// falls back to the prompt when the provider fails to describe the room.
+func (w *World) attemptJob(ctx context.Context, job roomJob, opts GenerateOptions, last bool) (*Room, error) {
+	if opts.RateLimiter != nil {
+		if err := opts.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	w.log().Debug(logging.WithFields(ctx, logging.Fields{"coord": job.coord.String(), "prompt": job.prompt}), "Generating room")
+	return w.generatePlacedRoom(ctx, job, opts.MaxObjects, opts.Structured, last)
+}
+
+// roomRand returns the generator for the random choices made while a room is
+// generated. It depends only on the seed and the room ID, so rooms come out
+// the same whatever order they are generated in.
+func (w *World) roomRand(id string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return rand.New(rand.NewSource(w.Seed ^ int64(h.Sum64())))
+}
@@ -0,0 +1,217 @@
+package worldgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+// slowProvider answers after a delay that depends on the prompt, so rooms
+// finish out of order, and records how many calls ran at once
+type slowProvider struct {
+	ai.MockProvider
+	mu       sync.Mutex
+	running  int
+	busiest  int
+	failures map[string]int
+}
+
+func (p *slowProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.running++
+	p.busiest = max(p.busiest, p.running)
+	fail := p.failures[prompt] > 0
+	if fail {
+		p.failures[prompt]--
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	select {
+	case <-time.After(time.Duration(h.Sum32()%5) * time.Millisecond):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if fail {
+		return "", errors.New("model overloaded")
+	}
+	return p.MockProvider.GenerateDescription(ctx, prompt)
+}
+
+// countingLimiter lets a fixed number of requests through
+type countingLimiter struct {
+	mu    sync.Mutex
+	left  int
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waits++
+	if l.left == 0 {
+		return errors.New("rate limit exceeded")
+	}
+	l.left--
+	return nil
+}
+
+func generateConcurrently(t *testing.T, provider ai.Provider, opts GenerateOptions) (*World, error) {
+	t.Helper()
+	world, err := NewWorld(21)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(provider)
+	opts.Directions = CardinalDirections
+	opts.RetryDelay = time.Millisecond
+	return world, world.GenerateWorldWithOptions(context.Background(), "A clockwork city", 12, opts)
+}
+
+func TestConcurrentGenerationIsDeterministic(t *testing.T) {
+	var worlds [][]byte
+	for _, concurrency := range []int{1, 3, 12} {
+		provider := &slowProvider{}
+		opts := DefaultGenerateOptions()
+		opts.Concurrency = concurrency
+		opts.Regions = []Region{{Name: "Works", Prompt: "grinding gears", Objects: []string{"cog", "spring", "lever"}}}
+		world, err := generateConcurrently(t, provider, opts)
+		if err != nil {
+			t.Fatalf("Generation with %d workers failed: %v", concurrency, err)
+		}
+		if provider.busiest > concurrency {
+			t.Errorf("Expected at most %d rooms at once, got %d", concurrency, provider.busiest)
+		}
+		data, err := json.Marshal(world)
+		if err != nil {
+			t.Fatalf("Failed to marshal world: %v", err)
+		}
+		worlds = append(worlds, data)
+	}
+
+	for i := 1; i < len(worlds); i++ {
+		if string(worlds[i]) != string(worlds[0]) {
+			t.Errorf("Expected the same world whatever the concurrency\nfirst: %s\ngot:   %s", worlds[0], worlds[i])
+		}
+	}
+}
+
+func TestConcurrentGenerationRetriesRooms(t *testing.T) {
+	provider := &slowProvider{failures: map[string]int{"A clockwork city - Central Hub": 2}}
+	var reports []Progress
+	opts := DefaultGenerateOptions()
+	opts.Progress = func(p Progress) { reports = append(reports, p) }
+	world, err := generateConcurrently(t, provider, opts)
+	if err != nil {
+		t.Fatalf("Generation failed: %v", err)
+	}
+
+	start := world.Rooms[world.Start]
+	if !strings.HasPrefix(start.Description, "You find yourself in") {
+		t.Errorf("Expected the start room to be described by the provider after retrying, got %q", start.Description)
+	}
+	failed, done := 0, 0
+	for _, report := range reports {
+		if report.Total != 12 {
+			t.Errorf("Expected a total of 12 rooms, got %d", report.Total)
+		}
+		if report.Done < done {
+			t.Errorf("Expected the done count to grow, got %d after %d", report.Done, done)
+		}
+		done = report.Done
+		if report.Err != nil {
+			failed++
+			if report.RoomID != world.Start {
+				t.Errorf("Expected only the start room to fail, got %s", report.RoomID)
+			}
+		} else if report.RoomID == world.Start && report.Attempt != 3 {
+			t.Errorf("Expected the start room to succeed on attempt 3, got %d", report.Attempt)
+		}
+	}
+	if failed != 2 || done != 12 {
+		t.Errorf("Expected 2 failed attempts and 12 rooms done, got %d and %d", failed, done)
+	}
+
+	// Without retries the only attempt falls back to the prompt
+	provider = &slowProvider{failures: map[string]int{"A clockwork city - Central Hub": 1}}
+	opts = DefaultGenerateOptions()
+	opts.Retries = -1
+	world, err = generateConcurrently(t, provider, opts)
+	if err != nil {
+		t.Fatalf("Generation failed: %v", err)
+	}
+	if got := world.Rooms[world.Start].Description; got != "A clockwork city - Central Hub" {
+		t.Errorf("Expected the last attempt to fall back to the prompt, got %q", got)
+	}
+}
+
+func TestConcurrentGenerationHonoursRateLimiter(t *testing.T) {
+	limiter := &countingLimiter{left: 12}
+	opts := DefaultGenerateOptions()
+	opts.RateLimiter = limiter
+	if _, err := generateConcurrently(t, &slowProvider{}, opts); err != nil {
+		t.Fatalf("Generation failed: %v", err)
+	}
+	if limiter.waits != 12 {
+		t.Errorf("Expected one wait per room, got %d", limiter.waits)
+	}
+
+	limiter = &countingLimiter{left: 5}
+	opts.RateLimiter = limiter
+	opts.Retries = 1
+	_, err := generateConcurrently(t, &slowProvider{}, opts)
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		t.Errorf("Expected the rate limit to stop generation, got %v", err)
+	}
+}
+
+func TestConcurrentGenerationStopsOnCancel(t *testing.T) {
+	world, err := NewWorld(21)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&slowProvider{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := DefaultGenerateOptions()
+	opts.Progress = func(p Progress) {
+		if p.Done == 2 {
+			cancel()
+		}
+	}
+	err = world.GenerateWorldWithOptions(ctx, "A clockwork city", 30, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected generation to be cancelled, got %v", err)
+	}
+	if len(world.Rooms) != 0 {
+		t.Errorf("Expected no rooms after cancelling, got %d", len(world.Rooms))
+	}
+}
+
+func TestRoomRandDependsOnlyOnRoom(t *testing.T) {
+	world, err := NewWorld(5)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	first := world.roomRand("room_1").Int63()
+	world.roomRand("room_2").Int63()
+	if again := world.roomRand("room_1").Int63(); again != first {
+		t.Errorf("Expected the same draws for the same room, got %d and %d", first, again)
+	}
+	if other := world.roomRand("room_2").Int63(); other == first {
+		t.Errorf("Expected different draws for different rooms, got %d twice", first)
+	}
+}
@@ -0,0 +1,230 @@
+package worldgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Region is a themed zone of a world, such as "Harbor District" or "Catacombs"
+type Region struct {
+	Name string `json:"name"`
+	// Prompt is added to the base prompt for every room in the region
+	Prompt string `json:"prompt"`
+	// Moods are picked from at random to vary the rooms of the region
+	Moods []string `json:"moods,omitempty"`
+	// Objects lists things typically found in the region
+	Objects []string `json:"objects,omitempty"`
+}
+
+// LoadRegions reads a list of regions from a JSON file
+func LoadRegions(filename string) ([]Region, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regions: %w", err)
+	}
+
+	var regions []Region
+	if err := json.Unmarshal(data, &regions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal regions: %w", err)
+	}
+	return regions, validateRegions(regions)
+}
+
+// validateRegions checks that every region has a unique name
+func validateRegions(regions []Region) error {
+	seen := make(map[string]bool)
+	for _, region := range regions {
+		if region.Name == "" {
+			return fmt.Errorf("region name cannot be empty")
+		}
+		if seen[region.Name] {
+			return fmt.Errorf("duplicate region name: %q", region.Name)
+		}
+		seen[region.Name] = true
+	}
+	return nil
+}
+
+// Region returns a region of the world by name
+func (w *World) Region(name string) (Region, bool) {
+	for _, region := range w.Regions {
+		if region.Name == name {
+			return region, true
+		}
+	}
+	return Region{}, false
+}
+
+// assignRegions splits a layout into contiguous regions and keeps a single
+// gateway between neighbouring regions.
+//
+// Region centres are spread out by picking, one after another, the node
+// farthest from the centres chosen so far. Regions then grow outwards from
+// their centres, the smallest region first, so they end up similar in size.
+// Finally, passages between regions are pruned so that regions form a tree
+// and every transition is a deliberate chokepoint.
+func assignRegions(layout *Layout, regions []Region) {
+	if len(regions) == 0 || layout.Len() == 0 {
+		return
+	}
+	if len(regions) > layout.Len() {
+		regions = regions[:layout.Len()]
+	}
+
+	adjacent := make([][]int, layout.Len())
+	for _, edge := range layout.Edges {
+		adjacent[edge.From] = append(adjacent[edge.From], edge.To)
+		adjacent[edge.To] = append(adjacent[edge.To], edge.From)
+	}
+
+	// Pick spread out centres
+	centres := []int{0}
+	distance := layoutDistances(adjacent, 0)
+	for len(centres) < len(regions) {
+		farthest := -1
+		for index, d := range distance {
+			if d > 0 && (farthest < 0 || d > distance[farthest]) {
+				farthest = index
+			}
+		}
+		if farthest < 0 {
+			break
+		}
+		centres = append(centres, farthest)
+		for index, d := range layoutDistances(adjacent, farthest) {
+			if d < distance[index] {
+				distance[index] = d
+			}
+		}
+	}
+
+	// Grow the regions one room at a time, smallest region first
+	owner := make([]int, layout.Len())
+	for index := range owner {
+		owner[index] = -1
+	}
+	frontiers := make([][]int, len(centres))
+	sizes := make([]int, len(centres))
+	for region, centre := range centres {
+		owner[centre] = region
+		frontiers[region] = []int{centre}
+		sizes[region] = 1
+	}
+	for {
+		region := -1
+		for r := range frontiers {
+			if len(frontiers[r]) > 0 && (region < 0 || sizes[r] < sizes[region]) {
+				region = r
+			}
+		}
+		if region < 0 {
+			break
+		}
+
+		claimed := false
+		for !claimed && len(frontiers[region]) > 0 {
+			current := frontiers[region][0]
+			for _, next := range adjacent[current] {
+				if owner[next] < 0 {
+					owner[next] = region
+					sizes[region]++
+					frontiers[region] = append(frontiers[region], next)
+					claimed = true
+					break
+				}
+			}
+			if !claimed {
+				frontiers[region] = frontiers[region][1:]
+			}
+		}
+	}
+
+	for index := range layout.Nodes {
+		// Rooms the regions could not reach belong to the first region
+		if owner[index] < 0 {
+			owner[index] = 0
+		}
+		layout.Nodes[index].Region = regions[owner[index]].Name
+	}
+
+	// Keep passages inside regions and one gateway per pair of regions that
+	// joins two parts of the world not linked yet
+	linked := make([]int, len(centres))
+	for index := range linked {
+		linked[index] = index
+	}
+	var find func(int) int
+	find = func(r int) int {
+		if linked[r] != r {
+			linked[r] = find(linked[r])
+		}
+		return linked[r]
+	}
+	layout.retainEdges(func(edge LayoutEdge) bool {
+		from, to := find(owner[edge.From]), find(owner[edge.To])
+		if owner[edge.From] == owner[edge.To] {
+			return true
+		}
+		if from == to {
+			return false
+		}
+		linked[from] = to
+		return true
+	})
+}
+
+// layoutDistances returns the number of steps from start to every node, or -1 when unreachable
+func layoutDistances(adjacent [][]int, start int) []int {
+	distance := make([]int, len(adjacent))
+	for index := range distance {
+		distance[index] = -1
+	}
+	distance[start] = 0
+	for queue := []int{start}; len(queue) > 0; queue = queue[1:] {
+		for _, next := range adjacent[queue[0]] {
+			if distance[next] < 0 {
+				distance[next] = distance[queue[0]] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distance
+}
+
+// retainEdges drops every edge for which keep returns false
+func (l *Layout) retainEdges(keep func(LayoutEdge) bool) {
+	edges := l.Edges
+	l.Edges = nil
+	l.exits = make(map[int]map[Direction]int)
+	for _, edge := range edges {
+		if keep(edge) {
+			// The edge was valid before, so it cannot fail now
+			_ = l.Connect(edge.From, edge.To, edge.Direction)
+		}
+	}
+}
+
+// regionContext describes a room's region for its prompt. borders lists the
+// regions the room leads into, mood is the picked mood, if any.
+func regionContext(region Region, mood string, borders []string) string {
+	var parts []string
+	where := fmt.Sprintf("in the %s", region.Name)
+	if region.Prompt != "" {
+		where = fmt.Sprintf("%s: %s", where, region.Prompt)
+	}
+	parts = append(parts, where)
+	if mood != "" {
+		parts = append(parts, fmt.Sprintf("Mood: %s", mood))
+	}
+	if len(region.Objects) > 0 {
+		parts = append(parts, fmt.Sprintf("Typical objects: %s", strings.Join(region.Objects, ", ")))
+	}
+	if len(borders) > 0 {
+		sort.Strings(borders)
+		parts = append(parts, fmt.Sprintf("A passage here leads into the %s", strings.Join(borders, " and the ")))
+	}
+	return strings.Join(parts, ". ")
+}
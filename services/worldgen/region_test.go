@@ -0,0 +1,123 @@
+package worldgen
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testRegions = []Region{
+	{Name: "Harbor District", Prompt: "salt-stained docks and warehouses", Moods: []string{"bustling", "foggy"}, Objects: []string{"fishing net", "crate"}},
+	{Name: "Catacombs", Prompt: "bone-lined tunnels under the city", Moods: []string{"silent", "dread"}},
+	{Name: "Old Town", Prompt: "crooked streets and shuttered shops"},
+}
+
+func TestAssignRegionsIsContiguousWithSingleGateways(t *testing.T) {
+	layout, err := growLayout(rand.New(rand.NewSource(8)), 45, CardinalDirections, 0.5)
+	if err != nil {
+		t.Fatalf("Failed to grow layout: %v", err)
+	}
+	assignRegions(layout, testRegions)
+
+	if !layoutConnected(layout) {
+		t.Fatal("Expected the world to stay connected after pruning region borders")
+	}
+
+	sizes := make(map[string]int)
+	for _, node := range layout.Nodes {
+		sizes[node.Region]++
+	}
+	for _, region := range testRegions {
+		if sizes[region.Name] == 0 {
+			t.Errorf("Region %s has no rooms", region.Name)
+		}
+	}
+
+	gateways := 0
+	for _, edge := range layout.Edges {
+		if layout.Nodes[edge.From].Region != layout.Nodes[edge.To].Region {
+			gateways++
+		}
+	}
+	if gateways != len(testRegions)-1 {
+		t.Errorf("Expected %d gateways between regions, got %d", len(testRegions)-1, gateways)
+	}
+
+	// Every region must be walkable without leaving it
+	for _, region := range testRegions {
+		var members []int
+		for index, node := range layout.Nodes {
+			if node.Region == region.Name {
+				members = append(members, index)
+			}
+		}
+		adjacent := make([][]int, layout.Len())
+		for _, edge := range layout.Edges {
+			if layout.Nodes[edge.From].Region == region.Name && layout.Nodes[edge.To].Region == region.Name {
+				adjacent[edge.From] = append(adjacent[edge.From], edge.To)
+				adjacent[edge.To] = append(adjacent[edge.To], edge.From)
+			}
+		}
+		distance := layoutDistances(adjacent, members[0])
+		for _, member := range members {
+			if distance[member] < 0 {
+				t.Errorf("Region %s is split: node %d cannot be reached from node %d", region.Name, member, members[0])
+			}
+		}
+	}
+}
+
+func TestGenerateWorldWithRegions(t *testing.T) {
+	world, err := NewWorld(21)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&countingProvider{})
+
+	opts := DefaultGenerateOptions()
+	opts.Regions = testRegions
+	if err := world.GenerateWorldWithOptions(context.Background(), "A port city", 12, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	borderRooms := 0
+	for _, room := range world.Rooms {
+		region, ok := world.Region(room.Region)
+		if !ok {
+			t.Fatalf("Room %s has unknown region %q", room.ID, room.Region)
+		}
+		if !strings.Contains(room.Description, region.Prompt) {
+			t.Errorf("Expected the prompt of room %s to include its region context, got %q", room.ID, room.Description)
+		}
+		if strings.Contains(room.Description, "A passage here leads into") {
+			borderRooms++
+		}
+	}
+	if borderRooms == 0 {
+		t.Error("Expected gateway rooms to mention the neighbouring region")
+	}
+
+	opts.Regions = []Region{{Name: "Twin"}, {Name: "Twin"}}
+	if err := world.GenerateWorldWithOptions(context.Background(), "A port city", 4, opts); err == nil {
+		t.Error("Expected error for duplicate region names")
+	}
+}
+
+func TestLoadRegions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regions.json")
+	data := `[{"name": "Catacombs", "prompt": "bone-lined tunnels", "moods": ["silent"], "objects": ["skull"]}]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write regions: %v", err)
+	}
+
+	regions, err := LoadRegions(path)
+	if err != nil {
+		t.Fatalf("Failed to load regions: %v", err)
+	}
+	if len(regions) != 1 || regions[0].Name != "Catacombs" || regions[0].Objects[0] != "skull" {
+		t.Errorf("Unexpected regions: %+v", regions)
+	}
+}
@@ -0,0 +1,558 @@
+package worldgen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderFormat names an output format of World.Render
+type RenderFormat string
+
+const (
+	// RenderASCII draws each level of the world as a text grid
+	RenderASCII RenderFormat = "ascii"
+	// RenderDOT writes a Graphviz graph of the exits
+	RenderDOT RenderFormat = "dot"
+	// RenderSVG draws each level of the world as a self-contained SVG image
+	RenderSVG RenderFormat = "svg"
+)
+
+// RenderFormats lists the supported render formats
+var RenderFormats = []RenderFormat{RenderASCII, RenderDOT, RenderSVG}
+
+// Render draws a map of the whole world in the given format
+func (w *World) Render(format RenderFormat) ([]byte, error) {
+	return w.RenderRooms(format, w.roomIDs())
+}
+
+// RenderRooms draws a map of only the given rooms, such as the part of the
+// world a player has explored. Exits into other rooms are left out.
+func (w *World) RenderRooms(format RenderFormat, ids []string) ([]byte, error) {
+	subset := &World{Rooms: make(map[string]*Room, len(ids)), Start: w.Start, Regions: w.Regions}
+	for _, id := range ids {
+		room, ok := w.GetRoom(id)
+		if !ok || room == nil {
+			return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
+		}
+		subset.Rooms[id] = room
+	}
+
+	switch format {
+	case RenderASCII:
+		return subset.renderASCII(), nil
+	case RenderDOT:
+		return subset.renderDOT(), nil
+	case RenderSVG:
+		return subset.renderSVG(), nil
+	default:
+		return nil, fmt.Errorf("unknown render format %q", format)
+	}
+}
+
+// mapExit is an exit as drawn on a map. An exit with a matching exit back
+// through the opposite direction is drawn once, as a two-way exit.
+type mapExit struct {
+	from, to  string
+	direction Direction
+	// back is the direction of the exit back, or empty for a one-way exit
+	back Direction
+}
+
+// mapExits returns the exits between the world's rooms, merging two-way exits
+func (w *World) mapExits() []mapExit {
+	edges := w.edges()
+	var exits []mapExit
+	for _, id := range w.roomIDs() {
+		for _, edge := range edges[id] {
+			exit := mapExit{from: id, to: edge.target, direction: edge.direction}
+			opposite := edge.direction.GetOppositeDirection()
+			if opposite != "" && edge.target != id && w.Rooms[edge.target].Exits[string(opposite)] == id {
+				if edge.target < id {
+					continue // Drawn from the other room
+				}
+				exit.back = opposite
+			}
+			exits = append(exits, exit)
+		}
+	}
+	return exits
+}
+
+// mapPositions places every room on the grid for drawing. Rooms keep their
+// Coord; the others are placed next to a neighbour in the direction of the
+// exit between them, or to the east of the rooms placed so far.
+func (w *World) mapPositions() map[string]Coord {
+	ids := w.roomIDs()
+	positions := make(map[string]Coord, len(ids))
+	occupied := make(map[Coord]bool)
+	place := func(id string, c Coord) {
+		positions[id] = c
+		occupied[c] = true
+	}
+	for _, id := range ids {
+		if room := w.Rooms[id]; room != nil && room.Coord != nil && !occupied[*room.Coord] {
+			place(id, *room.Coord)
+		}
+	}
+
+	type neighbour struct {
+		id     string
+		offset Offset
+	}
+	edges := w.edges()
+	neighbours := make(map[string][]neighbour)
+	for _, id := range ids {
+		for _, edge := range edges[id] {
+			if offset := edge.direction.Offset(); !offset.IsZero() {
+				neighbours[id] = append(neighbours[id], neighbour{id: edge.target, offset: offset})
+				neighbours[edge.target] = append(neighbours[edge.target], neighbour{id: id, offset: offset.Negate()})
+			}
+		}
+	}
+
+	for {
+		var queue []string
+		for _, id := range ids {
+			if _, ok := positions[id]; ok {
+				queue = append(queue, id)
+			}
+		}
+		for ; len(queue) > 0; queue = queue[1:] {
+			for _, next := range neighbours[queue[0]] {
+				if _, ok := positions[next.id]; ok {
+					continue
+				}
+				if c := positions[queue[0]].Move(next.offset); !occupied[c] {
+					place(next.id, c)
+					queue = append(queue, next.id)
+				}
+			}
+		}
+
+		unplaced := ""
+		if _, ok := w.Rooms[w.Start]; ok && len(positions) == 0 {
+			unplaced = w.Start
+		}
+		for _, id := range ids {
+			if _, ok := positions[id]; !ok && unplaced == "" {
+				unplaced = id
+			}
+		}
+		if unplaced == "" {
+			return positions
+		}
+		c := Coord{}
+		for id := range positions {
+			c.X = max(c.X, positions[id].X+2)
+		}
+		place(unplaced, c)
+	}
+}
+
+// mapLevels returns the Z levels used by the positions, from the top down
+func mapLevels(positions map[string]Coord) []int {
+	seen := make(map[int]bool)
+	var levels []int
+	for _, c := range positions {
+		if !seen[c.Z] {
+			seen[c.Z] = true
+			levels = append(levels, c.Z)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+	return levels
+}
+
+// mapBounds returns the smallest and largest X and Y used on a level
+func mapBounds(positions map[string]Coord, z int) (minX, maxX, minY, maxY int) {
+	first := true
+	for _, c := range positions {
+		if c.Z != z {
+			continue
+		}
+		if first {
+			minX, maxX, minY, maxY = c.X, c.X, c.Y, c.Y
+			first = false
+		}
+		minX, maxX = min(minX, c.X), max(maxX, c.X)
+		minY, maxY = min(minY, c.Y), max(maxY, c.Y)
+	}
+	return minX, maxX, minY, maxY
+}
+
+// adjacent reports whether an exit's direction matches the positions of its rooms
+func (e mapExit) adjacent(positions map[string]Coord) bool {
+	from, to := positions[e.from], positions[e.to]
+	offset := e.direction.Offset()
+	return !offset.IsZero() && from.Move(offset) == to
+}
+
+// roomLabel returns the room's name, or its ID when it has none
+func (w *World) roomLabel(id string) string {
+	if room := w.Rooms[id]; room != nil && room.Name != "" {
+		return room.Name
+	}
+	return id
+}
+
+// roomTitle returns the room's label followed by its region, if any
+func (w *World) roomTitle(id string) string {
+	if room := w.Rooms[id]; room != nil && room.Region != "" {
+		return fmt.Sprintf("%s (%s)", w.roomLabel(id), room.Region)
+	}
+	return w.roomLabel(id)
+}
+
+// roomRegions returns the names of the regions the rooms belong to, in sorted order
+func (w *World) roomRegions() []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, room := range w.Rooms {
+		if room != nil && room.Region != "" && !seen[room.Region] {
+			seen[room.Region] = true
+			regions = append(regions, room.Region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// truncate shortens text to at most n runes, ending with "~" when cut
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimRight(string(runes[:n-1]), " ") + "~"
+}
+
+// Cells of the ASCII map are "[key label^v]" with a gap for exits between them
+const (
+	asciiLabelWidth = 8
+	asciiGapWidth   = 3
+)
+
+// renderASCII draws each level as a grid of rooms joined by "---", "|", "/"
+// and "\". "^" and "v" mark exits up and down. Each room is numbered and
+// listed under the grid with its full name, along with any exits that cannot
+// be drawn on the grid.
+func (w *World) renderASCII() []byte {
+	positions := w.mapPositions()
+	exits := w.mapExits()
+
+	type link struct{ a, b Coord }
+	links := make(map[link]bool)
+	linked := func(a, b Coord) bool { return links[link{a, b}] || links[link{b, a}] }
+	marks := make(map[string]map[rune]bool)
+	mark := func(id string, direction Direction) {
+		if marks[id] == nil {
+			marks[id] = make(map[rune]bool)
+		}
+		if z := direction.Offset().Z; z > 0 {
+			marks[id]['^'] = true
+		} else if z < 0 {
+			marks[id]['v'] = true
+		}
+	}
+	others := make(map[string][]string)
+	for _, exit := range exits {
+		from, to := positions[exit.from], positions[exit.to]
+		switch {
+		case exit.adjacent(positions) && from.Z == to.Z:
+			links[link{from, to}] = true
+		case exit.adjacent(positions):
+			mark(exit.from, exit.direction)
+			if exit.back != "" {
+				mark(exit.to, exit.back)
+			}
+		default:
+			others[exit.from] = append(others[exit.from], fmt.Sprintf("%s -> %s", exit.direction, w.roomLabel(exit.to)))
+			if exit.back != "" {
+				others[exit.to] = append(others[exit.to], fmt.Sprintf("%s -> %s", exit.back, w.roomLabel(exit.from)))
+			}
+		}
+	}
+
+	rooms := make(map[Coord]string, len(positions))
+	for id, c := range positions {
+		rooms[c] = id
+	}
+
+	// Rooms are numbered in map order, top level first, and keyed under each level
+	levels := mapLevels(positions)
+	keys := make(map[string]int, len(positions))
+	for _, z := range levels {
+		minX, maxX, minY, maxY := mapBounds(positions, z)
+		for y := maxY; y >= minY; y-- {
+			for x := minX; x <= maxX; x++ {
+				if id, ok := rooms[Coord{X: x, Y: y, Z: z}]; ok {
+					keys[id] = len(keys) + 1
+				}
+			}
+		}
+	}
+	keyWidth := len(strconv.Itoa(len(keys)))
+	cellWidth := keyWidth + asciiLabelWidth + 5
+	cell := func(id string) string {
+		label := []rune(truncate(w.roomLabel(id), asciiLabelWidth))
+		label = append(label, []rune(strings.Repeat(" ", asciiLabelWidth-len(label)))...)
+		up, down := ' ', ' '
+		if marks[id]['^'] {
+			up = '^'
+		}
+		if marks[id]['v'] {
+			down = 'v'
+		}
+		return fmt.Sprintf("[%*d %s%c%c]", keyWidth, keys[id], string(label), up, down)
+	}
+
+	var b strings.Builder
+	for i, z := range levels {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		name := levelName(z)
+		fmt.Fprintf(&b, "%s%s\n\n", strings.ToUpper(name[:1]), name[1:])
+
+		minX, maxX, minY, maxY := mapBounds(positions, z)
+		var legend []string
+		for y := maxY; y >= minY; y-- {
+			var row, below strings.Builder
+			for x := minX; x <= maxX; x++ {
+				here := Coord{X: x, Y: y, Z: z}
+				if id, ok := rooms[here]; ok {
+					row.WriteString(cell(id))
+					legend = append(legend, id)
+				} else {
+					row.WriteString(strings.Repeat(" ", cellWidth))
+				}
+				south := here.Move(Offset{Y: -1})
+				if linked(here, south) {
+					below.WriteString(strings.Repeat(" ", cellWidth/2) + "|" + strings.Repeat(" ", cellWidth-cellWidth/2-1))
+				} else {
+					below.WriteString(strings.Repeat(" ", cellWidth))
+				}
+				if x == maxX {
+					break
+				}
+
+				east := here.Move(Offset{X: 1})
+				if linked(here, east) {
+					row.WriteString(strings.Repeat("-", asciiGapWidth))
+				} else {
+					row.WriteString(strings.Repeat(" ", asciiGapWidth))
+				}
+				diagonal := " "
+				falling := linked(here, east.Move(Offset{Y: -1}))
+				rising := linked(south, east)
+				switch {
+				case falling && rising:
+					diagonal = "X"
+				case falling:
+					diagonal = "\\"
+				case rising:
+					diagonal = "/"
+				}
+				below.WriteString(" " + diagonal + " ")
+			}
+			b.WriteString(strings.TrimRight(row.String(), " ") + "\n")
+			if y > minY {
+				b.WriteString(strings.TrimRight(below.String(), " ") + "\n")
+			}
+		}
+
+		b.WriteString("\n")
+		for _, id := range legend {
+			title := w.roomTitle(id)
+			if w.roomLabel(id) != id {
+				title += " [" + id + "]"
+			}
+			fmt.Fprintf(&b, "%*d  %s\n", keyWidth, keys[id], title)
+			for _, exit := range others[id] {
+				fmt.Fprintf(&b, "%s  %s\n", strings.Repeat(" ", keyWidth), exit)
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+// renderDOT writes a Graphviz digraph with a cluster per region. Two-way
+// exits are drawn as one edge with arrows at both ends.
+func (w *World) renderDOT() []byte {
+	var b bytes.Buffer
+	b.WriteString("digraph world {\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+
+	node := func(indent, id string) {
+		attrs := fmt.Sprintf("label=%s", dotQuote(w.roomLabel(id)))
+		if id == w.Start {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", indent, dotQuote(id), attrs)
+	}
+
+	byRegion := make(map[string][]string)
+	for _, id := range w.roomIDs() {
+		byRegion[w.Rooms[id].Region] = append(byRegion[w.Rooms[id].Region], id)
+	}
+	for i, region := range w.roomRegions() {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(region))
+		for _, id := range byRegion[region] {
+			node("    ", id)
+		}
+		b.WriteString("  }\n")
+	}
+	for _, id := range byRegion[""] {
+		node("  ", id)
+	}
+
+	for _, exit := range w.mapExits() {
+		if exit.back != "" {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s, dir=both];\n", dotQuote(exit.from), dotQuote(exit.to),
+				dotQuote(fmt.Sprintf("%s / %s", exit.direction, exit.back)))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(exit.from), dotQuote(exit.to), dotQuote(string(exit.direction)))
+		}
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// dotQuote quotes a string as a DOT identifier
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// Sizes of the SVG map in pixels
+const (
+	svgCellWidth  = 150
+	svgCellHeight = 56
+	svgGapX       = 70
+	svgGapY       = 50
+	svgMargin     = 20
+	svgTitle      = 30
+)
+
+// svgFills are the room colours, one per region in sorted order
+var svgFills = []string{"#dbeafe", "#dcfce7", "#fef3c7", "#fce7f3", "#ede9fe", "#ffedd5", "#ccfbf1", "#e5e7eb"}
+
+// renderSVG draws each level as a panel of rooms coloured by region, joined
+// by lines labelled with the exit directions. One-way exits have an arrow.
+// Exits to other levels or without a position are listed inside the room.
+func (w *World) renderSVG() []byte {
+	positions := w.mapPositions()
+	levels := mapLevels(positions)
+
+	regionFill := make(map[string]string)
+	for i, region := range w.roomRegions() {
+		regionFill[region] = svgFills[i%len(svgFills)]
+	}
+
+	// Lay the levels out top to bottom
+	type panel struct{ minX, maxY, top int }
+	panels := make(map[int]panel)
+	width, height := 0, svgMargin
+	for _, z := range levels {
+		minX, maxX, minY, maxY := mapBounds(positions, z)
+		panels[z] = panel{minX: minX, maxY: maxY, top: height + svgTitle}
+		cols, rows := maxX-minX+1, maxY-minY+1
+		width = max(width, cols*(svgCellWidth+svgGapX)-svgGapX)
+		height += svgTitle + rows*(svgCellHeight+svgGapY) - svgGapY + svgMargin
+	}
+	width += 2 * svgMargin
+	origin := func(id string) (int, int) {
+		c := positions[id]
+		p := panels[c.Z]
+		return svgMargin + (c.X-p.minX)*(svgCellWidth+svgGapX), p.top + (p.maxY-c.Y)*(svgCellHeight+svgGapY)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#374151"/></marker></defs>` + "\n")
+	b.WriteString(`<rect width="100%" height="100%" fill="white"/>` + "\n")
+
+	for _, z := range levels {
+		name := levelName(z)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" font-weight="bold">%s</text>`+"\n", svgMargin, panels[z].top-10, svgEscape(strings.ToUpper(name[:1])+name[1:]))
+	}
+
+	others := make(map[string][]string)
+	for _, exit := range w.mapExits() {
+		from, to := positions[exit.from], positions[exit.to]
+		if exit.from == exit.to || from.Z != to.Z {
+			others[exit.from] = append(others[exit.from], string(exit.direction))
+			if exit.back != "" {
+				others[exit.to] = append(others[exit.to], string(exit.back))
+			}
+			continue
+		}
+
+		// Run the line between the edges of the two boxes
+		x1, y1 := origin(exit.from)
+		x2, y2 := origin(exit.to)
+		dx, dy := float64(x2-x1), float64(y2-y1)
+		t := 1.0
+		if dx != 0 {
+			t = min(t, svgCellWidth/2/math.Abs(dx))
+		}
+		if dy != 0 {
+			t = min(t, svgCellHeight/2/math.Abs(dy))
+		}
+		cx1, cy1 := float64(x1+svgCellWidth/2), float64(y1+svgCellHeight/2)
+		cx2, cy2 := float64(x2+svgCellWidth/2), float64(y2+svgCellHeight/2)
+		sx, sy, ex, ey := cx1+t*dx, cy1+t*dy, cx2-t*dx, cy2-t*dy
+
+		label := string(exit.direction)
+		marker := ` marker-end="url(#arrow)"`
+		if exit.back != "" {
+			label = fmt.Sprintf("%s / %s", exit.direction, exit.back)
+			marker = ""
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#374151" stroke-width="1.5"%s/>`+"\n", sx, sy, ex, ey, marker)
+		// Labels sit above horizontal and diagonal lines and beside vertical ones
+		lx, ly, anchor := (sx+ex)/2, (sy+ey)/2-4, "middle"
+		if dx == 0 {
+			lx, ly, anchor = lx+4, ly+7, "start"
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="%s" fill="#374151">%s</text>`+"\n", lx, ly, anchor, svgEscape(label))
+	}
+
+	for _, id := range w.roomIDs() {
+		x, y := origin(id)
+		room := w.Rooms[id]
+		fill := "#f3f4f6"
+		if room.Region != "" {
+			fill = regionFill[room.Region]
+		}
+		strokeWidth := 1
+		if id == w.Start {
+			strokeWidth = 3
+		}
+		fmt.Fprintf(&b, `<g id="room-%s">`+"\n", svgEscape(id))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="#111827" stroke-width="%d"/>`+"\n", x, y, svgCellWidth, svgCellHeight, fill, strokeWidth)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", x+8, y+18, svgEscape(truncate(w.roomLabel(id), 22)))
+		if room.Region != "" {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" font-style="italic">%s</text>`+"\n", x+8, y+33, svgEscape(truncate(room.Region, 26)))
+		}
+		if len(others[id]) > 0 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10">%s</text>`+"\n", x+8, y+48, svgEscape(truncate("exits: "+strings.Join(others[id], ", "), 28)))
+		}
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// svgEscape escapes text for use in SVG content and attributes
+func svgEscape(s string) string {
+	return html.EscapeString(s)
+}
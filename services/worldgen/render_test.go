@@ -0,0 +1,153 @@
+package worldgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// renderWorld builds a manor with two levels, a region and exits of every kind
+func renderWorld(t *testing.T) *World {
+	t.Helper()
+	world, err := NewWorld(1)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	add := func(id, name, region string, coord *Coord) {
+		room := namedScene(id, name, "The "+name+".")
+		room.Region = region
+		room.Coord = coord
+		if err := world.AddRoom(room); err != nil {
+			t.Fatalf("Failed to add room: %v", err)
+		}
+	}
+	add("hall", "Great Hall", "Manor", &Coord{})
+	add("kitchen", "Kitchen", "Manor", &Coord{X: 1})
+	add("garden", "Rose Garden", "Grounds", &Coord{Y: 1})
+	add("attic", "Attic", "Manor", &Coord{Z: 1})
+	add("pantry", "Pantry & Stores", "Manor", nil)
+	add("well", "Old Well", "Grounds", nil)
+	world.Start = "hall"
+
+	world.ConnectRooms("hall", "kitchen", East)
+	world.ConnectRooms("hall", "garden", North)
+	world.ConnectRooms("hall", "attic", Up)
+	world.ConnectRooms("kitchen", "pantry", In)
+	world.ConnectOneWay("garden", "well", East)
+	return world
+}
+
+func TestRenderASCII(t *testing.T) {
+	data, err := renderWorld(t).Render(RenderASCII)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	want := `Floor 1 above ground
+
+[1 Attic    v]
+
+1  Attic (Manor) [attic]
+
+Ground floor
+
+[2 Rose Ga~  ]---[3 Old Well  ]
+       |
+[4 Great H~^ ]---[5 Kitchen   ]                    [6 Pantry~   ]
+
+2  Rose Garden (Grounds) [garden]
+3  Old Well (Grounds) [well]
+4  Great Hall (Manor) [hall]
+5  Kitchen (Manor) [kitchen]
+   in -> Pantry & Stores
+6  Pantry & Stores (Manor) [pantry]
+   out -> Kitchen
+`
+	if got := string(data); got != want {
+		t.Errorf("Unexpected map:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	data, err := renderWorld(t).Render(RenderDOT)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	dot := string(data)
+
+	for _, want := range []string{
+		"digraph world {",
+		`label="Grounds";`,
+		`"hall" [label="Great Hall", peripheries=2];`,
+		`"hall" -> "kitchen" [label="east / west", dir=both];`,
+		`"garden" -> "well" [label="east"];`,
+		`"attic" -> "hall" [label="down / up", dir=both];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT output to contain %s, got:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, `"well" -> "garden"`) {
+		t.Errorf("One-way exit should have no edge back:\n%s", dot)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	data, err := renderWorld(t).Render(RenderSVG)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// The image must be well-formed XML with no external references
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("SVG is not well-formed: %v\n%s", err, data)
+		}
+	}
+
+	svg := string(data)
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"`,
+		"Floor 1 above ground",
+		"Pantry &amp; Stores",
+		"east / west",
+		`marker-end="url(#arrow)"`, // garden to well is one-way
+		"exits: up",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("Expected SVG to contain %s", want)
+		}
+	}
+	if strings.Contains(svg, "href") {
+		t.Error("SVG should be self-contained")
+	}
+}
+
+func TestRenderRooms(t *testing.T) {
+	world := renderWorld(t)
+
+	data, err := world.RenderRooms(RenderDOT, []string{"hall", "garden"})
+	if err != nil {
+		t.Fatalf("RenderRooms failed: %v", err)
+	}
+	dot := string(data)
+	if !strings.Contains(dot, `"garden" -> "hall" [label="south / north", dir=both];`) {
+		t.Errorf("Expected the exit between explored rooms, got:\n%s", dot)
+	}
+	if strings.Contains(dot, "kitchen") || strings.Contains(dot, "well") {
+		t.Errorf("Unexplored rooms should not be drawn, got:\n%s", dot)
+	}
+
+	if _, err := world.RenderRooms(RenderSVG, []string{"hall", "missing"}); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+	if _, err := world.Render("png"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -1,91 +1,31 @@
 package worldgen
 
 import (
-	"bufio"
-	"context"
 	"fmt"
-	"os"
-	"strings"
-	"time"
-
-	"textadventureservices/services/worldgen/ai"
+	"hash/fnv"
 )
 
-var aiProvider ai.Provider
-var roomCounter int64
-
-func init() {
-	// Read OpenAI API key from .env.openai file
-	file, err := os.Open(".env.openai")
-	if err != nil {
-		fmt.Printf("Warning: Failed to open .env.openai: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	var apiKey string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "OPENAI_API_KEY=") {
-			apiKey = strings.TrimPrefix(line, "OPENAI_API_KEY=")
-			break
-		}
-	}
-
-	if apiKey == "" {
-		fmt.Println("Warning: OPENAI_API_KEY not found in .env.openai")
-		return
-	}
-
-	// Initialize OpenAI provider with configuration
-	providerCfg := ai.ProviderConfig{
-		Type:     ai.ProviderOpenAI,
-		Model:    "gpt-4o",
-		APIKey:   apiKey,
-		Endpoint: "https://api.openai.com/v1",
-	}
-
-	provider, err := ai.NewProvider(providerCfg)
-	if err != nil {
-		fmt.Printf("Warning: Failed to create AI provider: %v\n", err)
-		return
-	}
-
-	if err := provider.Initialize(providerCfg); err != nil {
-		fmt.Printf("Warning: Failed to initialize AI provider: %v\n", err)
-		return
-	}
-
-	aiProvider = provider
+// GenerateRoom creates a new room with the given description and exits.
+// The room ID is derived from the description and exits, so the same input
+// always yields the same ID; use World.NewRoom for rooms that belong to a world
+// and Generator.GenerateRoom to have the description enhanced by a provider.
+func GenerateRoom(description string, exits []string) (*Room, error) {
+	return newContentRoom(description, description, exits)
 }
 
-// GenerateRoom creates a new room with the given description and exits
-func GenerateRoom(description string, exits []string) (*Room, error) {
+// newContentRoom creates a room whose ID is derived from the original description and exits
+func newContentRoom(description, enhancedDesc string, exits []string) (*Room, error) {
 	if description == "" {
 		return nil, fmt.Errorf("room description cannot be empty")
 	}
 
-	// Enhance the description using AI if available
-	enhancedDesc := description
-	if aiProvider != nil {
-		desc, err := aiProvider.GenerateDescription(context.Background(), description)
-		if err != nil {
-			fmt.Printf("Warning: Failed to enhance description: %v\n", err)
-		} else {
-			enhancedDesc = desc
-		}
-	}
-
-	// Create a map of exits, but don't fill in the target IDs yet
 	exitMap := make(map[string]string)
 	for _, exit := range exits {
-		exitMap[exit] = "" // Leave target ID empty, to be filled in later
+		exitMap[exit] = UnexploredExit // Filled in once the room behind it exists
 	}
 
-	roomCounter++
 	room := &Room{
-		ID:          fmt.Sprintf("room_%d_%d", time.Now().UnixNano(), roomCounter),
+		ID:          contentRoomID(description, exits),
 		Description: enhancedDesc,
 		Objects:     make([]Object, 0),
 		Exits:       exitMap,
@@ -95,11 +35,30 @@ func GenerateRoom(description string, exits []string) (*Room, error) {
 	return room, nil
 }
 
+// contentRoomID derives a stable room ID from the room's original description and exits
+func contentRoomID(description string, exits []string) string {
+	h := fnv.New64a()
+	h.Write([]byte(description))
+	for _, exit := range exits {
+		h.Write([]byte{0})
+		h.Write([]byte(exit))
+	}
+	return fmt.Sprintf("room_%016x", h.Sum64())
+}
+
 // AddObject adds an object to the room
 func (r *Room) AddObject(obj Object) {
 	r.Objects = append(r.Objects, obj)
 }
 
+// GetObject returns an object by its index
+func (r *Room) GetObject(index int) (Object, bool) {
+	if index < 0 || index >= len(r.Objects) {
+		return Object{}, false
+	}
+	return r.Objects[index], true
+}
+
 // AddExit adds an exit to the room
 func (r *Room) AddExit(direction, targetID string) {
 	if r.Exits == nil {
@@ -108,13 +67,14 @@ func (r *Room) AddExit(direction, targetID string) {
 	r.Exits[direction] = targetID
 }
 
-// GetExit returns the target room ID for a given direction
+// GetExit returns the target room ID for a given direction. Exits whose room
+// is not connected or unexplored have no target.
 func (r *Room) GetExit(direction string) (string, bool) {
 	if r.Exits == nil {
 		return "", false
 	}
 	id, ok := r.Exits[direction]
-	if !ok || id == "" {
+	if !ok || id == "" || id == UnexploredExit {
 		return "", false
 	}
 	return id, true
@@ -128,6 +88,11 @@ func (r *Room) SetProperty(key string, value interface{}) {
 	r.Properties[key] = value
 }
 
+// AddProperty sets a property value for the room, like SetProperty
+func (r *Room) AddProperty(key string, value interface{}) {
+	r.SetProperty(key, value)
+}
+
 // GetProperty gets a property value from the room
 func (r *Room) GetProperty(key string) (interface{}, bool) {
 	if r.Properties == nil {
@@ -6,18 +6,13 @@ import (
 	"os"
 )
 
-// Scene represents a location in the game world
-type Scene struct {
-	ID          string                 `json:"id"`
-	Description string                 `json:"description"`
-	Objects     []Object               `json:"objects"`
-	Exits       map[string]string      `json:"exits"`
-	Properties  map[string]interface{} `json:"properties"`
-}
+// Scene is the former name of Room. Both names refer to the same type, so a
+// scene and a room can never disagree.
+type Scene = Room
 
-// NewScene creates a new scene with the given properties
+// NewScene creates an empty location with the given ID and description
 func NewScene(id, description string) *Scene {
-	return &Scene{
+	return &Room{
 		ID:          id,
 		Description: description,
 		Objects:     make([]Object, 0),
@@ -26,66 +21,46 @@ func NewScene(id, description string) *Scene {
 	}
 }
 
-// AddObject adds an object to the scene
-func (s *Scene) AddObject(object Object) {
-	s.Objects = append(s.Objects, object)
+// Scenes returns the world's locations keyed by ID. It is the Rooms map
+// itself, not a copy, for callers that still think in scenes.
+func (w *World) Scenes() map[string]*Scene {
+	return w.Rooms
 }
 
-// GetObject returns an object by its index
-func (s *Scene) GetObject(index int) (Object, bool) {
-	if index < 0 || index >= len(s.Objects) {
-		return Object{}, false
+// GetScene returns a location by its ID, or an error wrapping ErrRoomNotFound
+func (w *World) GetScene(id string) (*Scene, error) {
+	room, ok := w.GetRoom(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
 	}
-	return s.Objects[index], true
-}
-
-// AddExit adds an exit to the scene
-func (s *Scene) AddExit(direction string, destination string) {
-	s.Exits[direction] = destination
-}
-
-// GetExit returns the destination of an exit
-func (s *Scene) GetExit(direction string) (string, bool) {
-	destination, ok := s.Exits[direction]
-	return destination, ok
-}
-
-// AddProperty adds a property to the scene
-func (s *Scene) AddProperty(key string, value interface{}) {
-	s.Properties[key] = value
-}
-
-// GetProperty returns the value of a property
-func (s *Scene) GetProperty(key string) (interface{}, bool) {
-	value, ok := s.Properties[key]
-	return value, ok
+	return room, nil
 }
 
-// Save writes the scene to a JSON file
-func (s *Scene) Save(filename string) error {
-	data, err := json.MarshalIndent(s, "", "  ")
+// Save writes the room to a JSON file
+func (r *Room) Save(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal scene: %w", err)
+		return fmt.Errorf("failed to marshal room: %w", err)
 	}
 
 	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write scene file: %w", err)
+		return fmt.Errorf("failed to write room file: %w", err)
 	}
 
 	return nil
 }
 
-// LoadScene reads a scene from a JSON file
+// LoadScene reads a single room from a JSON file
 func LoadScene(filename string) (*Scene, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read scene file: %w", err)
+		return nil, fmt.Errorf("failed to read room file: %w", err)
 	}
 
-	var scene Scene
-	if err := json.Unmarshal(data, &scene); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal scene: %w", err)
+	var room Room
+	if err := json.Unmarshal(data, &room); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal room: %w", err)
 	}
 
-	return &scene, nil
+	return &room, nil
 }
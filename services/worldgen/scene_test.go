@@ -1,28 +1,36 @@
 package worldgen
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// namedScene creates a scene with a name, as rooms read from files have
+func namedScene(id, name, description string) *Scene {
+	scene := NewScene(id, description)
+	scene.Name = name
+	return scene
+}
+
 func TestSceneCreation(t *testing.T) {
 	tests := []struct {
 		name        string
 		id          string
-		sceneName   string
 		description string
 		wantErr     bool
 	}{
 		{
 			name:        "Valid scene creation",
 			id:          "scene_1",
-			sceneName:   "Forest Clearing",
 			description: "A peaceful forest clearing with sunlight filtering through the trees.",
 			wantErr:     false,
 		},
 		{
 			name:        "Another valid scene",
 			id:          "scene_2",
-			sceneName:   "Dark Cave",
 			description: "A dark cave with mysterious echoes.",
 			wantErr:     false,
 		},
@@ -30,7 +38,7 @@ func TestSceneCreation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			scene := NewScene(tt.id, tt.sceneName, tt.description)
+			scene := NewScene(tt.id, tt.description)
 			if scene == nil && !tt.wantErr {
 				t.Errorf("NewScene() returned nil, wanted non-nil")
 			}
@@ -39,9 +47,6 @@ func TestSceneCreation(t *testing.T) {
 				if scene.ID != tt.id {
 					t.Errorf("Scene.ID = %v, want %v", scene.ID, tt.id)
 				}
-				if scene.Name != tt.sceneName {
-					t.Errorf("Scene.Name = %v, want %v", scene.Name, tt.sceneName)
-				}
 				if scene.Description != tt.description {
 					t.Errorf("Scene.Description = %v, want %v", scene.Description, tt.description)
 				}
@@ -76,7 +81,10 @@ func TestRoomConnections(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			scene := NewScene("test_scene", "Test Scene", "A test scene")
+			world, err := NewWorld(1)
+			if err != nil {
+				t.Fatalf("Failed to create world: %v", err)
+			}
 
 			// Create the rooms
 			from, err := GenerateRoom(tt.fromDesc, nil)
@@ -89,9 +97,13 @@ func TestRoomConnections(t *testing.T) {
 				t.Fatalf("Failed to create to room: %v", err)
 			}
 
-			// Add rooms to scene
-			scene.AddRoom(from)
-			scene.AddRoom(to)
+			// Add rooms to world
+			if err := world.AddRoom(from); err != nil {
+				t.Fatalf("Failed to add room: %v", err)
+			}
+			if err := world.AddRoom(to); err != nil {
+				t.Fatalf("Failed to add room: %v", err)
+			}
 
 			// Debug info
 			t.Logf("From room ID: %s", from.ID)
@@ -99,7 +111,7 @@ func TestRoomConnections(t *testing.T) {
 			t.Logf("Direction: %s", tt.direction)
 
 			// Connect the rooms
-			err = scene.ConnectRooms(from.ID, to.ID, tt.direction)
+			err = world.ConnectRooms(from.ID, to.ID, tt.direction)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ConnectRooms() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -136,3 +148,69 @@ func TestRoomConnections(t *testing.T) {
 		})
 	}
 }
+
+func TestScenesAreRooms(t *testing.T) {
+	world, err := NewWorld(1)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	room := namedScene("room_1", "Hall", "A long hall.")
+	if err := world.AddRoom(room); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+
+	// Changes through either name are visible through the other
+	scene, err := world.GetScene("room_1")
+	if err != nil {
+		t.Fatalf("GetScene failed: %v", err)
+	}
+	scene.AddObject(Object{ID: "obj_1", Name: "lamp"})
+	if got, _ := world.GetRoom("room_1"); len(got.Objects) != 1 || len(world.Scenes()["room_1"].Objects) != 1 {
+		t.Errorf("Expected the object to be visible through rooms and scenes")
+	}
+
+	if _, err := world.GetScene("missing"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+	if err := world.ConnectRooms("room_1", "missing", North); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+}
+
+func TestLoadWorldWithLegacyScenes(t *testing.T) {
+	// Older files stored every room twice; the scenes copy lacks newer fields
+	legacy := `{
+  "seed": 3,
+  "rooms": {
+    "room_1": {"id": "room_1", "name": "Hall", "description": "A long hall.", "objects": [], "exits": {"north": "room_2"}, "properties": {}}
+  },
+  "scenes": {
+    "room_1": {"id": "room_1", "description": "A stale copy.", "objects": [], "exits": {}, "properties": {}},
+    "room_2": {"id": "room_2", "description": "A quiet study.", "objects": [], "exits": {"south": "room_1"}, "properties": {}}
+  }
+}`
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write world: %v", err)
+	}
+
+	world, err := LoadWorld(path)
+	if err != nil {
+		t.Fatalf("LoadWorld failed: %v", err)
+	}
+	if len(world.Rooms) != 2 {
+		t.Fatalf("Expected both rooms, got %d", len(world.Rooms))
+	}
+	if world.Rooms["room_1"].Description != "A long hall." || world.Rooms["room_2"].Description != "A quiet study." {
+		t.Errorf("Expected rooms to win over stale scenes, got %+v", world.Rooms)
+	}
+
+	// Saving writes the rooms only
+	if err := world.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `"scenes"`) {
+		t.Errorf("Expected saved world without a scenes map")
+	}
+}
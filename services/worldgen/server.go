@@ -0,0 +1,148 @@
+package worldgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment, so proxies
+// do not close it while a slow room is generated
+const sseKeepAlive = 15 * time.Second
+
+// JobHandler serves generation jobs over HTTP:
+//
+//	POST   /api/v1/generate-world   start a job; answers 202 with the job
+//	GET    /api/v1/jobs/{id}        the job, with the world once completed
+//	DELETE /api/v1/jobs/{id}        cancel the job
+//	GET    /api/v1/jobs/{id}/events the job's events as Server-Sent Events
+type JobHandler struct {
+	jobs *JobManager
+}
+
+// NewJobHandler creates a handler for the jobs of a job manager
+func NewJobHandler(jobs *JobManager) *JobHandler {
+	return &JobHandler{jobs: jobs}
+}
+
+// RegisterRoutes adds the job routes to a mux
+func (h *JobHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/generate-world", h.HandleStart)
+	mux.HandleFunc("/api/v1/jobs/", h.HandleJob)
+}
+
+// HandleStart starts a job for the GenerateRequest in the body
+func (h *JobHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	job, err := h.jobs.Start(req)
+	if errors.Is(err, ErrInvalidRequest) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrTooManyJobs) {
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID())
+	writeJSON(w, http.StatusAccepted, job.Snapshot())
+}
+
+// HandleJob serves a job and its events
+func (h *JobHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/"), "/")
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, job.Snapshot())
+	case rest == "" && r.Method == http.MethodDelete:
+		if _, err := h.jobs.Cancel(id); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job.Snapshot())
+	case rest == "events" && r.Method == http.MethodGet:
+		h.streamEvents(w, r, job)
+	case rest == "" || rest == "events":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// streamEvents sends the job's events as Server-Sent Events until the job
+// finishes or the client goes away. Clients that reconnect with a
+// Last-Event-ID header only get the events they missed.
+func (h *JobHandler) streamEvents(w http.ResponseWriter, r *http.Request, job *Job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		events, changed, finished := job.Events(after)
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			after = event.ID
+		}
+		flusher.Flush()
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
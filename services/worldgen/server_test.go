@@ -0,0 +1,327 @@
+package worldgen
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"textadventureservices/services/usage"
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/config"
+	"textadventureservices/services/worldgen/logging"
+)
+
+// blockingProvider describes rooms only once released, or fails when the
+// request is cancelled
+type blockingProvider struct {
+	ai.MockProvider
+	release chan struct{}
+}
+
+func (p *blockingProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	select {
+	case <-p.release:
+		return p.MockProvider.GenerateDescription(ctx, prompt)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func newJobServer(t *testing.T, provider ai.Provider) *httptest.Server {
+	t.Helper()
+	service := &Service{provider: provider, config: config.DefaultConfig(), logger: logging.NewNoopLogger()}
+	mux := http.NewServeMux()
+	NewJobHandler(NewJobManager(service)).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func startJob(t *testing.T, server *httptest.Server, body string) JobSnapshot {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/api/v1/generate-world", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+	var job JobSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if resp.Header.Get("Location") != "/api/v1/jobs/"+job.ID {
+		t.Errorf("Expected the job's location, got %q", resp.Header.Get("Location"))
+	}
+	return job
+}
+
+// readEvents reads a job's event stream until the server ends it
+func readEvents(t *testing.T, server *httptest.Server, id string, lastEventID string) []JobEvent {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/jobs/"+id+"/events", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", got)
+	}
+
+	var events []JobEvent
+	var eventType string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var event JobEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("Invalid event data %q: %v", line, err)
+			}
+			if event.Type != eventType {
+				t.Errorf("Expected event %s to carry its type, got %s", eventType, event.Type)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func getJob(t *testing.T, server *httptest.Server, id string) (int, JobSnapshot) {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/api/v1/jobs/" + id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	defer resp.Body.Close()
+	var job JobSnapshot
+	json.NewDecoder(resp.Body).Decode(&job)
+	return resp.StatusCode, job
+}
+
+func TestJobStreamsRoomEvents(t *testing.T) {
+	server := newJobServer(t, ai.NewMockProvider())
+	job := startJob(t, server, `{"prompt": "A sunken library", "num_rooms": 4, "seed": 7}`)
+	if job.Status != JobRunning || job.Total != 4 || job.Seed != 7 {
+		t.Errorf("Expected a running job for 4 rooms with seed 7, got %+v", job)
+	}
+
+	events := readEvents(t, server, job.ID, "")
+	var types []string
+	for i, event := range events {
+		types = append(types, event.Type)
+		if event.ID != i+1 {
+			t.Errorf("Expected event %d to have ID %d, got %d", i, i+1, event.ID)
+		}
+	}
+	want := "started room room room room completed"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("Expected events %q, got %q", want, got)
+	}
+	if last := events[len(events)-1]; last.Done != 4 || last.Total != 4 {
+		t.Errorf("Expected 4 of 4 rooms done, got %d of %d", last.Done, last.Total)
+	}
+
+	status, snapshot := getJob(t, server, job.ID)
+	if status != http.StatusOK || snapshot.Status != JobCompleted || snapshot.FinishedAt == nil {
+		t.Fatalf("Expected a completed job, got %d %+v", status, snapshot)
+	}
+	if snapshot.World == nil || len(snapshot.World.Rooms) != 4 || snapshot.World.Seed != 7 {
+		t.Errorf("Expected the job to hold the generated world, got %+v", snapshot.World)
+	}
+
+	// Reconnecting clients only get the events they missed
+	missed := readEvents(t, server, job.ID, "4")
+	if len(missed) != 2 || missed[0].ID != 5 || missed[1].Type != EventCompleted {
+		t.Errorf("Expected events 5 and 6 after reconnecting, got %+v", missed)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	server := newJobServer(t, provider)
+	job := startJob(t, server, `{"prompt": "A frozen keep", "num_rooms": 6}`)
+
+	req, err := http.NewRequest(http.MethodDelete, server.URL+"/api/v1/jobs/"+job.ID, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to cancel job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	events := readEvents(t, server, job.ID, "")
+	if last := events[len(events)-1]; last.Type != EventCancelled {
+		t.Errorf("Expected the stream to end with a cancelled event, got %+v", last)
+	}
+	status, snapshot := getJob(t, server, job.ID)
+	if status != http.StatusOK || snapshot.Status != JobCancelled || snapshot.World != nil {
+		t.Errorf("Expected a cancelled job without a world, got %d %+v", status, snapshot)
+	}
+
+	// Finished jobs cannot be cancelled again
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to cancel job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestJobRequestErrors(t *testing.T) {
+	server := newJobServer(t, ai.NewMockProvider())
+	for _, body := range []string{`{}`, `{"prompt": "A cave", "num_rooms": -1}`, `{"prompt": "A cave", "topology": "spiral"}`, `not json`} {
+		resp, err := http.Post(server.URL+"/api/v1/generate-world", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+
+	if status, _ := getJob(t, server, "job_missing"); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown job, got %d", status)
+	}
+	resp, err := http.Get(server.URL + "/api/v1/generate-world")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestTooManyJobs(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	service := &Service{provider: provider, config: config.DefaultConfig(), logger: logging.NewNoopLogger()}
+	jobs := NewJobManager(service)
+	jobs.maxRunning = 1
+	mux := http.NewServeMux()
+	NewJobHandler(jobs).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	job := startJob(t, server, `{"prompt": "A frozen keep", "num_rooms": 2}`)
+	resp, err := http.Post(server.URL+"/api/v1/generate-world", "application/json", strings.NewReader(`{"prompt": "A frozen keep"}`))
+	if err != nil {
+		t.Fatalf("Failed to post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 while a job is running, got %d", resp.StatusCode)
+	}
+
+	// Finished jobs no longer count towards the limit
+	close(provider.release)
+	readEvents(t, server, job.ID, "")
+	startJob(t, server, `{"prompt": "A frozen keep", "num_rooms": 2}`)
+}
+
+func TestJobFinishKeepsFirstOutcome(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{status: JobRunning, changed: make(chan struct{})}
+
+	// A cancel arriving after the world was generated does not discard it
+	cancel()
+	world := &World{}
+	job.finish(ctx, world, nil)
+	job.finish(ctx, nil, context.Canceled)
+	snapshot := job.Snapshot()
+	if snapshot.Status != JobCompleted || snapshot.World != world {
+		t.Errorf("Expected the job to stay completed, got %+v", snapshot)
+	}
+	if events, _, _ := job.Events(0); len(events) != 1 || events[0].Type != EventCompleted {
+		t.Errorf("Expected a single completed event, got %+v", events)
+	}
+}
+
+func TestJobEventsWaitForProgress(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	jobs := NewJobManager(&Service{provider: provider, config: config.DefaultConfig(), logger: logging.NewNoopLogger()})
+	job, err := jobs.Start(GenerateRequest{Prompt: "A frozen keep", NumRooms: 2})
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	events, changed, finished := job.Events(0)
+	if len(events) != 1 || finished {
+		t.Fatalf("Expected only the started event, got %+v", events)
+	}
+	close(provider.release)
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected to be told about the next event")
+	}
+	for !finished {
+		_, changed, finished = job.Events(0)
+		if !finished {
+			<-changed
+		}
+	}
+	if job.Snapshot().Status != JobCompleted {
+		t.Errorf("Expected the job to complete, got %+v", job.Snapshot())
+	}
+}
+
+func TestJobStopsAtSessionBudget(t *testing.T) {
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"A cold hall."},"done":true,"prompt_eval_count":40,"eval_count":10}`)
+	}))
+	defer model.Close()
+
+	tracker := usage.NewTracker(usage.Config{SessionBudget: usage.Budget{Tokens: 200}})
+	provider, err := ai.Open(ai.ProviderConfig{Type: ai.ProviderOllama, Endpoint: model.URL, Model: "llama3", Tracker: tracker})
+	if err != nil {
+		t.Fatalf("Failed to open provider: %v", err)
+	}
+	service := &Service{provider: provider, config: config.DefaultConfig(), logger: logging.NewNoopLogger(), tracker: tracker}
+	mux := http.NewServeMux()
+	NewJobHandler(NewJobManager(service)).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	job := startJob(t, server, `{"prompt": "A frozen keep", "num_rooms": 20, "session": "player-1"}`)
+	events := readEvents(t, server, job.ID, "")
+	if last := events[len(events)-1]; last.Type != EventFailed || !strings.Contains(last.Error, "session budget exceeded") {
+		t.Errorf("Expected the job to fail on the budget, got %+v", last)
+	}
+
+	_, snapshot := getJob(t, server, job.ID)
+	if snapshot.Session != "player-1" || snapshot.Usage.TotalTokens < 200 || snapshot.Usage.Requests > 20 {
+		t.Errorf("Expected the job's usage to reach the budget and stop, got %+v", snapshot)
+	}
+	if spent := tracker.Session("player-1"); spent != snapshot.Usage {
+		t.Errorf("Expected the job's usage to count for its session, got %+v and %+v", spent, snapshot.Usage)
+	}
+	if tracker.Report().Commands["generate-world"].Requests == 0 {
+		t.Error("Expected the usage to be attributed to the generate-world command")
+	}
+}
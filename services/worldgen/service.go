@@ -3,33 +3,67 @@ package worldgen
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"strings"
+	"sync"
 	"time"
-	
+
 	"textadventureservices/services/ai"
+	"textadventureservices/services/usage"
+	wgai "textadventureservices/services/worldgen/ai"
 	"textadventureservices/services/worldgen/config"
 	"textadventureservices/services/worldgen/logging"
 )
 
 // Service represents the world generation service
 type Service struct {
-	aiProvider ai.Provider
-	config     *config.Config
-	logger     logging.Logger
+	// provider describes rooms and objects: either the configured world
+	// generation provider or an adapter around the AI service
+	provider wgai.Provider
+	config   *config.Config
+	logger   logging.Logger
+	replay   *wgai.ResponseStore
+	// replayMu serializes saving the replay store, which jobs share
+	replayMu sync.Mutex
+	// replayModel keys replayed responses by the model of the active provider;
+	// empty takes it from the provider
+	replayModel string
+	// tracker records the usage of every provider the service uses
+	tracker *usage.Tracker
 }
 
-// NewService creates a new world generation service
+// NewService creates a new world generation service.
+// The provider is selected by cfg.Provider or WORLDGEN_PROVIDER; without
+// either, rooms are described through the AI service in cfg.AIProvider.
 func NewService(cfg *config.Config) (*Service, error) {
-	aiService, err := ai.NewService(&ai.Config{
-		Model:          cfg.AIProvider.Model,
-		MaxTokens:      cfg.AIProvider.MaxTokens,
-		Temperature:    cfg.AIProvider.Temperature,
-		RateLimit:      cfg.AIProvider.RateLimit,
-		TimeoutSeconds: cfg.AIProvider.TimeoutSeconds,
-	}, logging.NewNoopLogger())
+	providerCfg, selected, err := cfg.ProviderConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AI service: %w", err)
+		return nil, fmt.Errorf("failed to load provider config: %w", err)
+	}
+
+	limiter := cfg.AIProvider.RateLimits.Limiter()
+	tracker := usage.NewTracker(cfg.AIProvider.Usage)
+
+	var provider wgai.Provider
+	replayModel := cfg.AIProvider.DefaultModel
+	if selected {
+		replayModel = providerCfg.Model
+		providerCfg.RateLimiter = limiter
+		providerCfg.Tracker = tracker
+		provider, err = wgai.Open(providerCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider: %w", err)
+		}
+	} else {
+		aiConfig := cfg.AIProvider
+		// Rooms wait for the rate limits for as long as generation runs
+		aiConfig.RateLimits.MaxWaitSeconds = 0
+		aiService, err := ai.NewService(&aiConfig, logging.NewNoopLogger())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AI service: %w", err)
+		}
+		aiService.SetRateLimiter(limiter)
+		aiService.SetTracker(tracker)
+		provider = descriptionProvider{aiService}
 	}
 
 	// Create logger based on configuration
@@ -39,62 +73,107 @@ func NewService(cfg *config.Config) (*Service, error) {
 	} else {
 		logger = logging.NewNoopLogger()
 	}
-	
+
+	var replay *wgai.ResponseStore
+	if cfg.Replay.Mode != "" {
+		replay, err = wgai.LoadResponseStore(cfg.Replay.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load replay store: %w", err)
+		}
+	}
+
 	return &Service{
-		aiProvider: aiService,
-		config:     cfg,
-		logger:     logger,
+		provider:    provider,
+		config:      cfg,
+		logger:      logger,
+		replay:      replay,
+		replayModel: replayModel,
+		tracker:     tracker,
 	}, nil
 }
 
-// GenerateWorld generates a new world based on a prompt
+// SetLogger replaces the logger chosen from the configuration, e.g. to log to
+// stderr when no logging service is configured
+func (s *Service) SetLogger(logger logging.Logger) {
+	s.logger = logger
+}
+
+// Usage returns the tracker the service records the usage of its providers with
+func (s *Service) Usage() *usage.Tracker {
+	return s.tracker
+}
+
+// GenerateWorld generates a new world based on a prompt.
+// The seed comes from the configuration, or from the clock when none is configured.
 func (s *Service) GenerateWorld(ctx context.Context, prompt string) (*World, error) {
-	s.logger.Info(ctx, fmt.Sprintf("Starting world generation with prompt: %s", prompt))
+	return s.GenerateWorldWithSeed(ctx, prompt, s.defaultSeed())
+}
 
-	// Generate initial description
-	description, err := s.aiProvider.GenerateDescription(ctx, prompt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate world description: %w", err)
+// defaultSeed returns the configured seed, or a time-based seed when none is configured
+func (s *Service) defaultSeed() int64 {
+	if s.config.Seed != 0 {
+		return s.config.Seed
 	}
+	return time.Now().UnixNano()
+}
 
-	// Create world with initial room
-	seed := time.Now().UnixNano()
-	world, err := NewWorld(seed)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create world: %w", err)
+// GenerateWorldWithSeed generates a new world from a prompt and an explicit seed.
+// Room IDs and layout depend only on the seed; AI text is reproducible when a
+// replay store is configured.
+func (s *Service) GenerateWorldWithSeed(ctx context.Context, prompt string, seed int64) (*World, error) {
+	return s.generate(ctx, prompt, seed, s.config.DefaultRooms, DefaultGenerateOptions())
+}
+
+// generate generates a world, logging each room before calling opts.Progress.
+// The providers wait for the service's rate limiter before every request.
+func (s *Service) generate(ctx context.Context, prompt string, seed int64, numRooms int, opts GenerateOptions) (*World, error) {
+	ctx = logging.WithFields(ctx, logging.Fields{"seed": seed})
+
+	progress := opts.Progress
+	opts.Progress = func(p Progress) {
+		roomCtx := logging.WithFields(ctx, logging.Fields{"room": p.RoomID, "attempt": p.Attempt, "done": p.Done, "total": p.Total})
+		if p.Err != nil {
+			s.logger.Warn(logging.WithFields(roomCtx, logging.Fields{"error": p.Err.Error()}), "Room attempt failed")
+		} else {
+			s.logger.Info(roomCtx, "Generated room")
+		}
+		if progress != nil {
+			progress(p)
+		}
 	}
 
-	startRoom, err := GenerateRoom(description, []string{})
+	generator := NewGenerator(s.describer(seed))
+	generator.SetLogger(s.logger)
+	world, err := generator.GenerateWorld(ctx, seed, prompt, numRooms, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create start room: %w", err)
+		return nil, fmt.Errorf("failed to generate world: %w", err)
 	}
-	world.AddRoom(startRoom)
 
-	// Generate additional rooms based on config
-	for i := 1; i < s.config.DefaultRooms; i++ {
-		roomPrompt := fmt.Sprintf("Generate a connected room for: %s", description)
-		roomDesc, err := s.aiProvider.GenerateDescription(ctx, roomPrompt)
-		if err != nil {
-			s.logger.Error(ctx, fmt.Sprintf("Failed to generate room %d: %v", i, err))
-			continue
-		}
-		
-		room, err := GenerateRoom(roomDesc, []string{})
+	if s.replay != nil && s.config.Replay.Mode != string(wgai.ReplayReplay) {
+		s.replayMu.Lock()
+		err := s.replay.Save(s.config.Replay.Path)
+		s.replayMu.Unlock()
 		if err != nil {
-			s.logger.Error(ctx, fmt.Sprintf("Failed to create room %d: %v", i, err))
-			continue
+			return nil, fmt.Errorf("failed to save replay store: %w", err)
 		}
-		world.AddRoom(room)
-		world.ConnectRooms(startRoom.ID, room.ID, getRandomDirection())
 	}
 
-	s.logger.Info(ctx, "World generation completed successfully")
 	return world, nil
 }
 
+// describer returns the description source for a world with the given seed,
+// wrapped in the replay store when one is configured
+func (s *Service) describer(seed int64) wgai.Provider {
+	provider := s.provider
+	if s.replay != nil {
+		provider = wgai.NewReplayProvider(provider, s.replay, seed, s.replayModel, wgai.ReplayMode(s.config.Replay.Mode))
+	}
+	return provider
+}
+
 // ProcessInput handles user input and returns a response
 func (s *Service) ProcessInput(ctx context.Context, input string) (string, error) {
-	s.logger.Info(ctx, fmt.Sprintf("Processing input: %s", input))
+	s.logger.Info(logging.WithFields(ctx, logging.Fields{"input": input}), "Processing input")
 
 	// Extract command and object from input
 	parts := strings.Fields(input)
@@ -104,15 +183,16 @@ func (s *Service) ProcessInput(ctx context.Context, input string) (string, error
 
 	command := strings.ToLower(parts[0])
 	object := strings.Join(parts[1:], " ")
+	ctx = usage.With(ctx, usage.Scope{Command: command})
 
 	// Handle different commands
 	switch command {
 	case "examine":
 		// Generate description for the object using AI
 		prompt := fmt.Sprintf("Describe the '%s' in detail", object)
-		description, err := s.aiProvider.GenerateDescription(ctx, prompt)
+		description, err := s.provider.GenerateDescription(ctx, prompt)
 		if err != nil {
-			s.logger.Error(ctx, fmt.Sprintf("Failed to generate object description: %v", err))
+			s.logger.Error(logging.WithFields(ctx, logging.Fields{"object": object, "error": err.Error()}), "Failed to generate object description")
 			return "", fmt.Errorf("failed to examine object: %w", err)
 		}
 		return description, nil
@@ -123,7 +203,26 @@ func (s *Service) ProcessInput(ctx context.Context, input string) (string, error
 	}
 }
 
-func getRandomDirection() Direction {
-	directions := []Direction{North, South, East, West}
-	return directions[rand.Intn(len(directions))]
+// descriptionProvider adapts the AI service to the worldgen provider interface
+// so that its descriptions can be recorded and replayed
+type descriptionProvider struct {
+	ai.Provider
+}
+
+func (p descriptionProvider) Initialize(config wgai.ProviderConfig) error {
+	return nil
+}
+
+func (p descriptionProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	return basePrompt, nil
+}
+
+// GenerateObjects asks the AI service for a comma-separated list of the objects in a scene
+func (p descriptionProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	prompt := fmt.Sprintf("List 3-5 interactive objects found in this scene as a comma-separated list, with no other text: %s", sceneDescription)
+	response, err := p.GenerateDescription(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return wgai.ParseList(response), nil
 }
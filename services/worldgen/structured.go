@@ -0,0 +1,97 @@
+package worldgen
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+// Room property keys set from structured room documents
+const (
+	PropAmbient          = "ambient"
+	PropExitDescriptions = "exit_descriptions"
+)
+
+// exitDirections returns the exits of a layout node in alphabetical order
+func (l *Layout) exitDirections(index int) []string {
+	var exits []string
+	for dir := range l.exits[index] {
+		exits = append(exits, string(dir))
+	}
+	sort.Strings(exits)
+	return exits
+}
+
+// generateRoomSpec asks the provider for a structured room. It returns nil
+// when the provider cannot produce structured rooms or keeps failing, so the
+// caller can fall back to a prose description.
+func (w *World) generateRoomSpec(ctx context.Context, prompt string, exits []string) *ai.RoomSpec {
+	provider, ok := w.aiProvider().(ai.StructuredProvider)
+	if !ok {
+		return nil
+	}
+	start := time.Now()
+	spec, err := provider.GenerateRoomSpec(ctx, ai.RoomRequest{Prompt: prompt, Exits: exits})
+	if errors.Is(err, ai.ErrStructuredUnsupported) {
+		return nil
+	}
+	w.logCall(ctx, "generate structured room", start, err)
+	if err != nil {
+		return nil
+	}
+	return spec
+}
+
+// roomFromSpec creates a room from a validated structured room document
+func (w *World) roomFromSpec(id string, spec *ai.RoomSpec, maxObjects int) *Room {
+	room := newRoomWithID(id, spec.Description, nil)
+	room.Name = spec.Name
+
+	if len(spec.Ambient) > 0 {
+		room.Properties[PropAmbient] = append([]string(nil), spec.Ambient...)
+	}
+	if len(spec.Exits) > 0 {
+		exits := make(map[string]string, len(spec.Exits))
+		for _, exit := range spec.Exits {
+			exits[exit.Direction] = exit.Description
+		}
+		room.Properties[PropExitDescriptions] = exits
+	}
+
+	if maxObjects <= 0 {
+		return room
+	}
+	byName := make(map[string]ai.ObjectSpec, len(spec.Objects))
+	names := make([]string, 0, len(spec.Objects))
+	for _, object := range spec.Objects {
+		name := normalizeObjectName(object.Name)
+		// Like dedupeObjectNames, the first object with a name wins
+		if _, seen := byName[strings.ToLower(name)]; !seen {
+			byName[strings.ToLower(name)] = object
+		}
+		names = append(names, name)
+	}
+	for _, name := range dedupeObjectNames(names, maxObjects) {
+		object := byName[strings.ToLower(name)]
+		description := object.Description
+		if description == "" {
+			description = plainObjectDescription(name)
+		}
+		// Properties from the model override the guessed defaults
+		properties := defaultObjectProperties(name)
+		for key, value := range object.Properties {
+			properties[key] = value
+		}
+		room.Objects = append(room.Objects, Object{
+			ID:          objectID(room.ID, name),
+			Name:        name,
+			Description: description,
+			Properties:  properties,
+		})
+	}
+	return room
+}
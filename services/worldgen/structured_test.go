@@ -0,0 +1,127 @@
+package worldgen
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"textadventureservices/services/worldgen/ai"
+)
+
+// structuredProvider returns a structured room describing every requested exit
+type structuredProvider struct {
+	countingProvider
+}
+
+func (p *structuredProvider) GenerateRoomSpec(ctx context.Context, req ai.RoomRequest) (*ai.RoomSpec, error) {
+	p.mu.Lock()
+	p.calls++
+	name := fmt.Sprintf("Hall %d", p.calls)
+	p.mu.Unlock()
+	spec := &ai.RoomSpec{
+		Name:        name,
+		Description: req.Prompt,
+		Objects: []ai.ObjectSpec{
+			{Name: "a brass key", Description: "Small and tarnished."},
+			{Name: "the brass key", Description: "A duplicate."},
+			{Name: "iron chest", Properties: map[string]interface{}{"portable": true}},
+		},
+		Ambient: []string{"a distant bell"},
+	}
+	for _, exit := range req.Exits {
+		spec.Exits = append(spec.Exits, ai.ExitSpec{Direction: exit, Description: "a passage " + exit})
+	}
+	return spec, nil
+}
+
+func TestGenerateWorldStructured(t *testing.T) {
+	world, err := NewWorld(5)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&structuredProvider{})
+
+	opts := DefaultGenerateOptions()
+	opts.Structured = true
+	if err := world.GenerateWorldWithOptions(context.Background(), "A bell tower", 4, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+
+	for _, room := range world.Rooms {
+		if room.Name == "" {
+			t.Errorf("Expected room %s to have a name", room.ID)
+		}
+		if len(room.Objects) != 2 {
+			t.Errorf("Expected duplicate objects to be merged, got %+v", room.Objects)
+		}
+		for _, object := range room.Objects {
+			if object.Name == "brass key" && object.Description != "Small and tarnished." {
+				t.Errorf("Expected the first brass key to be kept, got %q", object.Description)
+			}
+			if object.Name == "iron chest" && (object.Properties[PropPortable] != true || object.Properties[PropContainer] != true) {
+				t.Errorf("Expected model properties over guessed defaults, got %v", object.Properties)
+			}
+		}
+		exitDescriptions, ok := room.Properties[PropExitDescriptions].(map[string]string)
+		if !ok {
+			t.Fatalf("Expected exit descriptions on room %s", room.ID)
+		}
+		for dir := range room.Exits {
+			if exitDescriptions[dir] == "" {
+				t.Errorf("Expected a description of exit %s in room %s", dir, room.ID)
+			}
+		}
+	}
+}
+
+func TestGenerateWorldStructuredFallsBackToProse(t *testing.T) {
+	world, err := NewWorld(5)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&countingProvider{})
+
+	opts := DefaultGenerateOptions()
+	opts.Structured = true
+	if err := world.GenerateWorldWithOptions(context.Background(), "A bell tower", 3, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+	for _, room := range world.Rooms {
+		if room.Name != "" || room.Description == "" {
+			t.Errorf("Expected a prose room without a name, got %+v", room)
+		}
+	}
+}
+
+func TestGenerateWorldWithTemplates(t *testing.T) {
+	// The template provider exercises the whole structured pipeline offline
+	generate := func() *World {
+		opts := DefaultGenerateOptions()
+		opts.Structured = true
+		world, err := NewGenerator(ai.NewTemplateProvider(9)).GenerateWorld(context.Background(), 9, "An abandoned space station", 6, opts)
+		if err != nil {
+			t.Fatalf("Failed to generate world: %v", err)
+		}
+		return world
+	}
+
+	world := generate()
+	for _, room := range world.Rooms {
+		if room.Name == "" || room.Description == "" || len(room.Objects) == 0 {
+			t.Errorf("Expected a named, described and furnished room, got %+v", room)
+		}
+		exitDescriptions, _ := room.Properties[PropExitDescriptions].(map[string]string)
+		for direction := range room.Exits {
+			if exitDescriptions[direction] == "" {
+				t.Errorf("Expected room %s to describe its %s exit", room.ID, direction)
+			}
+		}
+	}
+
+	again := generate()
+	for id, room := range world.Rooms {
+		if again.Rooms[id] == nil || again.Rooms[id].Description != room.Description {
+			t.Errorf("Expected room %s to be identical across runs", id)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package worldgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// TopologyStrategy decides the shape of a world's map. Every strategy
+// produces a Layout, which GenerateWorldWithOptions turns into rooms and
+// exits the same way regardless of the strategy used.
+type TopologyStrategy interface {
+	// Name is the identifier used to select the strategy, e.g. from the command line
+	Name() string
+	// Layout places numRooms nodes on the grid and connects them
+	Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error)
+}
+
+// Node kinds set by the built-in strategies; they are added to room prompts
+const (
+	KindHub      = "hub"
+	KindCorridor = "corridor"
+	KindChamber  = "chamber"
+	KindCave     = "cavern"
+)
+
+var topologies = struct {
+	sync.RWMutex
+	byName map[string]TopologyStrategy
+}{byName: make(map[string]TopologyStrategy)}
+
+func init() {
+	for _, strategy := range []TopologyStrategy{
+		SprawlTopology{},
+		LinearTopology{},
+		HubTopology{},
+		MazeTopology{},
+		CaveTopology{},
+		DungeonTopology{},
+	} {
+		if err := RegisterTopology(strategy); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterTopology makes a strategy available by name
+func RegisterTopology(strategy TopologyStrategy) error {
+	topologies.Lock()
+	defer topologies.Unlock()
+	if _, exists := topologies.byName[strategy.Name()]; exists {
+		return fmt.Errorf("topology %q is already registered", strategy.Name())
+	}
+	topologies.byName[strategy.Name()] = strategy
+	return nil
+}
+
+// TopologyByName returns a registered strategy
+func TopologyByName(name string) (TopologyStrategy, error) {
+	topologies.RLock()
+	defer topologies.RUnlock()
+	strategy, ok := topologies.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown topology: %q", name)
+	}
+	return strategy, nil
+}
+
+// TopologyNames returns the names of all registered strategies in alphabetical order
+func TopologyNames() []string {
+	topologies.RLock()
+	defer topologies.RUnlock()
+	names := make([]string, 0, len(topologies.byName))
+	for name := range topologies.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SprawlTopology grows a random tree out from a central hub and closes
+// loops between adjacent rooms. It is the default strategy.
+type SprawlTopology struct{}
+
+func (SprawlTopology) Name() string { return "sprawl" }
+
+func (SprawlTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	return growLayout(rng, numRooms, opts.Directions, opts.LoopChance)
+}
+
+// LinearTopology lays rooms out as a single corridor running east, with
+// occasional one-room jogs to the north or south
+type LinearTopology struct{}
+
+func (LinearTopology) Name() string { return "linear" }
+
+func (LinearTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	layout := NewLayout()
+	prev, err := layout.AddNode(Coord{}, -1, "")
+	if err != nil {
+		return nil, err
+	}
+	layout.Nodes[prev].Kind = KindCorridor
+
+	var last Direction
+	for layout.Len() < numRooms {
+		// Jogs always follow an eastward step, so the corridor never crosses itself
+		dir := East
+		if last == East && rng.Float64() < 0.3 {
+			dir = []Direction{North, South}[rng.Intn(2)]
+		}
+		next, err := layout.AddNode(layout.Nodes[prev].Coord.Move(dir.Offset()), prev, dir)
+		if err != nil {
+			return nil, err
+		}
+		layout.Nodes[next].Kind = KindCorridor
+		if err := layout.Connect(prev, next, dir); err != nil {
+			return nil, err
+		}
+		prev, last = next, dir
+	}
+	return layout, nil
+}
+
+// HubTopology places a hub room in the middle and spreads the other rooms
+// over straight spokes, one per direction
+type HubTopology struct{}
+
+func (HubTopology) Name() string { return "hub" }
+
+func (HubTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	spokes := spatialDirections(opts.Directions)
+	if len(spokes) == 0 {
+		return nil, fmt.Errorf("hub topology needs at least one spatial direction")
+	}
+	// Shuffle so that small worlds don't always use the same spokes
+	spokes = append([]Direction(nil), spokes...)
+	rng.Shuffle(len(spokes), func(i, j int) { spokes[i], spokes[j] = spokes[j], spokes[i] })
+
+	layout := NewLayout()
+	hub, err := layout.AddNode(Coord{}, -1, "")
+	if err != nil {
+		return nil, err
+	}
+	layout.Nodes[hub].Kind = KindHub
+
+	ends := make([]int, len(spokes))
+	for i := range ends {
+		ends[i] = hub
+	}
+	for i := 0; layout.Len() < numRooms; i++ {
+		spoke := i % len(spokes)
+		dir := spokes[spoke]
+		next, err := layout.AddNode(layout.Nodes[ends[spoke]].Coord.Move(dir.Offset()), ends[spoke], dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := layout.Connect(ends[spoke], next, dir); err != nil {
+			return nil, err
+		}
+		ends[spoke] = next
+	}
+	return layout, nil
+}
+
+// MazeTopology carves a maze with a recursive backtracker on a grid just
+// large enough for the rooms. LoopChance braids the maze by opening extra walls.
+type MazeTopology struct{}
+
+func (MazeTopology) Name() string { return "maze" }
+
+func (MazeTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	width := int(math.Ceil(math.Sqrt(float64(numRooms))))
+
+	// Fill the grid row by row; a partial last row stays connected to the rows above
+	layout := NewLayout()
+	for i := 0; i < numRooms; i++ {
+		if _, err := layout.AddNode(Coord{X: i % width, Y: i / width}, -1, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	visited := make([]bool, numRooms)
+	stack := []int{0}
+	visited[0] = true
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		var unvisited []Direction
+		for _, dir := range CardinalDirections {
+			if next, ok := layout.At(layout.Nodes[current].Coord.Move(dir.Offset())); ok && !visited[next] {
+				unvisited = append(unvisited, dir)
+			}
+		}
+		if len(unvisited) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		dir := unvisited[rng.Intn(len(unvisited))]
+		next, _ := layout.At(layout.Nodes[current].Coord.Move(dir.Offset()))
+		if err := layout.Connect(current, next, dir); err != nil {
+			return nil, err
+		}
+		layout.Nodes[next].Parent = current
+		layout.Nodes[next].Via = dir
+		visited[next] = true
+		stack = append(stack, next)
+	}
+
+	for index := range layout.Nodes {
+		layout.closeLoops(rng, index, []Direction{North, East}, opts.LoopChance)
+	}
+	return layout, nil
+}
+
+// CaveTopology shapes an organic cave with cellular automata and keeps the
+// largest open area. Open cells next to each other are usually connected.
+type CaveTopology struct{}
+
+func (CaveTopology) Name() string { return "cave" }
+
+func (CaveTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	side := int(math.Ceil(math.Sqrt(float64(numRooms)*2.5))) + 2
+	if side < 8 {
+		side = 8
+	}
+
+	// Retry on a slightly larger grid whenever the noise leaves too little open space
+	for attempt := 0; attempt < 20; attempt, side = attempt+1, side+1 {
+		open := cellularCave(rng, side)
+		region := largestRegion(open, side)
+		if len(region) < numRooms {
+			continue
+		}
+
+		// Take the rooms closest to the start of the region, breadth first,
+		// so the chosen cells stay connected
+		layout := NewLayout()
+		start := region[0]
+		if _, err := layout.AddNode(start, -1, ""); err != nil {
+			return nil, err
+		}
+		for queue := []int{0}; len(queue) > 0 && layout.Len() < numRooms; queue = queue[1:] {
+			current := queue[0]
+			for _, dir := range CardinalDirections {
+				c := layout.Nodes[current].Coord.Move(dir.Offset())
+				if !open[c] || layout.Len() >= numRooms {
+					continue
+				}
+				if _, taken := layout.At(c); taken {
+					continue
+				}
+				next, err := layout.AddNode(c, current, dir)
+				if err != nil {
+					return nil, err
+				}
+				if err := layout.Connect(current, next, dir); err != nil {
+					return nil, err
+				}
+				queue = append(queue, next)
+			}
+		}
+		for index := range layout.Nodes {
+			layout.Nodes[index].Kind = KindCave
+			// Caves are open spaces, so neighbouring cells are linked more often than not
+			layout.closeLoops(rng, index, []Direction{North, East}, math.Max(opts.LoopChance, 0.6))
+		}
+		return layout, nil
+	}
+	return nil, fmt.Errorf("failed to carve a cave large enough for %d rooms", numRooms)
+}
+
+// cellularCave fills a square grid with noise and smooths it into cave shapes
+func cellularCave(rng *rand.Rand, side int) map[Coord]bool {
+	open := make(map[Coord]bool)
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			if rng.Float64() >= 0.4 {
+				open[Coord{X: x, Y: y}] = true
+			}
+		}
+	}
+
+	for step := 0; step < 4; step++ {
+		next := make(map[Coord]bool)
+		for y := 0; y < side; y++ {
+			for x := 0; x < side; x++ {
+				walls := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if dx == 0 && dy == 0 {
+							continue
+						}
+						if !open[Coord{X: x + dx, Y: y + dy}] {
+							walls++
+						}
+					}
+				}
+				if walls < 5 {
+					next[Coord{X: x, Y: y}] = true
+				}
+			}
+		}
+		open = next
+	}
+	return open
+}
+
+// largestRegion returns the cells of the largest orthogonally connected open area,
+// starting from its lowest, leftmost cell
+func largestRegion(open map[Coord]bool, side int) []Coord {
+	seen := make(map[Coord]bool)
+	var best []Coord
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			start := Coord{X: x, Y: y}
+			if !open[start] || seen[start] {
+				continue
+			}
+			region := []Coord{start}
+			seen[start] = true
+			for i := 0; i < len(region); i++ {
+				for _, dir := range CardinalDirections {
+					c := region[i].Move(dir.Offset())
+					if open[c] && !seen[c] {
+						seen[c] = true
+						region = append(region, c)
+					}
+				}
+			}
+			if len(region) > len(best) {
+				best = region
+			}
+		}
+	}
+	return best
+}
+
+// DungeonTopology builds rectangular chambers joined by corridors
+type DungeonTopology struct{}
+
+func (DungeonTopology) Name() string { return "dungeon" }
+
+func (DungeonTopology) Layout(rng *rand.Rand, numRooms int, opts GenerateOptions) (*Layout, error) {
+	layout := NewLayout()
+	var chambers [][]int
+
+	// addChamber places up to budget cells of a w×h chamber, nearest to the
+	// entry cell first so a partial chamber stays connected, and links every
+	// cell to its neighbours inside the chamber
+	addChamber := func(origin, entry Coord, w, h, budget, parent int, via Direction) ([]int, error) {
+		coords := make([]Coord, 0, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				coords = append(coords, Coord{X: origin.X + x, Y: origin.Y + y})
+			}
+		}
+		sort.SliceStable(coords, func(i, j int) bool {
+			return manhattan(coords[i], entry) < manhattan(coords[j], entry)
+		})
+
+		var cells []int
+		for _, c := range coords {
+			if len(cells) >= budget {
+				break
+			}
+			p, v := parent, via
+			var neighbours []Direction
+			for _, dir := range CardinalDirections {
+				if n, ok := layout.At(c.Move(dir.Offset())); ok && containsNode(cells, n) {
+					neighbours = append(neighbours, dir)
+				}
+			}
+			if len(neighbours) > 0 {
+				p, _ = layout.At(c.Move(neighbours[0].Offset()))
+				v = neighbours[0].GetOppositeDirection()
+			}
+			index, err := layout.AddNode(c, p, v)
+			if err != nil {
+				return nil, err
+			}
+			layout.Nodes[index].Kind = KindChamber
+			for _, dir := range neighbours {
+				n, _ := layout.At(c.Move(dir.Offset()))
+				if err := layout.Connect(index, n, dir); err != nil {
+					return nil, err
+				}
+			}
+			cells = append(cells, index)
+		}
+		return cells, nil
+	}
+
+	first, err := addChamber(Coord{}, Coord{}, 2+rng.Intn(2), 2+rng.Intn(2), numRooms, -1, "")
+	if err != nil {
+		return nil, err
+	}
+	chambers = append(chambers, first)
+
+	for attempts := 0; layout.Len() < numRooms; attempts++ {
+		if attempts > numRooms*20 {
+			return nil, fmt.Errorf("failed to fit %d rooms into the dungeon", numRooms)
+		}
+
+		// Run a corridor out of a random cell of a random chamber
+		from := chambers[rng.Intn(len(chambers))]
+		door := from[rng.Intn(len(from))]
+		dir := CardinalDirections[rng.Intn(len(CardinalDirections))]
+		length := 1 + rng.Intn(3)
+		w, h := 2+rng.Intn(2), 2+rng.Intn(2)
+
+		corridor := make([]Coord, length)
+		c := layout.Nodes[door].Coord
+		for i := range corridor {
+			c = c.Move(dir.Offset())
+			corridor[i] = c
+		}
+		entry := c.Move(dir.Offset())
+		origin := chamberOrigin(entry, dir, w, h)
+		if !dungeonAreaFree(layout, corridor, origin, w, h) {
+			continue
+		}
+
+		prev := door
+		for _, cell := range corridor {
+			if layout.Len() >= numRooms {
+				break
+			}
+			next, err := layout.AddNode(cell, prev, dir)
+			if err != nil {
+				return nil, err
+			}
+			layout.Nodes[next].Kind = KindCorridor
+			if err := layout.Connect(prev, next, dir); err != nil {
+				return nil, err
+			}
+			prev = next
+		}
+		if layout.Len() >= numRooms {
+			break
+		}
+
+		cells, err := addChamber(origin, entry, w, h, numRooms-layout.Len(), prev, dir)
+		if err != nil {
+			return nil, err
+		}
+		// The corridor ends next to the chamber's entrance cell, which is placed first
+		if err := layout.Connect(prev, cells[0], dir); err != nil {
+			return nil, err
+		}
+		chambers = append(chambers, cells)
+	}
+	return layout, nil
+}
+
+// chamberOrigin returns the lower left corner of a w×h chamber whose
+// entrance cell is entry, entered while travelling in dir
+func chamberOrigin(entry Coord, dir Direction, w, h int) Coord {
+	switch dir {
+	case North, East:
+		return entry
+	case South:
+		return Coord{X: entry.X, Y: entry.Y - h + 1}
+	default: // West
+		return Coord{X: entry.X - w + 1, Y: entry.Y}
+	}
+}
+
+// dungeonAreaFree reports whether a corridor and chamber fit without touching
+// existing cells; chambers keep a one-cell margin so walls stay between them
+func dungeonAreaFree(layout *Layout, corridor []Coord, origin Coord, w, h int) bool {
+	for _, c := range corridor {
+		if _, taken := layout.At(c); taken {
+			return false
+		}
+	}
+	for y := origin.Y - 1; y <= origin.Y+h; y++ {
+		for x := origin.X - 1; x <= origin.X+w; x++ {
+			if _, taken := layout.At(Coord{X: x, Y: y}); taken {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func manhattan(a, b Coord) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y) + abs(a.Z-b.Z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func containsNode(nodes []int, index int) bool {
+	for _, n := range nodes {
+		if n == index {
+			return true
+		}
+	}
+	return false
+}
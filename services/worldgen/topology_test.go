@@ -0,0 +1,146 @@
+package worldgen
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// layoutConnected reports whether every node can be reached from node 0
+func layoutConnected(layout *Layout) bool {
+	adjacent := make(map[int][]int)
+	for _, edge := range layout.Edges {
+		adjacent[edge.From] = append(adjacent[edge.From], edge.To)
+		adjacent[edge.To] = append(adjacent[edge.To], edge.From)
+	}
+	seen := map[int]bool{0: true}
+	for queue := []int{0}; len(queue) > 0; queue = queue[1:] {
+		for _, next := range adjacent[queue[0]] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return len(seen) == layout.Len()
+}
+
+func degrees(layout *Layout) map[int]int {
+	degree := make(map[int]int)
+	for _, edge := range layout.Edges {
+		degree[edge.From]++
+		degree[edge.To]++
+	}
+	return degree
+}
+
+func TestTopologiesProduceConnectedLayouts(t *testing.T) {
+	for _, name := range TopologyNames() {
+		strategy, err := TopologyByName(name)
+		if err != nil {
+			t.Fatalf("Failed to look up topology %s: %v", name, err)
+		}
+		for _, numRooms := range []int{1, 2, 7, 30} {
+			opts := DefaultGenerateOptions()
+			opts.Topology = strategy
+			layout, err := strategy.Layout(rand.New(rand.NewSource(3)), numRooms, opts)
+			if err != nil {
+				t.Fatalf("%s: failed to lay out %d rooms: %v", name, numRooms, err)
+			}
+			if layout.Len() != numRooms {
+				t.Errorf("%s: expected %d nodes, got %d", name, numRooms, layout.Len())
+			}
+			if !layoutConnected(layout) {
+				t.Errorf("%s: layout of %d rooms is not connected", name, numRooms)
+			}
+			for _, edge := range layout.Edges {
+				from, to := layout.Nodes[edge.From].Coord, layout.Nodes[edge.To].Coord
+				if from.Move(edge.Direction.Offset()) != to {
+					t.Errorf("%s: edge %s from %s does not reach %s", name, edge.Direction, from, to)
+				}
+			}
+
+			again, err := strategy.Layout(rand.New(rand.NewSource(3)), numRooms, opts)
+			if err != nil {
+				t.Fatalf("%s: failed to lay out %d rooms again: %v", name, numRooms, err)
+			}
+			if !reflect.DeepEqual(layout.Nodes, again.Nodes) || !reflect.DeepEqual(layout.Edges, again.Edges) {
+				t.Errorf("%s: same seed produced different layouts", name)
+			}
+		}
+	}
+}
+
+func TestTopologyShapes(t *testing.T) {
+	opts := DefaultGenerateOptions()
+	opts.LoopChance = 0
+	rng := func() *rand.Rand { return rand.New(rand.NewSource(9)) }
+
+	linear, err := LinearTopology{}.Layout(rng(), 20, opts)
+	if err != nil {
+		t.Fatalf("Failed to lay out corridor: %v", err)
+	}
+	for index, degree := range degrees(linear) {
+		if degree > 2 {
+			t.Errorf("Corridor node %d has %d exits", index, degree)
+		}
+	}
+
+	hub, err := HubTopology{}.Layout(rng(), 13, opts)
+	if err != nil {
+		t.Fatalf("Failed to lay out hub: %v", err)
+	}
+	if degrees(hub)[0] != len(CardinalDirections) {
+		t.Errorf("Expected the hub to have %d spokes, got %d", len(CardinalDirections), degrees(hub)[0])
+	}
+
+	maze, err := MazeTopology{}.Layout(rng(), 25, opts)
+	if err != nil {
+		t.Fatalf("Failed to lay out maze: %v", err)
+	}
+	if len(maze.Edges) != 24 {
+		t.Errorf("Expected a perfect maze with 24 passages, got %d", len(maze.Edges))
+	}
+
+	dungeon, err := DungeonTopology{}.Layout(rng(), 40, opts)
+	if err != nil {
+		t.Fatalf("Failed to lay out dungeon: %v", err)
+	}
+	kinds := make(map[string]int)
+	for _, node := range dungeon.Nodes {
+		kinds[node.Kind]++
+	}
+	if kinds[KindChamber] == 0 || kinds[KindCorridor] == 0 {
+		t.Errorf("Expected chambers and corridors, got %v", kinds)
+	}
+}
+
+func TestGenerateWorldWithTopology(t *testing.T) {
+	world, err := NewWorld(4)
+	if err != nil {
+		t.Fatalf("Failed to create world: %v", err)
+	}
+	world.SetProvider(&countingProvider{})
+
+	opts := DefaultGenerateOptions()
+	opts.Topology = LinearTopology{}
+	if err := world.GenerateWorldWithOptions(context.Background(), "A sunken temple", 6, opts); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+	if len(world.Rooms) != 6 {
+		t.Fatalf("Expected 6 rooms, got %d", len(world.Rooms))
+	}
+	for _, room := range world.Rooms {
+		if len(room.Exits) == 0 || len(room.Exits) > 2 {
+			t.Errorf("Corridor room %s has %d exits", room.ID, len(room.Exits))
+		}
+	}
+
+	if _, err := TopologyByName("spiral"); err == nil {
+		t.Error("Expected error for an unknown topology")
+	}
+	if err := RegisterTopology(MazeTopology{}); err == nil {
+		t.Error("Expected error when registering a topology twice")
+	}
+}
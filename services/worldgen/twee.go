@@ -0,0 +1,388 @@
+package worldgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+// Passage tags that carry room data in Twee stories. Spaces in region names
+// are written as underscores, since tags cannot contain spaces.
+const (
+	tweeIDTag     = "id:"
+	tweeRegionTag = "region:"
+)
+
+// tweeFormat is the story format named in exported stories. The passages only
+// use plain links and lists, so any story format can play them.
+const (
+	tweeFormat        = "Harlowe"
+	tweeFormatVersion = "3.3.9"
+)
+
+// tweeSpecialPassages are Twine and story format passages that are not rooms
+var tweeSpecialPassages = map[string]bool{
+	"StoryTitle": true, "StoryData": true, "StoryInit": true, "StoryCaption": true, "StoryMenu": true,
+	"StoryBanner": true, "StorySubtitle": true, "StoryAuthor": true, "StoryIncludes": true,
+	"StoryShare": true, "StoryInterface": true, "PassageReady": true, "PassageDone": true,
+	"PassageHeader": true, "PassageFooter": true,
+}
+
+// tweeSpecialTags mark passages holding code or styles rather than rooms
+var tweeSpecialTags = map[string]bool{
+	"script": true, "stylesheet": true, "widget": true, "header": true, "footer": true, "startup": true,
+	"debug-header": true, "debug-footer": true, "debug-startup": true, "Twine.private": true,
+}
+
+// tweeStoryData is the StoryData passage of a Twee 3 story
+type tweeStoryData struct {
+	IFID          string `json:"ifid"`
+	Format        string `json:"format,omitempty"`
+	FormatVersion string `json:"format-version,omitempty"`
+	Start         string `json:"start,omitempty"`
+}
+
+// tweeLink matches [[text->target]], [[target<-text]], [[text|target]] and [[target]]
+var tweeLink = regexp.MustCompile(`\[\[(.*?)\]\]`)
+
+// tweeEscaper escapes the characters with a meaning in passage headers
+var tweeEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "{", `\{`, "}", `\}`)
+
+// ExportTwee writes the world as a Twee 3 story with a passage per room. Exits
+// are links labelled with their direction and objects are listed as
+// "* name: description". Room IDs and regions are kept in passage tags, so
+// ImportTwee can read the story back. Repeated room names are numbered.
+func (w *World) ExportTwee(title string) ([]byte, error) {
+	if title == "" {
+		title = "Generated World"
+	}
+
+	ids := w.roomIDs()
+	passages := make(map[string]string, len(ids))
+	used := make(map[string]bool)
+	for _, id := range ids {
+		if w.Rooms[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
+		}
+		name := strings.Join(strings.Fields(w.roomLabel(id)), " ")
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s (%d)", strings.Join(strings.Fields(w.roomLabel(id)), " "), i)
+		}
+		used[name] = true
+		passages[id] = name
+	}
+
+	data := tweeStoryData{IFID: w.ifid(), Format: tweeFormat, FormatVersion: tweeFormatVersion}
+	if _, ok := w.Rooms[w.Start]; ok {
+		data.Start = passages[w.Start]
+	} else if len(ids) > 0 {
+		data.Start = passages[ids[0]]
+	}
+	storyData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal story data: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ":: StoryTitle\n%s\n\n", title)
+	fmt.Fprintf(&b, ":: StoryData\n%s\n\n", storyData)
+
+	// Passages are placed on the Twine story map like the rooms on the grid
+	positions := w.mapPositions()
+	rows := make(map[int]int)
+	top := 0
+	for _, z := range mapLevels(positions) {
+		_, _, minY, maxY := mapBounds(positions, z)
+		rows[z] = top + maxY
+		top += maxY - minY + 2
+	}
+	edges := w.edges()
+	for _, id := range ids {
+		room := w.Rooms[id]
+		tags := []string{tweeIDTag + id}
+		if room.Region != "" {
+			tags = append(tags, tweeRegionTag+strings.Join(strings.Fields(room.Region), "_"))
+		}
+		c := positions[id]
+		fmt.Fprintf(&b, ":: %s [%s] {\"position\":\"%d,%d\"}\n", tweeEscaper.Replace(passages[id]), strings.Join(tags, " "),
+			100+c.X*150, 100+(rows[c.Z]-c.Y)*150)
+
+		if description := strings.TrimSpace(room.Description); description != "" {
+			b.WriteString(tweeLine(description) + "\n")
+		}
+		if len(room.Objects) > 0 {
+			b.WriteString("\n")
+			for _, object := range room.Objects {
+				line := "* " + strings.Join(strings.Fields(object.Name), " ")
+				if description := strings.TrimSpace(object.Description); description != "" {
+					line += ": " + strings.Join(strings.Fields(description), " ")
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+		if len(edges[id]) > 0 {
+			b.WriteString("\n")
+			for _, edge := range edges[id] {
+				fmt.Fprintf(&b, "[[%s->%s]]\n", edge.direction, passages[edge.target])
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// tweeLine escapes lines of passage text that would start a new passage
+func tweeLine(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "::") {
+			lines[i] = `\` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ifid returns a version 4 UUID derived from the seed, used as the story's
+// Interactive Fiction ID so that exporting the same world twice matches
+func (w *World) ifid() string {
+	rng := rand.New(rand.NewSource(w.Seed))
+	return fmt.Sprintf("%08X-%04X-4%03X-%04X-%012X", rng.Uint32(), rng.Intn(1<<16), rng.Intn(1<<12),
+		0x8000|rng.Intn(1<<14), rng.Int63n(1<<48))
+}
+
+// tweePassage is a passage read from a Twee story
+type tweePassage struct {
+	name string
+	tags []string
+	text []string
+	line int
+}
+
+// ImportTwee reads a Twee story into a world, so that levels can be written
+// or touched up by hand. Each passage is a room named after the passage:
+//
+//	:: Great Hall [id:hall region:Manor]
+//	A vaulted hall hung with faded banners.
+//
+//	* brass lamp: A dented lamp, still warm.
+//
+//	[[east->Kitchen]]
+//	[[Garden<-north]]
+//
+// Links are exits labelled with their direction; "->", "<-" and "|" links
+// are all understood. Lines starting with "* " are objects. The id and region
+// tags are optional. The StoryData start passage becomes the start room, and
+// the seed is derived from the story's IFID.
+func ImportTwee(data []byte) (*World, error) {
+	passages, err := parseTwee(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var story tweeStoryData
+	title := ""
+	var rooms []tweePassage
+	for _, passage := range passages {
+		switch {
+		case passage.name == "StoryData":
+			if err := json.Unmarshal([]byte(strings.Join(passage.text, "\n")), &story); err != nil {
+				return nil, fmt.Errorf("line %d: invalid StoryData: %w", passage.line, err)
+			}
+		case passage.name == "StoryTitle":
+			title = strings.TrimSpace(strings.Join(passage.text, "\n"))
+		case tweeSpecialPassages[passage.name] || passage.special():
+			continue
+		default:
+			rooms = append(rooms, passage)
+		}
+	}
+
+	h := fnv.New64a()
+	if story.IFID != "" {
+		h.Write([]byte(story.IFID))
+	} else {
+		h.Write([]byte(title))
+	}
+	world, err := NewWorld(int64(h.Sum64() >> 1))
+	if err != nil {
+		return nil, err
+	}
+
+	// Passage names are assigned IDs before any links are read
+	roomIDs := make(map[string]string, len(rooms))
+	for _, passage := range rooms {
+		if _, exists := roomIDs[passage.name]; exists {
+			return nil, fmt.Errorf("line %d: duplicate passage %q", passage.line, passage.name)
+		}
+		id := passage.tag(tweeIDTag)
+		if id == "" {
+			id = "room_" + strings.Join(strings.FieldsFunc(strings.ToLower(passage.name), func(r rune) bool {
+				return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+			}), "_")
+		}
+		base := id
+		for i := 2; world.Rooms[id] != nil; i++ {
+			id = fmt.Sprintf("%s_%d", base, i)
+		}
+		roomIDs[passage.name] = id
+		room := NewScene(id, "")
+		room.Name = passage.name
+		room.Region = strings.ReplaceAll(passage.tag(tweeRegionTag), "_", " ")
+		if err := world.AddRoom(room); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, passage := range rooms {
+		room := world.Rooms[roomIDs[passage.name]]
+		var description []string
+		for i, line := range passage.text {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "* ") {
+				name, objectDescription, _ := strings.Cut(strings.TrimSpace(trimmed[2:]), ":")
+				name = strings.TrimSpace(name)
+				room.AddObject(Object{
+					ID:          objectID(room.ID, name),
+					Name:        name,
+					Description: strings.TrimSpace(objectDescription),
+					Properties:  defaultObjectProperties(name),
+				})
+				continue
+			}
+
+			var linkErr error
+			text := tweeLink.ReplaceAllStringFunc(line, func(link string) string {
+				label, target := parseTweeLink(link[2 : len(link)-2])
+				targetID, ok := roomIDs[target]
+				switch {
+				case linkErr != nil:
+				case !ok:
+					linkErr = fmt.Errorf("line %d: link to unknown passage %q: %w", passage.line+i+1, target, ErrRoomNotFound)
+				case label == target:
+					linkErr = fmt.Errorf("line %d: link to %q has no direction", passage.line+i+1, target)
+				default:
+					direction, known := LookupDirection(label)
+					if !known {
+						direction = Direction(normalizeDirectionName(label))
+					}
+					room.AddExit(string(direction), targetID)
+				}
+				return label
+			})
+			if linkErr != nil {
+				return nil, linkErr
+			}
+			// Lines holding nothing but links are the exit list, not prose
+			if strings.TrimSpace(tweeLink.ReplaceAllString(line, "")) != "" {
+				if strings.HasPrefix(text, `\::`) {
+					text = text[1:]
+				}
+				description = append(description, strings.TrimRight(text, " "))
+			} else if trimmed == "" && len(description) > 0 && description[len(description)-1] != "" {
+				description = append(description, "")
+			}
+		}
+		room.Description = strings.TrimSpace(strings.Join(description, "\n"))
+	}
+
+	if id, ok := roomIDs[story.Start]; ok {
+		world.Start = id
+	} else if len(rooms) > 0 {
+		world.Start = roomIDs[rooms[0].name]
+	}
+	return world, nil
+}
+
+// parseTwee splits Twee source into passages
+func parseTwee(source string) ([]tweePassage, error) {
+	var passages []tweePassage
+	for i, line := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(line, "::") {
+			if len(passages) > 0 {
+				last := &passages[len(passages)-1]
+				last.text = append(last.text, line)
+			}
+			continue
+		}
+
+		name, tags, err := parseTweeHeader(strings.TrimSpace(line[2:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		passages = append(passages, tweePassage{name: name, tags: tags, line: i + 1})
+	}
+	return passages, nil
+}
+
+// parseTweeHeader reads the name and tags of a passage header, ignoring the metadata
+func parseTweeHeader(header string) (string, []string, error) {
+	var name strings.Builder
+	rest := ""
+	for i := 0; i < len(header); i++ {
+		switch c := header[i]; {
+		case c == '\\' && i+1 < len(header):
+			i++
+			name.WriteByte(header[i])
+			continue
+		case c == '[' || c == '{':
+			rest = header[i:]
+		default:
+			name.WriteByte(c)
+			continue
+		}
+		break
+	}
+
+	var tags []string
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated passage tags")
+		}
+		tags = strings.Fields(rest[1:end])
+	}
+
+	trimmed := strings.TrimSpace(name.String())
+	if trimmed == "" {
+		return "", nil, fmt.Errorf("passage has no name")
+	}
+	return trimmed, tags, nil
+}
+
+// parseTweeLink returns the text and target of the inside of a link
+func parseTweeLink(link string) (string, string) {
+	if text, target, ok := strings.Cut(link, "->"); ok {
+		return strings.TrimSpace(text), strings.TrimSpace(target)
+	}
+	if target, text, ok := strings.Cut(link, "<-"); ok {
+		return strings.TrimSpace(text), strings.TrimSpace(target)
+	}
+	if text, target, ok := strings.Cut(link, "|"); ok {
+		return strings.TrimSpace(text), strings.TrimSpace(target)
+	}
+	return strings.TrimSpace(link), strings.TrimSpace(link)
+}
+
+// tag returns the value of the passage's first tag with the given prefix
+func (p tweePassage) tag(prefix string) string {
+	for _, tag := range p.tags {
+		if strings.HasPrefix(tag, prefix) {
+			return tag[len(prefix):]
+		}
+	}
+	return ""
+}
+
+// special reports whether the passage holds code or styles rather than a room
+func (p tweePassage) special() bool {
+	for _, tag := range p.tags {
+		if tweeSpecialTags[tag] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package worldgen
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTweeRoundTrip(t *testing.T) {
+	world := renderWorld(t)
+	world.Rooms["hall"].AddObject(Object{ID: "obj_lamp", Name: "brass lamp", Description: "A dented lamp.", Properties: defaultObjectProperties("brass lamp")})
+	world.Rooms["hall"].Region = "Manor House"
+
+	data, err := world.ExportTwee("Manor")
+	if err != nil {
+		t.Fatalf("ExportTwee failed: %v", err)
+	}
+	story := string(data)
+	for _, want := range []string{
+		":: StoryTitle\nManor\n",
+		`"start": "Great Hall"`,
+		":: Great Hall [id:hall region:Manor_House]",
+		"* brass lamp: A dented lamp.",
+		"[[east->Kitchen]]",
+		"[[in->Pantry & Stores]]",
+	} {
+		if !strings.Contains(story, want) {
+			t.Errorf("Expected story to contain %q, got:\n%s", want, story)
+		}
+	}
+	if again, _ := world.ExportTwee("Manor"); string(again) != story {
+		t.Error("Expected the same world to export the same story")
+	}
+
+	imported, err := ImportTwee(data)
+	if err != nil {
+		t.Fatalf("ImportTwee failed: %v", err)
+	}
+	if imported.Start != world.Start {
+		t.Errorf("Expected start %s, got %s", world.Start, imported.Start)
+	}
+	if len(imported.Rooms) != len(world.Rooms) {
+		t.Fatalf("Expected %d rooms, got %d", len(world.Rooms), len(imported.Rooms))
+	}
+	for id, room := range world.Rooms {
+		got, ok := imported.Rooms[id]
+		if !ok {
+			t.Errorf("Room %s was not imported", id)
+			continue
+		}
+		if got.Name != room.Name || got.Description != room.Description || got.Region != room.Region {
+			t.Errorf("Room %s changed: %+v, want %+v", id, got, room)
+		}
+		if !reflect.DeepEqual(got.Exits, room.Exits) {
+			t.Errorf("Room %s has exits %v, want %v", id, got.Exits, room.Exits)
+		}
+	}
+	if lamp, ok := imported.Rooms["hall"].GetObject(0); !ok || lamp.Name != "brass lamp" || lamp.Description != "A dented lamp." {
+		t.Errorf("Expected the lamp to be imported, got %+v", lamp)
+	}
+}
+
+func TestImportHandWrittenTwee(t *testing.T) {
+	story := `:: StoryTitle
+The Lighthouse
+
+:: StoryData
+{"ifid": "D674C58C-DEFA-4F70-B7A2-27742230C0FC", "start": "Shore"}
+
+:: StoryInit [script]
+(set: $lamp to false)
+
+:: Keeper's Room
+A cramped room under the lamp.
+A ladder leads [[up->Lamp Room]] and stairs lead [[Shore<-down]].
+
+* logbook: The last entry is smudged.
+
+:: Shore [region:Rocky_Coast]
+Waves break on black rocks.
+
+* driftwood
+
+[[north|Keeper's Room]]
+
+:: Lamp Room
+The great lens turns slowly.
+
+[[down->Keeper's Room]]
+`
+	world, err := ImportTwee([]byte(story))
+	if err != nil {
+		t.Fatalf("ImportTwee failed: %v", err)
+	}
+
+	if len(world.Rooms) != 3 {
+		t.Fatalf("Expected 3 rooms, got %d", len(world.Rooms))
+	}
+	keeper, ok := world.GetRoom("room_keeper_s_room")
+	if !ok {
+		t.Fatalf("Expected a room ID derived from the passage name, got %v", world.roomIDs())
+	}
+	if want := "A cramped room under the lamp.\nA ladder leads up and stairs lead down."; keeper.Description != want {
+		t.Errorf("Expected description %q, got %q", want, keeper.Description)
+	}
+	if want := map[string]string{"up": "room_lamp_room", "down": "room_shore"}; !reflect.DeepEqual(keeper.Exits, want) {
+		t.Errorf("Expected exits %v, got %v", want, keeper.Exits)
+	}
+	if logbook, _ := keeper.GetObject(0); logbook.Name != "logbook" || logbook.ID == "" {
+		t.Errorf("Expected the logbook object, got %+v", logbook)
+	}
+
+	shore := world.Rooms["room_shore"]
+	if world.Start != shore.ID || shore.Region != "Rocky Coast" || shore.Exits["north"] != keeper.ID {
+		t.Errorf("Unexpected shore room %+v (start %s)", shore, world.Start)
+	}
+	// Only the driftwood, which has no description, is worth a warning
+	if diagnostics := world.Validate(); diagnostics.HasErrors() || len(diagnostics) != 1 {
+		t.Errorf("Expected a single warning, got %v", diagnostics)
+	}
+
+	again, _ := ImportTwee([]byte(story))
+	if again.Seed != world.Seed {
+		t.Error("Expected the seed to be derived from the IFID")
+	}
+}
+
+func TestImportTweeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		story string
+		want  string
+	}{
+		{"unknown passage", ":: Hall\n[[north->Garden]]\n", `link to unknown passage "Garden"`},
+		{"no direction", ":: Hall\n[[Hall]]\n", `link to "Hall" has no direction`},
+		{"duplicate passage", ":: Hall\n\n:: Hall\n", `line 3: duplicate passage "Hall"`},
+		{"bad story data", ":: StoryData\n{\n", "invalid StoryData"},
+		{"unterminated tags", ":: Hall [tag\n", "unterminated passage tags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportTwee([]byte(tt.story))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ImportTwee([]byte(":: Hall\n[[north->Garden]]\n")); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+}
@@ -2,11 +2,17 @@ package worldgen
 
 // Room represents a location in the text adventure world
 type Room struct {
-	ID          string                 `json:"id"`
+	ID string `json:"id"`
+	// Name is a short title such as "Flooded Crypt", if the provider gave one
+	Name        string                 `json:"name,omitempty"`
 	Description string                 `json:"description"`
 	Objects     []Object               `json:"objects"`
 	Exits       map[string]string      `json:"exits"`
 	Properties  map[string]interface{} `json:"properties"`
+	// Coord is the room's position on the world grid, if it was laid out on one
+	Coord *Coord `json:"coord,omitempty"`
+	// Region is the name of the themed zone the room belongs to
+	Region string `json:"region,omitempty"`
 }
 
 // Object represents an interactive item in a room
@@ -0,0 +1,287 @@
+package worldgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity says whether a diagnostic makes a world unusable
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes reported by World.Validate
+const (
+	CodeDanglingExit       = "dangling-exit"
+	CodePlaceholderExit    = "placeholder-exit"
+	CodeOneWayExit         = "one-way-exit"
+	CodeUnknownDirection   = "unknown-direction"
+	CodeUnreachableRoom    = "unreachable-room"
+	CodeInvalidStart       = "invalid-start"
+	CodeRoomIDMismatch     = "room-id-mismatch"
+	CodeDuplicateObjectID  = "duplicate-object-id"
+	CodeMissingDescription = "missing-description"
+	CodeMissingObjectName  = "missing-object-name"
+)
+
+// PropOneWayExits lists the directions of a room's exits that are meant to
+// have no way back, so Validate does not report them
+const PropOneWayExits = "one_way_exits"
+
+// Diagnostic is a single problem found in a world
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	RoomID   string   `json:"roomId,omitempty"`
+	// Direction is set for problems with a specific exit
+	Direction string `json:"direction,omitempty"`
+	// ObjectID is set for problems with a specific object
+	ObjectID string `json:"objectId,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.RoomID
+	if d.Direction != "" {
+		location += " " + d.Direction
+	}
+	if d.ObjectID != "" {
+		location += " " + d.ObjectID
+	}
+	if location == "" {
+		return fmt.Sprintf("%s %s: %s", d.Severity, d.Code, d.Message)
+	}
+	return fmt.Sprintf("%s %s [%s]: %s", d.Severity, d.Code, location, d.Message)
+}
+
+// Diagnostics is the result of validating a world
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any diagnostic is an error
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of diagnostics with the given severity
+func (d Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, diagnostic := range d {
+		if diagnostic.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Validate checks the world's room graph and content. Errors make the world
+// unplayable: exits into rooms that do not exist, rooms players cannot reach,
+// duplicate object IDs and missing descriptions. Warnings point at likely
+// mistakes, such as exits with no way back or exits not yet connected.
+// Diagnostics are ordered by room ID.
+func (w *World) Validate() Diagnostics {
+	var diagnostics Diagnostics
+	report := func(severity Severity, code, roomID, format string, args ...interface{}) *Diagnostic {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: severity,
+			Code:     code,
+			RoomID:   roomID,
+			Message:  fmt.Sprintf(format, args...),
+		})
+		return &diagnostics[len(diagnostics)-1]
+	}
+
+	objectRooms := make(map[string]string)
+	for _, id := range w.roomIDs() {
+		room := w.Rooms[id]
+		if room == nil {
+			report(SeverityError, CodeMissingDescription, id, "room is empty")
+			continue
+		}
+		if room.ID != id {
+			report(SeverityError, CodeRoomIDMismatch, id, "room is stored under %s but has ID %q", id, room.ID)
+		}
+		if strings.TrimSpace(room.Description) == "" {
+			report(SeverityError, CodeMissingDescription, id, "room has no description")
+		}
+
+		oneWay := room.oneWayExits()
+		for _, direction := range sortedKeys(room.Exits) {
+			target := room.Exits[direction]
+			dir := Direction(direction)
+			if !IsValidDirection(dir) {
+				report(SeverityWarning, CodeUnknownDirection, id, "exit uses unregistered direction %q", direction).Direction = direction
+			}
+			switch targetRoom, ok := w.Rooms[target]; {
+			case target == "" || target == UnexploredExit:
+				// Unexplored exits of a lazy world are fine as long as a frontier room is behind them
+				if w.pendingBehind(id, dir) == nil {
+					report(SeverityWarning, CodePlaceholderExit, id, "exit %s is not connected to a room", direction).Direction = direction
+				}
+			case !ok || targetRoom == nil:
+				report(SeverityError, CodeDanglingExit, id, "exit %s leads to missing room %s", direction, target).Direction = direction
+			case !dir.IsOneWay() && !oneWay[direction] && !targetRoom.leadsTo(id):
+				report(SeverityWarning, CodeOneWayExit, id, "exit %s leads to %s, which has no exit back", direction, target).Direction = direction
+			}
+		}
+
+		for _, object := range room.Objects {
+			if strings.TrimSpace(object.Name) == "" {
+				report(SeverityError, CodeMissingObjectName, id, "object has no name").ObjectID = object.ID
+			}
+			if strings.TrimSpace(object.Description) == "" {
+				report(SeverityWarning, CodeMissingDescription, id, "object %q has no description", object.Name).ObjectID = object.ID
+			}
+			if previous, seen := objectRooms[object.ID]; seen {
+				report(SeverityError, CodeDuplicateObjectID, id, "object ID %s is also used in room %s", object.ID, previous).ObjectID = object.ID
+			} else {
+				objectRooms[object.ID] = id
+			}
+		}
+	}
+
+	if w.Frontier != nil {
+		for _, id := range sortedPendingIDs(w.Frontier.Rooms) {
+			pending := w.Frontier.Rooms[id]
+			if _, generated := w.Rooms[id]; generated {
+				report(SeverityError, CodeRoomIDMismatch, id, "room is both generated and waiting in the frontier")
+			}
+			for _, direction := range sortedKeys(pending.Exits) {
+				target := pending.Exits[direction]
+				_, generated := w.Rooms[target]
+				if _, waiting := w.Frontier.Rooms[target]; !generated && !waiting {
+					report(SeverityError, CodeDanglingExit, id, "unexplored room's exit %s leads to missing room %s", direction, target).Direction = direction
+				}
+			}
+		}
+	}
+
+	diagnostics = append(diagnostics, w.validateReachability()...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].RoomID < diagnostics[j].RoomID
+	})
+	return diagnostics
+}
+
+// validateReachability reports rooms that cannot be reached from the start
+// room. Worlds without a start room must at least be connected.
+func (w *World) validateReachability() Diagnostics {
+	ids := w.roomIDs()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	start, directed := w.Start, true
+	if start == "" {
+		start, directed = ids[0], false
+	} else if _, ok := w.Rooms[start]; !ok {
+		return Diagnostics{{
+			Severity: SeverityError,
+			Code:     CodeInvalidStart,
+			RoomID:   start,
+			Message:  "start room does not exist",
+		}}
+	}
+
+	// Without a start room only rooms cut off entirely are reported
+	reached := make(map[string]bool)
+	if directed {
+		reachable, _ := w.ReachableFrom(start)
+		for _, id := range reachable {
+			reached[id] = true
+		}
+	} else {
+		for _, component := range w.Components() {
+			if component[0] == start {
+				for _, id := range component {
+					reached[id] = true
+				}
+			}
+		}
+	}
+
+	var diagnostics Diagnostics
+	for _, id := range ids {
+		if reached[id] {
+			continue
+		}
+		message := fmt.Sprintf("room cannot be reached from the start room %s", start)
+		if !directed {
+			message = fmt.Sprintf("room is not connected to room %s", start)
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeUnreachableRoom,
+			RoomID:   id,
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// roomIDs returns the IDs of the world's rooms in sorted order
+func (w *World) roomIDs() []string {
+	ids := make([]string, 0, len(w.Rooms))
+	for id := range w.Rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// leadsTo reports whether any of the room's exits leads to the given room
+func (r *Room) leadsTo(id string) bool {
+	for _, target := range r.Exits {
+		if target == id {
+			return true
+		}
+	}
+	return false
+}
+
+// oneWayExits returns the directions listed in the room's PropOneWayExits property.
+// After loading from JSON the property is a []interface{} rather than a []string.
+func (r *Room) oneWayExits() map[string]bool {
+	directions := make(map[string]bool)
+	switch list := r.Properties[PropOneWayExits].(type) {
+	case []string:
+		for _, direction := range list {
+			directions[direction] = true
+		}
+	case []interface{}:
+		for _, direction := range list {
+			if name, ok := direction.(string); ok {
+				directions[name] = true
+			}
+		}
+	}
+	return directions
+}
+
+// sortedPendingIDs returns the IDs of frontier rooms in sorted order
+func sortedPendingIDs(rooms map[string]*PendingRoom) []string {
+	ids := make([]string, 0, len(rooms))
+	for id := range rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedKeys returns the keys of a string map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package worldgen
+
+import (
+	"context"
+	"testing"
+)
+
+// diagnosticCodes returns the codes of the diagnostics, counted
+func diagnosticCodes(diagnostics Diagnostics) map[string]int {
+	codes := make(map[string]int)
+	for _, diagnostic := range diagnostics {
+		codes[diagnostic.Code]++
+	}
+	return codes
+}
+
+func TestValidateGeneratedWorlds(t *testing.T) {
+	for _, name := range TopologyNames() {
+		topology, _ := TopologyByName(name)
+		world, err := NewWorld(11)
+		if err != nil {
+			t.Fatalf("Failed to create world: %v", err)
+		}
+		opts := DefaultGenerateOptions()
+		opts.Topology = topology
+		if err := world.GenerateWorldWithOptions(context.Background(), "A lighthouse", 12, opts); err != nil {
+			t.Fatalf("Failed to generate %s world: %v", name, err)
+		}
+		if diagnostics := world.Validate(); len(diagnostics) > 0 {
+			t.Errorf("Expected a clean %s world, got %v", name, diagnostics)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	world, _ := NewWorld(1)
+	hall := namedScene("hall", "Hall", "A long hall.")
+	study := namedScene("study", "Study", "A quiet study.")
+	attic := namedScene("attic", "Attic", "")
+	cellar := namedScene("cellar", "Cellar", "A damp cellar.")
+	for _, room := range []*Room{hall, study, attic, cellar} {
+		if err := world.AddRoom(room); err != nil {
+			t.Fatalf("Failed to add room: %v", err)
+		}
+	}
+	world.Start = "hall"
+
+	world.ConnectRooms("hall", "study", North)
+	hall.AddExit("east", "")          // placeholder
+	hall.AddExit("west", "garden")    // dangling
+	study.AddExit("up", "attic")      // no way back
+	hall.AddExit("sideways", "study") // unregistered direction
+	attic.AddObject(Object{ID: "obj_1", Name: "trunk", Description: "A trunk."})
+	study.AddObject(Object{ID: "obj_1", Name: "", Description: ""})
+
+	diagnostics := world.Validate()
+	want := map[string]int{
+		CodePlaceholderExit:    1,
+		CodeDanglingExit:       1,
+		CodeOneWayExit:         1, // study up
+		CodeUnknownDirection:   1,
+		CodeMissingDescription: 2, // the attic and the nameless object
+		CodeMissingObjectName:  1,
+		CodeDuplicateObjectID:  1,
+		CodeUnreachableRoom:    1, // the cellar
+	}
+	got := diagnosticCodes(diagnostics)
+	for code, count := range want {
+		if got[code] != count {
+			t.Errorf("Expected %d %s diagnostics, got %d: %v", count, code, got[code], diagnostics)
+		}
+	}
+	if !diagnostics.HasErrors() {
+		t.Error("Expected errors")
+	}
+	for i := 1; i < len(diagnostics); i++ {
+		if diagnostics[i-1].RoomID > diagnostics[i].RoomID {
+			t.Errorf("Expected diagnostics ordered by room, got %v", diagnostics)
+		}
+	}
+
+	// Marked one-way exits are intended
+	delete(study.Exits, "up")
+	if err := world.ConnectOneWay("study", "attic", Up); err != nil {
+		t.Fatalf("ConnectOneWay failed: %v", err)
+	}
+	if got := diagnosticCodes(world.Validate()); got[CodeOneWayExit] != 0 {
+		t.Errorf("Expected the marked one-way exit to be accepted, got %v", got)
+	}
+
+	world.Start = "nowhere"
+	if got := diagnosticCodes(world.Validate()); got[CodeInvalidStart] != 1 {
+		t.Errorf("Expected an invalid start room, got %v", got)
+	}
+}
+
+func TestValidateWithoutStart(t *testing.T) {
+	world, _ := NewWorld(1)
+	if err := world.AddRoom(namedScene("a", "A", "Room A.")); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if err := world.AddRoom(namedScene("b", "B", "Room B.")); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if err := world.AddRoom(namedScene("c", "C", "Room C.")); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	world.ConnectOneWay("b", "a", North)
+
+	// Without a start room only rooms cut off entirely are unreachable
+	diagnostics := world.Validate()
+	if got := diagnosticCodes(diagnostics); len(diagnostics) != 1 || got[CodeUnreachableRoom] != 1 || diagnostics[0].RoomID != "c" {
+		t.Errorf("Expected only room c to be unreachable, got %v", diagnostics)
+	}
+}
@@ -3,49 +3,146 @@ package worldgen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"textadventureservices/services/usage"
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/logging"
 )
 
 // World represents the entire game world
 type World struct {
-	Seed   int64             `json:"seed"`
-	Rooms  map[string]*Room  `json:"rooms"`
-	Scenes map[string]*Scene `json:"scenes"` // Added for compatibility with e2e tests
-	rng    *rand.Rand        `json:"-"`
+	Seed  int64            `json:"seed"`
+	Rooms map[string]*Room `json:"rooms"`
+	// Start is the ID of the room players begin in
+	Start string `json:"start,omitempty"`
+	// Regions are the themed zones the rooms are split into
+	Regions []Region `json:"regions,omitempty"`
+	// Frontier holds the rooms of a lazily generated world that are laid out
+	// but not generated yet; nil once every room has been explored
+	Frontier *Frontier  `json:"frontier,omitempty"`
+	rng      *rand.Rand `json:"-"`
+
+	// provider describes generated rooms; when nil the prompts are used as descriptions
+	provider ai.Provider
+	// logger receives the world's diagnostics; when nil nothing is logged
+	logger logging.Logger
 }
 
 // NewWorld creates a new world instance with the given seed
 func NewWorld(seed int64) (*World, error) {
 	world := &World{
-		Seed:   seed,
-		Rooms:  make(map[string]*Room),
-		Scenes: make(map[string]*Scene), // Initialize Scenes map
-		rng:    rand.New(rand.NewSource(seed)),
+		Seed:  seed,
+		Rooms: make(map[string]*Room),
+		rng:   rand.New(rand.NewSource(seed)),
 	}
 
 	// No longer create initial room here as it's handled by GenerateWorld
 	return world, nil
 }
 
-// AddRoom adds a room to the world and updates the scenes map
-func (w *World) AddRoom(room *Room) {
+// SetProvider sets the AI provider used to describe generated rooms.
+// Wrap the provider in an ai.ReplayProvider to make AI text reproducible.
+func (w *World) SetProvider(provider ai.Provider) {
+	w.provider = provider
+}
+
+// aiProvider returns the provider used for this world
+func (w *World) aiProvider() ai.Provider {
+	return w.provider
+}
+
+// SetLogger sets the logger that receives the world's diagnostics. Worlds
+// log nothing until a logger is set.
+func (w *World) SetLogger(logger logging.Logger) {
+	w.logger = logger
+}
+
+// log returns the logger used for this world
+func (w *World) log() logging.Logger {
+	if w.logger == nil {
+		return logging.NewNoopLogger()
+	}
+	return w.logger
+}
+
+// NewRoom creates a room with the given description and a seed-derived ID.
+// Unlike GenerateWorld it does not ask the AI provider to enhance the description.
+func (w *World) NewRoom(description string, exits []string) (*Room, error) {
+	if description == "" {
+		return nil, fmt.Errorf("room description cannot be empty")
+	}
+
+	return newRoomWithID(w.newRoomID(), description, exits), nil
+}
+
+// newRoomWithID creates a room whose exits are all unexplored
+func newRoomWithID(id, description string, exits []string) *Room {
+	exitMap := make(map[string]string)
+	for _, exit := range exits {
+		exitMap[exit] = UnexploredExit // Filled in once the room behind it exists
+	}
+
+	return &Room{
+		ID:          id,
+		Description: description,
+		Objects:     make([]Object, 0),
+		Exits:       exitMap,
+		Properties:  make(map[string]interface{}),
+	}
+}
+
+// newRoomID returns the next seed-derived room ID that no room of the world
+// uses yet. A loaded world draws from its seed again, so the IDs it gave out
+// before it was saved are skipped.
+func (w *World) newRoomID() string {
+	for {
+		id := fmt.Sprintf("room_%d", w.rng.Int63())
+		if !w.hasRoomID(id) {
+			return id
+		}
+	}
+}
+
+// hasRoomID reports whether a room or a pending room of the world has the ID
+func (w *World) hasRoomID(id string) bool {
+	if _, ok := w.Rooms[id]; ok {
+		return true
+	}
+	if w.Frontier != nil {
+		if _, ok := w.Frontier.Rooms[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRoom adds a room to the world. It fails with ErrDuplicateRoom instead of
+// replacing a room that has the same ID.
+func (w *World) AddRoom(room *Room) error {
 	if w.Rooms == nil {
 		w.Rooms = make(map[string]*Room)
 	}
-	w.Rooms[room.ID] = room
-	// Convert room to scene and add to scenes map
-	scene := &Scene{
-		ID:          room.ID,
-		Description: room.Description,
-		Objects:     room.Objects,
-		Exits:       room.Exits,
-		Properties:  room.Properties,
+	if w.hasRoomID(room.ID) {
+		return fmt.Errorf("%w: %s", ErrDuplicateRoom, room.ID)
 	}
-	w.Scenes[room.ID] = scene
+	w.Rooms[room.ID] = room
+	return nil
 }
 
+var (
+	// ErrRoomNotFound is returned when a room ID does not exist in the world
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrDuplicateRoom is returned when a room is added with an ID the world already uses
+	ErrDuplicateRoom = errors.New("duplicate room ID")
+)
+
 // GetRoom returns a room by its ID
 func (w *World) GetRoom(id string) (*Room, bool) {
 	if w.Rooms == nil {
@@ -55,23 +152,65 @@ func (w *World) GetRoom(id string) (*Room, bool) {
 	return room, ok
 }
 
-// GetScene returns a scene by its ID
-func (w *World) GetScene(id string) (*Scene, error) {
-	if w.Scenes == nil {
-		return nil, fmt.Errorf("scenes map is not initialized")
-	}
-	
-	scene, exists := w.Scenes[id]
-	if !exists {
-		return nil, fmt.Errorf("scene with ID %s not found", id)
+// GenerateOptions tunes how GenerateWorldWithOptions lays out a world
+type GenerateOptions struct {
+	// Directions are the exits new rooms can be attached through.
+	// Include Up and Down to grow multi-level buildings.
+	Directions []Direction
+	// LoopChance is the probability of linking a new room to each adjacent
+	// room it was not grown from, which closes loops in the map
+	LoopChance float64
+	// Topology decides the shape of the map; nil uses SprawlTopology
+	Topology TopologyStrategy
+	// Regions split the world into themed zones joined by single gateways.
+	// Without regions every room is themed by the base prompt alone.
+	Regions []Region
+	// MaxObjects caps the objects generated per room; 0 uses DefaultMaxObjects
+	// and a negative value leaves rooms empty
+	MaxObjects int
+	// Structured asks providers that support it for rooms as JSON documents
+	// with a name, objects, exit descriptions and ambient details. Rooms fall
+	// back to a prose description when the provider cannot deliver one.
+	Structured bool
+	// Lazy lays out the whole world but only generates the start room. The
+	// other rooms wait in the world's Frontier until Explore reaches them.
+	Lazy bool
+	// Concurrency caps the rooms generated at once; 0 uses DefaultConcurrency.
+	// The provider must be safe for concurrent use when it is above 1.
+	Concurrency int
+	// Retries is how often a room whose generation fails is tried again;
+	// 0 uses DefaultRoomRetries and a negative value never retries
+	Retries int
+	// RetryDelay is the wait before the first retry; 0 uses DefaultRetryDelay
+	RetryDelay time.Duration
+	// RateLimiter, when set, is waited for before every attempt at a room
+	RateLimiter Limiter
+	// Progress, when set, is called after every attempt at a room. Calls
+	// never overlap.
+	Progress func(Progress)
+}
+
+// DefaultGenerateOptions returns the options used by GenerateWorld
+func DefaultGenerateOptions() GenerateOptions {
+	return GenerateOptions{
+		Directions: CardinalDirections,
+		LoopChance: 0.25,
+		Topology:   SprawlTopology{},
 	}
-	return scene, nil
 }
 
 // GenerateWorld creates a multi-room world based on a base prompt
 func (w *World) GenerateWorld(basePrompt string, numRooms int) error {
-	fmt.Printf("Generating world with prompt '%s' and %d rooms...\n", basePrompt, numRooms)
-	
+	return w.GenerateWorldWithOptions(context.Background(), basePrompt, numRooms, DefaultGenerateOptions())
+}
+
+// GenerateWorldWithOptions creates a multi-room world based on a base prompt.
+// Rooms are first laid out on a 3D grid, so no two rooms share a coordinate
+// and every exit agrees with the geometry, then each room is generated.
+func (w *World) GenerateWorldWithOptions(ctx context.Context, basePrompt string, numRooms int, opts GenerateOptions) error {
+	ctx = logging.WithFields(ctx, logging.Fields{"seed": w.Seed})
+	w.log().Info(logging.WithFields(ctx, logging.Fields{"prompt": basePrompt, "rooms": numRooms}), "Generating world")
+
 	if numRooms <= 0 {
 		return fmt.Errorf("number of rooms must be positive")
 	}
@@ -80,101 +219,232 @@ func (w *World) GenerateWorld(basePrompt string, numRooms int) error {
 		return fmt.Errorf("base prompt cannot be empty")
 	}
 
+	directions := opts.Directions
+	if len(directions) == 0 {
+		directions = CardinalDirections
+	}
+	for _, dir := range directions {
+		if !IsValidDirection(dir) {
+			return fmt.Errorf("unknown direction: %q", dir)
+		}
+		if dir.IsOneWay() {
+			return fmt.Errorf("one-way direction %q cannot be used for generation", dir)
+		}
+	}
+
+	if err := validateRegions(opts.Regions); err != nil {
+		return err
+	}
+
+	opts.Directions = directions
+	topology := opts.Topology
+	if topology == nil {
+		topology = SprawlTopology{}
+	}
+
+	layout, err := topology.Layout(w.rng, numRooms, opts)
+	if err != nil {
+		return fmt.Errorf("failed to lay out world: %w", err)
+	}
+	if layout.Len() != numRooms {
+		return fmt.Errorf("%s topology laid out %d rooms instead of %d", topology.Name(), layout.Len(), numRooms)
+	}
+	assignRegions(layout, opts.Regions)
+	w.Regions = opts.Regions
+	w.log().Info(logging.WithFields(ctx, logging.Fields{"rooms": layout.Len(), "connections": len(layout.Edges), "topology": topology.Name()}), "Laid out world")
+
+	if opts.MaxObjects == 0 {
+		opts.MaxObjects = DefaultMaxObjects
+	}
+	return w.buildFromLayout(ctx, basePrompt, layout, opts)
+}
+
+// buildFromLayout generates one room per layout node, fills it with up to
+// opts.MaxObjects objects and connects the rooms along the layout edges.
+// Rooms are generated concurrently, see generateRooms.
+// With opts.Lazy only the first node is generated and the rest become the
+// world's frontier.
+func (w *World) buildFromLayout(ctx context.Context, basePrompt string, layout *Layout, opts GenerateOptions) error {
 	// Clear any existing rooms
 	w.Rooms = make(map[string]*Room)
-	w.Scenes = make(map[string]*Scene)
+	w.Start = ""
+	w.Frontier = nil
+
+	// Rooms with a passage into another region mention it in their prompt
+	borders := make(map[int][]string)
+	for _, edge := range layout.Edges {
+		from, to := layout.Nodes[edge.From], layout.Nodes[edge.To]
+		if from.Region != to.Region {
+			borders[edge.From] = append(borders[edge.From], to.Region)
+			borders[edge.To] = append(borders[edge.To], from.Region)
+		}
+	}
 
-	// Create the first room (central hub)
-	fmt.Println("Generating central hub room...")
-	centralRoom, err := w.generateRoom(fmt.Sprintf("%s - Central Hub", basePrompt), nil) // Start with no exits
-	if err != nil {
-		return fmt.Errorf("failed to generate central room: %w", err)
-	}
-	w.AddRoom(centralRoom)
-	fmt.Printf("Created central room with ID: %s\n", centralRoom.ID)
-
-	// Generate additional rooms and connect them
-	directions := []Direction{North, South, East, West}
-	remainingRooms := numRooms - 1 // Subtract 1 for the central room
-	currentRooms := []*Room{centralRoom}
-
-	fmt.Printf("Generating %d additional rooms...\n", remainingRooms)
-	attemptLimit := numRooms * 10 // Prevent infinite loops
-	attempts := 0
-
-	for remainingRooms > 0 && attempts < attemptLimit {
-		attempts++
-		// Pick a random existing room to connect from
-		sourceRoom := currentRooms[w.rng.Intn(len(currentRooms))]
-		fmt.Printf("Selected source room: %s\n", sourceRoom.ID)
-		
-		// Pick a random available direction
-		var availableDirections []Direction
-		for _, dir := range directions {
-			if targetID, exists := sourceRoom.Exits[string(dir)]; !exists || targetID == "" {
-				availableDirections = append(availableDirections, dir)
+	if opts.Lazy {
+		w.Frontier = &Frontier{MaxObjects: opts.MaxObjects, Structured: opts.Structured, Rooms: make(map[string]*PendingRoom)}
+	}
+	// IDs and prompts are worked out in layout order before any room is
+	// generated, so they do not depend on the order rooms finish in
+	ids := make([]string, layout.Len())
+	var jobs []roomJob
+	for index, node := range layout.Nodes {
+		roomPrompt := w.nodePrompt(basePrompt, node, borders[index])
+		ids[index] = w.newRoomID()
+		if opts.Lazy && index > 0 {
+			w.Frontier.Rooms[ids[index]] = &PendingRoom{
+				ID:     ids[index],
+				Prompt: roomPrompt,
+				Coord:  node.Coord,
+				Region: node.Region,
+				Exits:  make(map[string]string),
 			}
+			continue
 		}
+		jobs = append(jobs, roomJob{
+			id:     ids[index],
+			prompt: roomPrompt,
+			exits:  layout.exitDirections(index),
+			coord:  node.Coord,
+			region: node.Region,
+		})
+	}
 
-		if len(availableDirections) == 0 {
-			fmt.Printf("No available directions from room %s, trying another room...\n", sourceRoom.ID)
-			continue // Try another room if this one is fully connected
+	rooms, err := w.generateRooms(ctx, jobs, opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate room: %w", err)
+	}
+	for _, room := range rooms {
+		if err := w.AddRoom(room); err != nil {
+			return err
 		}
+		w.log().Debug(logging.WithFields(ctx, logging.Fields{"room": room.ID}), "Created room")
+	}
+	w.Start = ids[0]
 
-		// Choose a random direction and create a new room
-		direction := availableDirections[w.rng.Intn(len(availableDirections))]
-		oppositeDir := direction.GetOppositeDirection()
-		fmt.Printf("Selected direction: %s (opposite: %s)\n", direction, oppositeDir)
-
-		// Generate a themed room based on its direction from the center
-		roomPrompt := generateDirectionalPrompt(basePrompt, string(direction))
-		fmt.Printf("Generating room with prompt: %s\n", roomPrompt)
-		
-		newRoom, err := w.generateRoom(roomPrompt, []string{string(oppositeDir)})
-		if err != nil {
-			return fmt.Errorf("failed to generate room: %w", err)
+	for _, edge := range layout.Edges {
+		from, to := ids[edge.From], ids[edge.To]
+		if opts.Lazy {
+			w.linkLazy(from, to, edge.Direction)
+			continue
 		}
-		fmt.Printf("Created new room with ID: %s\n", newRoom.ID)
-
-		// Connect the rooms bidirectionally
-		if err := w.ConnectRooms(sourceRoom.ID, newRoom.ID, direction); err != nil {
+		if err := w.ConnectRooms(from, to, edge.Direction); err != nil {
 			return fmt.Errorf("failed to connect rooms: %w", err)
 		}
-		fmt.Printf("Connected rooms: %s -%s-> %s\n", sourceRoom.ID, direction, newRoom.ID)
+		w.log().Debug(logging.WithFields(ctx, logging.Fields{"room": from, "target": to, "direction": string(edge.Direction)}), "Connected rooms")
+	}
 
-		w.AddRoom(newRoom)
-		currentRooms = append(currentRooms, newRoom)
-		remainingRooms--
-		fmt.Printf("Remaining rooms to generate: %d\n", remainingRooms)
+	w.log().Info(logging.WithFields(ctx, logging.Fields{"rooms": len(w.Rooms)}), "World generation complete")
+	return nil
+}
+
+// nodePrompt builds the prompt for a laid out room from its place in the
+// layout and its region, picking one of the region's moods at random
+func (w *World) nodePrompt(basePrompt string, node LayoutNode, borders []string) string {
+	// Generate a themed room based on its direction from the room it grew from
+	roomPrompt := fmt.Sprintf("%s - Central Hub", basePrompt)
+	if node.Parent >= 0 {
+		roomPrompt = generateDirectionalPrompt(basePrompt, string(node.Via))
+	}
+	if label := kindLabel(node.Kind); label != "" {
+		roomPrompt = fmt.Sprintf("%s - %s", basePrompt, label)
 	}
+	if node.Coord.Z != 0 {
+		roomPrompt = fmt.Sprintf("%s (%s)", roomPrompt, levelName(node.Coord.Z))
+	}
+	if region, ok := w.Region(node.Region); ok {
+		var mood string
+		if len(region.Moods) > 0 {
+			mood = region.Moods[w.rng.Intn(len(region.Moods))]
+		}
+		roomPrompt = fmt.Sprintf("%s, %s", roomPrompt, regionContext(region, mood, borders))
+	}
+	return roomPrompt
+}
 
-	if remainingRooms > 0 {
-		return fmt.Errorf("failed to generate all rooms after %d attempts", attemptLimit)
+// generatePlacedRoom generates a laid out room, as a structured room when
+// asked for and supported, and fills it with objects. When the provider fails
+// to describe the room, the prompt is used if fallback is set. The provider
+// is called with the room's ID in the context, so replay keeps the responses
+// of rooms with the same prompt apart.
+func (w *World) generatePlacedRoom(ctx context.Context, job roomJob, maxObjects int, structured bool, fallback bool) (*Room, error) {
+	ctx = ai.WithRoom(ctx, job.id)
+	var spec *ai.RoomSpec
+	if structured {
+		spec = w.generateRoomSpec(ctx, job.prompt, job.exits)
 	}
 
-	fmt.Println("World generation complete!")
-	return nil
+	var room *Room
+	if spec != nil {
+		room = w.roomFromSpec(job.id, spec, maxObjects)
+	} else {
+		description, err := w.generateDescription(ctx, job.prompt)
+		if err != nil {
+			if !fallback || ctx.Err() != nil || errors.Is(err, usage.ErrBudgetExceeded) {
+				return nil, fmt.Errorf("failed to describe room: %w", err)
+			}
+			description = job.prompt
+		}
+		room = newRoomWithID(job.id, description, nil)
+	}
+	if strings.TrimSpace(room.Description) == "" {
+		return nil, fmt.Errorf("room description cannot be empty")
+	}
+	coord := job.coord
+	room.Coord = &coord
+	room.Region = job.region
+	if maxObjects > 0 && len(room.Objects) == 0 {
+		region, _ := w.Region(job.region)
+		room.Objects = w.generateObjects(ctx, room, region, maxObjects, w.roomRand(job.id))
+	}
+	return room, nil
 }
 
-// ConnectRooms connects two rooms bidirectionally
+// ConnectRooms connects two rooms, bidirectionally unless the direction is one-way
 func (w *World) ConnectRooms(sourceID string, targetID string, direction Direction) error {
+	info, ok := GetDirectionInfo(direction)
+	if !ok {
+		return fmt.Errorf("unknown direction: %q", direction)
+	}
+
 	sourceRoom, ok := w.GetRoom(sourceID)
 	if !ok {
-		return fmt.Errorf("source room %s not found", sourceID)
+		return fmt.Errorf("source room: %w: %s", ErrRoomNotFound, sourceID)
 	}
 
 	targetRoom, ok := w.GetRoom(targetID)
 	if !ok {
-		return fmt.Errorf("target room %s not found", targetID)
+		return fmt.Errorf("target room: %w: %s", ErrRoomNotFound, targetID)
 	}
 
-	// Get opposite direction
-	oppositeDir := direction.GetOppositeDirection()
+	sourceRoom.AddExit(string(direction), targetID)
+	if !info.OneWay {
+		targetRoom.AddExit(string(info.Opposite), sourceID)
+	}
 
-	// Connect rooms bidirectionally
-	sourceRoom.Exits[string(direction)] = targetID
-	targetRoom.Exits[string(oppositeDir)] = sourceID
+	return nil
+}
+
+// ConnectOneWay adds an exit from the source room without a way back and
+// records it in PropOneWayExits, so Validate knows it is intended
+func (w *World) ConnectOneWay(sourceID string, targetID string, direction Direction) error {
+	sourceRoom, ok := w.GetRoom(sourceID)
+	if !ok {
+		return fmt.Errorf("source room: %w: %s", ErrRoomNotFound, sourceID)
+	}
+	if _, ok := w.GetRoom(targetID); !ok {
+		return fmt.Errorf("target room: %w: %s", ErrRoomNotFound, targetID)
+	}
 
+	sourceRoom.AddExit(string(direction), targetID)
+	if !sourceRoom.oneWayExits()[string(direction)] {
+		var directions []string
+		for existing := range sourceRoom.oneWayExits() {
+			directions = append(directions, existing)
+		}
+		directions = append(directions, string(direction))
+		sort.Strings(directions)
+		sourceRoom.SetProperty(PropOneWayExits, directions)
+	}
 	return nil
 }
 
@@ -195,43 +465,85 @@ func generateDirectionalPrompt(basePrompt string, direction string) string {
 		return fmt.Sprintf("%s - Eastern Wing", basePrompt)
 	case "west":
 		return fmt.Sprintf("%s - Western Wing", basePrompt)
+	case "northeast":
+		return fmt.Sprintf("%s - Northeastern Wing", basePrompt)
+	case "northwest":
+		return fmt.Sprintf("%s - Northwestern Wing", basePrompt)
+	case "southeast":
+		return fmt.Sprintf("%s - Southeastern Wing", basePrompt)
+	case "southwest":
+		return fmt.Sprintf("%s - Southwestern Wing", basePrompt)
+	case "up":
+		return fmt.Sprintf("%s - Upper Floor", basePrompt)
+	case "down":
+		return fmt.Sprintf("%s - Cellar", basePrompt)
 	default:
 		return basePrompt
 	}
 }
 
+// kindLabel names the kind of place a layout node stands for
+func kindLabel(kind string) string {
+	switch kind {
+	case KindHub:
+		return "Central Hub"
+	case KindCorridor:
+		return "Narrow Corridor"
+	case KindChamber:
+		return "Chamber"
+	case KindCave:
+		return "Cavern"
+	default:
+		return ""
+	}
+}
+
+// levelName describes a floor relative to the ground level
+func levelName(z int) string {
+	switch {
+	case z > 0:
+		return fmt.Sprintf("floor %d above ground", z)
+	case z < 0:
+		return fmt.Sprintf("level %d below ground", -z)
+	default:
+		return "ground floor"
+	}
+}
+
 // generateRoom creates a new room using the world's RNG
 func (w *World) generateRoom(description string, exits []string) (*Room, error) {
 	if description == "" {
 		return nil, fmt.Errorf("room description cannot be empty")
 	}
+	return w.NewRoom(w.describeRoom(context.Background(), description), exits)
+}
 
-	// Enhance the description using AI if available
-	enhancedDesc := description
-	if aiProvider != nil {
-		desc, err := aiProvider.GenerateDescription(context.Background(), description)
-		if err != nil {
-			fmt.Printf("Warning: Failed to enhance description: %v\n", err)
-		} else {
-			enhancedDesc = desc
-		}
+// describeRoom enhances a room prompt using AI if available, falling back to the prompt itself
+func (w *World) describeRoom(ctx context.Context, prompt string) string {
+	desc, err := w.generateDescription(ctx, prompt)
+	if err != nil {
+		return prompt
 	}
+	return desc
+}
 
-	// Create a map of exits, but don't fill in the target IDs yet
-	exitMap := make(map[string]string)
-	for _, exit := range exits {
-		exitMap[exit] = "" // Leave target ID empty, to be filled in later
+// generateDescription asks the provider to describe a room. Without a
+// provider, or when the answer is empty, the prompt is the description.
+func (w *World) generateDescription(ctx context.Context, prompt string) (string, error) {
+	provider := w.aiProvider()
+	if provider == nil {
+		return prompt, nil
 	}
-
-	room := &Room{
-		ID:          fmt.Sprintf("room_%d", w.rng.Int63()),
-		Description: enhancedDesc,
-		Objects:     make([]Object, 0),
-		Exits:       exitMap,
-		Properties:  make(map[string]interface{}),
+	start := time.Now()
+	desc, err := provider.GenerateDescription(ctx, prompt)
+	w.logCall(ctx, "describe room", start, err)
+	if err != nil {
+		return "", err
 	}
-
-	return room, nil
+	if desc == "" {
+		return prompt, nil
+	}
+	return desc, nil
 }
 
 // Save writes the world to a JSON file
@@ -259,25 +571,70 @@ func LoadWorld(filename string) (*World, error) {
 	if err := json.Unmarshal(data, &world); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal world: %w", err)
 	}
+	return &world, nil
+}
+
+// MarshalJSON writes the world with a formatVersion header
+func (w World) MarshalJSON() ([]byte, error) {
+	type plain World
+	return json.Marshal(struct {
+		FormatVersion int `json:"formatVersion"`
+		plain
+	}{FormatVersion, plain(w)})
+}
+
+// UnmarshalJSON upgrades older world formats with MigrateWorldJSON, decodes
+// the world and recreates its RNG from the saved seed. New room IDs skip the
+// ones the world already uses, see newRoomID.
+func (w *World) UnmarshalJSON(data []byte) error {
+	data, _, err := MigrateWorldJSON(data)
+	if err != nil {
+		return err
+	}
 
-	// Recreate the RNG with the saved seed
-	world.rng = rand.New(rand.NewSource(world.Seed))
+	type plain World
+	var decoded struct {
+		FormatVersion int `json:"formatVersion"`
+		plain
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
 
-	return &world, nil
+	*w = World(decoded.plain)
+	if w.Rooms == nil {
+		w.Rooms = make(map[string]*Room)
+	}
+	w.rng = rand.New(rand.NewSource(w.Seed))
+	return nil
 }
 
-// generateID creates a unique ID for a room
-// In a real implementation, this would use a more sophisticated ID generation method
-func generateID() string {
-	return fmt.Sprintf("room_%d", rand.Int63())
+// logCall logs a call to the world's provider, see logProviderCall
+func (w *World) logCall(ctx context.Context, call string, start time.Time, err error) {
+	logProviderCall(ctx, w.log(), w.aiProvider(), call, start, err)
+}
+
+// logProviderCall logs a call to a provider with its latency: failures as
+// warnings and the rest at debug level
+func logProviderCall(ctx context.Context, logger logging.Logger, provider ai.Provider, call string, start time.Time, err error) {
+	fields := logging.Fields{
+		"call":       call,
+		"provider":   providerName(provider),
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.Warn(logging.WithFields(ctx, fields), "Provider call failed")
+		return
+	}
+	logger.Debug(logging.WithFields(ctx, fields), "Provider call finished")
 }
 
-// generateRandomString creates a random string of the specified length
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+// providerName names a provider in logs by its type, and its model when it reports one
+func providerName(provider ai.Provider) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", provider), "*")
+	if named, ok := provider.(interface{ Model() string }); ok && named.Model() != "" {
+		name += "/" + named.Model()
 	}
-	return string(b)
+	return name
 }
@@ -124,7 +124,9 @@ func TestWorldGenRoomConnections(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to generate central room: %v", err)
 	}
-	world.AddRoom(rooms[0])
+	if err := world.AddRoom(rooms[0]); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
 
 	// Create and connect rooms in each direction
 	for i, dir := range directions {
@@ -135,7 +137,9 @@ func TestWorldGenRoomConnections(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to generate room for direction %s: %v", dir, err)
 		}
-		world.AddRoom(rooms[i+1])
+		if err := world.AddRoom(rooms[i+1]); err != nil {
+			t.Fatalf("Failed to add room: %v", err)
+		}
 
 		// Connect rooms
 		rooms[0].Exits[dir] = rooms[i+1].ID
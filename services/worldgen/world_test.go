@@ -1,10 +1,47 @@
 package worldgen
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+
+	"textadventureservices/services/worldgen/ai"
+	"textadventureservices/services/worldgen/logging"
 )
 
+// countingProvider returns different text on every call, like a real model would
+type countingProvider struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *countingProvider) Initialize(config ai.ProviderConfig) error { return nil }
+
+func (p *countingProvider) EnhancePrompt(ctx context.Context, basePrompt string) (string, error) {
+	return basePrompt, nil
+}
+
+func (p *countingProvider) GenerateObjects(ctx context.Context, sceneDescription string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return []string{fmt.Sprintf("lamp %d", p.calls)}, nil
+}
+
+func (p *countingProvider) GenerateDescription(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return fmt.Sprintf("%s (take %d)", prompt, p.calls), nil
+}
+
 func TestNewWorld(t *testing.T) {
 	seed := int64(42)
 	world, err := NewWorld(seed)
@@ -39,7 +76,9 @@ func TestAddRoom(t *testing.T) {
 		Properties:  make(map[string]interface{}),
 	}
 
-	world.AddRoom(room)
+	if err := world.AddRoom(room); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
 
 	if len(world.Rooms) != 1 {
 		t.Errorf("Expected 1 room, got %d", len(world.Rooms))
@@ -50,6 +89,63 @@ func TestAddRoom(t *testing.T) {
 	}
 }
 
+func TestAddRoomRejectsDuplicateID(t *testing.T) {
+	world, err := NewWorld(42)
+	if err != nil {
+		t.Fatalf("Failed to create new world: %v", err)
+	}
+
+	// Rooms from the same input get the same content-derived ID
+	first, _ := GenerateRoom("A dusty cellar", []string{"north"})
+	second, _ := GenerateRoom("A dusty cellar", []string{"north"})
+	if err := world.AddRoom(first); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if err := world.AddRoom(second); !errors.Is(err, ErrDuplicateRoom) {
+		t.Errorf("Expected ErrDuplicateRoom, got %v", err)
+	}
+	if world.Rooms[first.ID] != first {
+		t.Error("Expected the first room to be kept")
+	}
+}
+
+func TestLoadedWorldDoesNotReuseRoomIDs(t *testing.T) {
+	world, err := NewWorld(42)
+	if err != nil {
+		t.Fatalf("Failed to create new world: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		room, err := world.NewRoom(fmt.Sprintf("Room %d", i), nil)
+		if err != nil {
+			t.Fatalf("Failed to create room: %v", err)
+		}
+		if err := world.AddRoom(room); err != nil {
+			t.Fatalf("Failed to add room: %v", err)
+		}
+	}
+
+	data, err := json.Marshal(world)
+	if err != nil {
+		t.Fatalf("Failed to marshal world: %v", err)
+	}
+	var loaded World
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Failed to unmarshal world: %v", err)
+	}
+
+	// The loaded world draws from the same seed again
+	room, err := loaded.NewRoom("Room 3", nil)
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	if err := loaded.AddRoom(room); err != nil {
+		t.Fatalf("Expected a fresh room ID after loading, got %v", err)
+	}
+	if len(loaded.Rooms) != 4 {
+		t.Errorf("Expected 4 rooms, got %d", len(loaded.Rooms))
+	}
+}
+
 func TestConnectRooms(t *testing.T) {
 	world, err := NewWorld(42)
 	if err != nil {
@@ -71,8 +167,12 @@ func TestConnectRooms(t *testing.T) {
 		Properties:  make(map[string]interface{}),
 	}
 
-	world.AddRoom(room1)
-	world.AddRoom(room2)
+	if err := world.AddRoom(room1); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
+	if err := world.AddRoom(room2); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
 
 	err = world.ConnectRooms(room1.ID, room2.ID, North)
 	if err != nil {
@@ -172,15 +272,16 @@ func TestSaveAndLoadWorld(t *testing.T) {
 			"temperature": 20.5,
 		},
 	}
-	world.AddRoom(room)
+	if err := world.AddRoom(room); err != nil {
+		t.Fatalf("Failed to add room: %v", err)
+	}
 
 	// Save the world
-	filename := "test_world.json"
+	filename := filepath.Join(t.TempDir(), "test_world.json")
 	err = world.Save(filename)
 	if err != nil {
 		t.Fatalf("Failed to save world: %v", err)
 	}
-	defer os.Remove(filename) // Clean up after test
 
 	// Load the world
 	loadedWorld, err := LoadWorld(filename)
@@ -246,3 +347,156 @@ func TestSaveAndLoadWorld(t *testing.T) {
 		t.Errorf("Expected room property 'temperature' to be 20.5")
 	}
 }
+
+func TestGenerateWorldIsDeterministic(t *testing.T) {
+	generate := func() []byte {
+		world, err := NewWorld(7)
+		if err != nil {
+			t.Fatalf("Failed to create new world: %v", err)
+		}
+		if err := world.GenerateWorld("A sunken library", 8); err != nil {
+			t.Fatalf("Failed to generate world: %v", err)
+		}
+		filename := filepath.Join(t.TempDir(), "world.json")
+		if err := world.Save(filename); err != nil {
+			t.Fatalf("Failed to save world: %v", err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read world: %v", err)
+		}
+		return data
+	}
+
+	first, second := generate(), generate()
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected identical world JSON for the same seed\nfirst:  %s\nsecond: %s", first, second)
+	}
+}
+
+func TestGenerateWorldRecordAndReplay(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "responses.json")
+
+	generate := func(inner ai.Provider, mode ai.ReplayMode) []byte {
+		store, err := ai.LoadResponseStore(storeFile)
+		if err != nil {
+			t.Fatalf("Failed to load response store: %v", err)
+		}
+		world, err := NewWorld(99)
+		if err != nil {
+			t.Fatalf("Failed to create new world: %v", err)
+		}
+		world.SetProvider(ai.NewReplayProvider(inner, store, world.Seed, "test-model", mode))
+		if err := world.GenerateWorld("A haunted lighthouse", 5); err != nil {
+			t.Fatalf("Failed to generate world: %v", err)
+		}
+		if err := store.Save(storeFile); err != nil {
+			t.Fatalf("Failed to save response store: %v", err)
+		}
+		filename := filepath.Join(t.TempDir(), "world.json")
+		if err := world.Save(filename); err != nil {
+			t.Fatalf("Failed to save world: %v", err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read world: %v", err)
+		}
+		return data
+	}
+
+	recorded := generate(&countingProvider{}, ai.ReplayRecord)
+	if !strings.Contains(string(recorded), "(take 1)") {
+		t.Fatalf("Expected recorded world to contain provider text, got %s", recorded)
+	}
+
+	// A fresh provider would produce different text, so replay must not call it
+	replayed := generate(nil, ai.ReplayReplay)
+	if !bytes.Equal(recorded, replayed) {
+		t.Errorf("Expected replayed world to match recorded world\nrecorded: %s\nreplayed: %s", recorded, replayed)
+	}
+}
+
+func TestReplayReproducesConcurrentWorld(t *testing.T) {
+	store := ai.NewResponseStore()
+	generate := func(inner ai.Provider, mode ai.ReplayMode) []byte {
+		world, err := NewWorld(99)
+		if err != nil {
+			t.Fatalf("Failed to create new world: %v", err)
+		}
+		world.SetProvider(ai.NewReplayProvider(inner, store, world.Seed, "test-model", mode))
+		opts := DefaultGenerateOptions()
+		opts.Concurrency = 4
+		if err := world.GenerateWorldWithOptions(context.Background(), "A haunted lighthouse", 20, opts); err != nil {
+			t.Fatalf("Failed to generate world: %v", err)
+		}
+		data, err := json.Marshal(world)
+		if err != nil {
+			t.Fatalf("Failed to marshal world: %v", err)
+		}
+		return data
+	}
+
+	// Rooms share prompts, and the counting provider answers each call
+	// differently, so rooms overwriting each other's responses would show
+	recorded := generate(&countingProvider{}, ai.ReplayRecord)
+	replayed := generate(nil, ai.ReplayReplay)
+	if !bytes.Equal(recorded, replayed) {
+		t.Errorf("Expected replayed world to match recorded world\nrecorded: %s\nreplayed: %s", recorded, replayed)
+	}
+}
+
+func TestReplayProviderMiss(t *testing.T) {
+	provider := ai.NewReplayProvider(nil, ai.NewResponseStore(), 1, "test-model", ai.ReplayReplay)
+	_, err := provider.GenerateDescription(context.Background(), "unrecorded prompt")
+	if !errors.Is(err, ai.ErrReplayMiss) {
+		t.Errorf("Expected ErrReplayMiss, got %v", err)
+	}
+}
+
+func TestGeneratorOffline(t *testing.T) {
+	// Nothing is configured at import time; a nil provider generates offline
+	world, err := NewGenerator(nil).GenerateWorld(context.Background(), 3, "A quiet village", 4, DefaultGenerateOptions())
+	if err != nil {
+		t.Fatalf("GenerateWorld failed: %v", err)
+	}
+	if len(world.Rooms) != 4 {
+		t.Errorf("expected 4 rooms, got %d", len(world.Rooms))
+	}
+	for _, room := range world.Rooms {
+		if !strings.Contains(room.Description, "A quiet village") {
+			t.Errorf("expected the prompt as description, got %q", room.Description)
+		}
+	}
+}
+
+func TestGeneratorLogsWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	generator := NewGenerator(&countingProvider{})
+	generator.SetLogger(logging.NewWriterLogger(&buf, logging.LogLevelDebug, logging.FormatJSON))
+	if _, err := generator.GenerateWorld(context.Background(), 7, "A quiet village", 3, DefaultGenerateOptions()); err != nil {
+		t.Fatalf("GenerateWorld failed: %v", err)
+	}
+
+	calls := 0
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("entry is not JSON: %v: %q", err, line)
+		}
+		if entry["seed"] != float64(7) {
+			t.Errorf("expected seed 7 on every entry, got %v", entry)
+		}
+		if entry["msg"] != "Provider call finished" {
+			continue
+		}
+		calls++
+		for _, key := range []string{"room", "attempt", "provider", "latency_ms"} {
+			if _, ok := entry[key]; !ok {
+				t.Errorf("expected %s on provider calls, got %v", key, entry)
+			}
+		}
+	}
+	if calls == 0 {
+		t.Errorf("expected provider calls to be logged, got %q", buf.String())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +14,10 @@ import (
 )
 
 func TestWorldGeneration_GPT4o(t *testing.T) {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		t.Skip("OPENAI_API_KEY not set; skipping live OpenAI test")
+	}
+
 	// Set required environment variables for test
 	os.Setenv("OPENAI_ENDPOINT", "https://api.openai.com/v1")
 
@@ -29,8 +34,10 @@ func TestWorldGeneration_GPT4o(t *testing.T) {
 
 	// Create a new world generation service
 	service, err := NewService(&config.Config{
-		AIProvider: cfg,
-		Server: config.ServerConfig{Port: 8080},
+		DefaultRooms: 3,
+		Provider:     string(config.ProviderOpenAI),
+		Providers:    map[string]ai.ProviderConfig{string(config.ProviderOpenAI): cfg},
+		Server:       config.ServerConfig{Port: 8080},
 	})
 	if err != nil {
 		t.Fatalf("Failed to create world generation service: %v", err)
@@ -102,9 +109,9 @@ func TestWorldGeneration_GPT4o(t *testing.T) {
 			// Optional: Save the generated world to a test file
 			testOutputDir := "test_output"
 			os.MkdirAll(testOutputDir, 0755)
-			outputFile := filepath.Join(testOutputDir, 
+			outputFile := filepath.Join(testOutputDir,
 				"world_"+sanitizeFilename(tc.name)+".json")
-			
+
 			if err := world.Save(outputFile); err != nil {
 				t.Errorf("Failed to save world to file: %v", err)
 			} else {
@@ -127,27 +134,14 @@ func TestWorldGeneration_GPT4o(t *testing.T) {
 }
 
 func TestGenerateDescription(t *testing.T) {
-	// Create a test configuration
-	cfg := &ai.ProviderConfig{
-		Type:   ai.ProviderOpenAI,
-		Model:  "gpt-4o",
-		APIKey: "test-key",
-		Parameters: map[string]interface{}{
-			"temperature": 0.7,
-			"max_tokens": 100,
-			"rate_limit": 10,
-			"timeout":    30,
-		},
-	}
-
-	// Create a new world generation service
-	service, err := NewService(cfg)
+	// The mock provider needs no network or API key
+	provider, err := ai.Open(ai.ProviderConfig{Type: ai.ProviderMock})
 	if err != nil {
-		t.Fatalf("Failed to create world generation service: %v", err)
+		t.Fatalf("Failed to open mock provider: %v", err)
 	}
 
 	// Test generating a world
-	world, err := service.GenerateWorld(context.Background(), "A mysterious castle")
+	world, err := NewGenerator(provider).GenerateWorld(context.Background(), 1, "A mysterious castle", 3, DefaultGenerateOptions())
 	if err != nil {
 		t.Fatalf("Failed to generate world: %v", err)
 	}
@@ -211,3 +205,69 @@ func sanitizeFilename(name string) string {
 	}, name)
 	return strings.ToLower(safe)
 }
+
+func TestServiceKeysReplayByActiveProvider(t *testing.T) {
+	t.Setenv(config.ProviderEnvVar, "")
+	cfg := config.DefaultConfig()
+	cfg.AIProvider.DefaultModel = "gpt-4o"
+	cfg.Provider = string(ai.ProviderTemplate)
+	cfg.Providers = map[string]ai.ProviderConfig{string(ai.ProviderTemplate): {Parameters: map[string]interface{}{"seed": 3.0}}}
+	cfg.Replay = config.ReplayConfig{Mode: string(ai.ReplayRecord), Path: filepath.Join(t.TempDir(), "replay.json")}
+	service, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	if _, err := service.GenerateWorldWithSeed(context.Background(), "A flooded cave", 1); err != nil {
+		t.Fatalf("Failed to generate world: %v", err)
+	}
+	if service.replay.Len() == 0 {
+		t.Fatal("Expected responses to be recorded")
+	}
+	for _, entry := range service.replay.Entries {
+		if entry.Model != ai.TemplateModel {
+			t.Errorf("Expected responses keyed by the template model, got %q", entry.Model)
+		}
+	}
+}
+
+func TestServiceSavesReplayStoreFromConcurrentJobs(t *testing.T) {
+	t.Setenv(config.ProviderEnvVar, "")
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Provider = string(ai.ProviderTemplate)
+	cfg.Replay = config.ReplayConfig{Mode: string(ai.ReplayAuto), Path: filepath.Join(dir, "replay.json")}
+	service, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for seed := int64(1); seed <= 8; seed++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			_, err := service.GenerateWorldWithSeed(context.Background(), "A flooded cave", seed)
+			errs <- err
+		}(seed)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to generate world: %v", err)
+		}
+	}
+
+	store, err := ai.LoadResponseStore(cfg.Replay.Path)
+	if err != nil {
+		t.Fatalf("Failed to load the saved store: %v", err)
+	}
+	if store.Len() != service.replay.Len() {
+		t.Errorf("Expected %d saved responses, got %d", service.replay.Len(), store.Len())
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected only the store in %s, got %d files", dir, len(files))
+	}
+}
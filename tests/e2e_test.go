@@ -30,10 +30,14 @@ func TestEndToEnd(t *testing.T) {
 	worldgenConfig := config.DefaultConfig()
 	worldgenConfig.DefaultRooms = 3
 	worldgenConfig.AIProvider = ai.Config{
-		Model:          "gpt-3.5-turbo",
-		MaxTokens:      150,
-		Temperature:    0.7,
-		RateLimit:      60,
+		DefaultModel: "gpt-3.5-turbo",
+		Parameters: ai.ModelParameters{
+			Temperature: 0.7,
+			MaxTokens:   150,
+		},
+		RateLimits: ai.RateLimits{
+			RequestsPerMinute: 60,
+		},
 		TimeoutSeconds: 30,
 		APIKey:         apiKey,
 	}
@@ -57,7 +61,7 @@ func TestEndToEnd(t *testing.T) {
 		if world == nil {
 			t.Fatal("Generated world is nil")
 		}
-		if len(world.Scenes) == 0 {
+		if len(world.Scenes()) == 0 {
 			t.Fatal("No scenes generated in world")
 		}
 
@@ -66,7 +70,7 @@ func TestEndToEnd(t *testing.T) {
 
 		// 2. Test navigation
 		var startScene *worldgen.Scene
-		for _, scene := range world.Scenes {
+		for _, scene := range world.Scenes() {
 			startScene = scene
 			break
 		}
@@ -116,9 +120,9 @@ func TestEndToEnd(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to load world: %v", err)
 		}
-		if len(loadedWorld.Scenes) != len(world.Scenes) {
+		if len(loadedWorld.Scenes()) != len(world.Scenes()) {
 			t.Fatalf("Loaded world has different number of scenes. Expected %d, got %d",
-				len(world.Scenes), len(loadedWorld.Scenes))
+				len(world.Scenes()), len(loadedWorld.Scenes()))
 		}
 
 		logger.Info(ctx, "Successfully tested world persistence")